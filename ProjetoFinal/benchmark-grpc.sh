@@ -17,7 +17,7 @@ echo "============================================"
 echo ""
 
 echo "Compilando benchmark..."
-go build -o bin/grpc_benchmark cmd/benchmark/grpc_benchmark.go
+go build -o bin/bench ./cmd/bench
 
 if [ $? -ne 0 ]; then
     echo "Erro ao compilar benchmark!"
@@ -27,4 +27,4 @@ fi
 echo "Executando benchmark..."
 echo ""
 
-./bin/grpc_benchmark -expr="$EXPRESSION" -clients=$CLIENTS -reqs=$REQUESTS
+./bin/bench -transports=grpc -expr="$EXPRESSION" -clients=$CLIENTS -reqs=$REQUESTS
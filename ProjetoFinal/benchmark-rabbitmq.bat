@@ -23,7 +23,7 @@ echo ================================================
 echo.
 
 echo Compilando benchmark...
-go build -o bin\rabbitmq_benchmark.exe cmd\benchmark\rabbitmq_benchmark.go
+go build -o bin\bench.exe .\cmd\bench
 
 if %ERRORLEVEL% NEQ 0 (
     echo Erro ao compilar benchmark!
@@ -33,6 +33,6 @@ if %ERRORLEVEL% NEQ 0 (
 echo Executando benchmark...
 echo.
 
-bin\rabbitmq_benchmark.exe -expr="%EXPRESSION%" -clients=%CLIENTS% -reqs=%REQUESTS%
+bin\bench.exe -transports=rabbitmq -expr="%EXPRESSION%" -clients=%CLIENTS% -reqs=%REQUESTS%
 
 endlocal
@@ -17,7 +17,7 @@ echo "================================================"
 echo ""
 
 echo "Compilando benchmark..."
-go build -o bin/rabbitmq_benchmark cmd/benchmark/rabbitmq_benchmark.go
+go build -o bin/bench ./cmd/bench
 
 if [ $? -ne 0 ]; then
     echo "Erro ao compilar benchmark!"
@@ -27,4 +27,4 @@ fi
 echo "Executando benchmark..."
 echo ""
 
-./bin/rabbitmq_benchmark -expr="$EXPRESSION" -clients=$CLIENTS -reqs=$REQUESTS
+./bin/bench -transports=rabbitmq -expr="$EXPRESSION" -clients=$CLIENTS -reqs=$REQUESTS
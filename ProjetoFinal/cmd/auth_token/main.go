@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+)
+
+var (
+	subject = flag.String("sub", "", "Principal (subject) do token")
+	ttl     = flag.Duration("ttl", 24*time.Hour, "Validade do token (0 não expira)")
+	secret  = flag.String("secret", "", "Segredo HMAC (padrão: $CALC_JWT_SECRET)")
+)
+
+// Emite um token JWT HS256 para uso com CALC_TOKEN
+func main() {
+	flag.Parse()
+
+	key := *secret
+	if key == "" {
+		key = os.Getenv(auth.JWTSecretEnv)
+	}
+	if *subject == "" || key == "" {
+		fmt.Fprintf(os.Stderr, "uso: auth_token -sub <principal> [-ttl 24h] (segredo em -secret ou %s)\n", auth.JWTSecretEnv)
+		os.Exit(2)
+	}
+
+	token, err := auth.SignJWT([]byte(key), *subject, *ttl, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao emitir token: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(token)
+}
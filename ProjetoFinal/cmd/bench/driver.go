@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/pkg/calcclient"
+	"google.golang.org/grpc/credentials"
+)
+
+// driver conecta um cliente do benchmark a um transporte. Para comparar outro
+// transporte, basta implementar driver e registrá-lo em newDriver.
+type driver interface {
+	Name() string
+	Dial(ctx context.Context, cfg calcclient.Config) (calcclient.Client, error)
+}
+
+// Transportes disponíveis em -transports, na ordem de execução padrão
+var transportNames = []string{"grpc", "rabbitmq"}
+
+type grpcDriver struct {
+	addr string
+	tls  credentials.TransportCredentials
+}
+
+func (grpcDriver) Name() string { return "grpc" }
+
+func (d grpcDriver) Dial(ctx context.Context, cfg calcclient.Config) (calcclient.Client, error) {
+	cfg.TLS = d.tls
+	return calcclient.DialGRPC(ctx, d.addr, cfg)
+}
+
+type amqpDriver struct {
+	url   string
+	codec string
+}
+
+func (amqpDriver) Name() string { return "rabbitmq" }
+
+func (d amqpDriver) Dial(_ context.Context, cfg calcclient.Config) (calcclient.Client, error) {
+	cfg.Codec = d.codec
+	return calcclient.DialAMQP(d.url, cfg)
+}
+
+// newDriver cria o driver do transporte com as opções de linha de comando
+func newDriver(name string) (driver, error) {
+	switch name {
+	case "grpc":
+		creds, err := tlsOpts.ClientCredentials()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao configurar TLS: %w", err)
+		}
+		return grpcDriver{addr: *dispatcherAddr, tls: creds}, nil
+	case "rabbitmq":
+		return amqpDriver{url: *rabbitmqURL, codec: *codec}, nil
+	}
+	return nil, fmt.Errorf("transporte desconhecido: %s (use %s)", name, strings.Join(transportNames, ", "))
+}
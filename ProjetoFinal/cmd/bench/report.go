@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+)
+
+// Formatos de -output
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var outputFormats = []string{outputText, outputJSON}
+
+// Largura das colunas do relatório lado a lado
+const (
+	labelWidth  = 20
+	columnWidth = 18
+)
+
+// printReport exibe os resultados dos transportes lado a lado, uma coluna por transporte
+func printReport(w io.Writer, sc *Scenario, results []result) {
+	fmt.Fprintln(w, "\n"+strings.Repeat("=", 60))
+	fmt.Fprintln(w, "📊 RESULTADOS DO BENCHMARK")
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+	fmt.Fprintf(w, "Cenário: %s\n", sc.Name)
+	fmt.Fprintf(w, "Carga:   %s\n", sc)
+
+	// Transportes que não puderam ser testados ficam fora da tabela
+	var ok []result
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "⚠️  %s: não testado (%v)\n", r.Transport, r.Err)
+			continue
+		}
+		ok = append(ok, r)
+	}
+	if len(ok) == 0 {
+		fmt.Fprintln(w, "\n"+strings.Repeat("=", 60))
+		return
+	}
+
+	row := func(label string, value func(Statistics) string) {
+		fmt.Fprintf(w, "   %-*s", labelWidth, label)
+		for _, r := range ok {
+			fmt.Fprintf(w, "%*s", columnWidth, value(r.Stats))
+		}
+		fmt.Fprintln(w)
+	}
+	latency := func(label string, field func(Statistics) time.Duration) {
+		row(label, func(s Statistics) string { return formatLatency(field(s)) })
+	}
+
+	fmt.Fprintf(w, "\n   %-*s", labelWidth, "")
+	for _, r := range ok {
+		fmt.Fprintf(w, "%*s", columnWidth, r.Transport)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "\n📈 Requisições:\n")
+	row("Total:", func(s Statistics) string { return fmt.Sprint(s.TotalRequests) })
+	row("Sucesso:", func(s Statistics) string { return withPercent(s.SuccessfulReqs, s.TotalRequests) })
+	row("Falhas:", func(s Statistics) string { return withPercent(s.FailedReqs, s.TotalRequests) })
+
+	fmt.Fprintf(w, "\n⏱️  Latência:\n")
+	latency("Mínima:", func(s Statistics) time.Duration { return s.MinLatency })
+	latency("Média:", func(s Statistics) time.Duration { return s.AverageLatency })
+	latency("Máxima:", func(s Statistics) time.Duration { return s.MaxLatency })
+	latency("P50:", func(s Statistics) time.Duration { return s.P50Latency })
+	latency("P95:", func(s Statistics) time.Duration { return s.P95Latency })
+	latency("P99:", func(s Statistics) time.Duration { return s.P99Latency })
+
+	fmt.Fprintf(w, "\n🚀 Desempenho:\n")
+	row("Duração total:", func(s Statistics) string { return s.TotalDuration.Round(time.Millisecond).String() })
+	row("Throughput:", func(s Statistics) string { return fmt.Sprintf("%.2f req/s", s.Throughput) })
+
+	if codes := errorCodes(ok); len(codes) > 0 {
+		fmt.Fprintf(w, "\n❌ Erros por código:\n")
+		for _, code := range codes {
+			row(code+":", func(s Statistics) string { return fmt.Sprint(s.Errors[code]) })
+		}
+	}
+
+	// Métricas coletadas dos servidores de cada transporte
+	for _, r := range ok {
+		if r.Server != nil {
+			fmt.Fprintf(w, "\n── %s ──", r.Transport)
+			metrics.PrintReport(w, r.Server)
+		}
+	}
+	fmt.Fprintln(w, "\n"+strings.Repeat("=", 60))
+}
+
+func withPercent(n, total int) string {
+	if total == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d (%.2f%%)", n, float64(n)*100/float64(total))
+}
+
+func formatLatency(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(time.Microsecond).String()
+}
+
+// errorCodes lista, ordenados, os códigos de erro de todos os transportes
+func errorCodes(results []result) []string {
+	var codes []string
+	for _, r := range results {
+		for code := range r.Stats.Errors {
+			if !slices.Contains(codes, code) {
+				codes = append(codes, code)
+			}
+		}
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// jsonReport é o relatório de -output json; durações em milissegundos
+type jsonReport struct {
+	Scenario   *Scenario       `json:"scenario"`
+	Transports []jsonTransport `json:"transports"`
+}
+
+type jsonTransport struct {
+	Transport  string         `json:"transport"`
+	Error      string         `json:"error,omitempty"` // o transporte não pôde ser testado
+	Total      int            `json:"total"`
+	Successful int            `json:"successful"`
+	Failed     int            `json:"failed"`
+	Errors     map[string]int `json:"errors,omitempty"`
+	DurationMs float64        `json:"duration_ms"`
+	AvgMs      float64        `json:"avg_ms"`
+	MinMs      float64        `json:"min_ms"`
+	MaxMs      float64        `json:"max_ms"`
+	P50Ms      float64        `json:"p50_ms"`
+	P95Ms      float64        `json:"p95_ms"`
+	P99Ms      float64        `json:"p99_ms"`
+	Throughput float64        `json:"throughput_per_s"`
+}
+
+func printJSONReport(w io.Writer, sc *Scenario, results []result) error {
+	report := jsonReport{Scenario: sc}
+	for _, r := range results {
+		t := jsonTransport{Transport: r.Transport}
+		if r.Err != nil {
+			t.Error = r.Err.Error()
+		} else {
+			s := r.Stats
+			t.Total, t.Successful, t.Failed = s.TotalRequests, s.SuccessfulReqs, s.FailedReqs
+			if len(s.Errors) > 0 {
+				t.Errors = s.Errors
+			}
+			t.DurationMs = ms(s.TotalDuration)
+			t.AvgMs, t.MinMs, t.MaxMs = ms(s.AverageLatency), ms(s.MinLatency), ms(s.MaxLatency)
+			t.P50Ms, t.P95Ms, t.P99Ms = ms(s.P50Latency), ms(s.P95Latency), ms(s.P99Latency)
+			t.Throughput = s.Throughput
+		}
+		report.Transports = append(report.Transports, t)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/pkg/calcclient"
+)
+
+// Códigos das falhas do lado do benchmark; os demais vêm do dispatcher
+const (
+	codeTimeout   = "TIMEOUT"         // a resposta não chegou dentro do timeout do cenário
+	codeTransport = "TRANSPORT_ERROR" // falha da conexão ou do broker
+)
+
+// dialTimeout limita a conexão de cada cliente
+const dialTimeout = 5 * time.Second
+
+// result é a execução do cenário em um transporte
+type result struct {
+	Transport string
+	Stats     Statistics
+	Err       error            // o transporte não pôde ser testado (ex: conexão recusada)
+	Server    metrics.Snapshot // variação das métricas dos servidores (-metrics)
+}
+
+// run aplica o cenário ao transporte. Todos os clientes se conectam antes do início da
+// medição; depois cada um envia uma expressão de cada vez, aguardando a resposta antes
+// da próxima, sem novas tentativas. Cada cliente tem o próprio ClientID e, no
+// RabbitMQ, a própria fila de respostas.
+func run(ctx context.Context, d driver, sc *Scenario, creds calcclient.Credentials) result {
+	res := result{Transport: d.Name()}
+
+	clients := make([]calcclient.Client, 0, sc.Clients)
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+	for i := range sc.Clients {
+		dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+		c, err := d.Dial(dialCtx, calcclient.Config{Credentials: creds, Timeout: sc.Timeout.Duration})
+		cancel()
+		if err != nil {
+			res.Err = fmt.Errorf("cliente %d: %w", i, err)
+			return res
+		}
+		clients = append(clients, c)
+	}
+	log.Printf("✅ [%s] %d clientes conectados", d.Name(), len(clients))
+
+	// Métricas dos servidores antes da carga
+	urls := metricsURLs[d.Name()]
+	var before metrics.Snapshot
+	if len(urls) > 0 {
+		var err error
+		if before, err = metrics.Scrape(urls); err != nil {
+			log.Printf("⚠️  [%s] Erro ao coletar métricas iniciais: %v", d.Name(), err)
+			urls = nil
+		}
+	}
+
+	start := time.Now()
+	var deadline time.Time
+	if sc.Duration.Duration > 0 {
+		deadline = start.Add(sc.Duration.Duration)
+	}
+	samples := make([][]sample, len(clients))
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			samples[i] = runClient(ctx, d.Name(), i, c, sc, start, deadline)
+		}()
+	}
+	wg.Wait()
+	res.Stats = calculateStatistics(slices.Concat(samples...), time.Since(start))
+
+	if len(urls) > 0 {
+		after, err := metrics.Scrape(urls)
+		if err != nil {
+			log.Printf("⚠️  [%s] Erro ao coletar métricas finais: %v", d.Name(), err)
+		} else {
+			res.Server = metrics.Diff(before, after)
+		}
+	}
+	return res
+}
+
+// runClient envia as expressões do cliente i até RequestsPerClient ou até o fim da duração
+func runClient(ctx context.Context, transport string, i int, c calcclient.Client, sc *Scenario, start, deadline time.Time) []sample {
+	// Rampa: o cliente i inicia em i*RampUp/Clients
+	if delay := sc.RampUp.Duration * time.Duration(i) / time.Duration(sc.Clients); delay > 0 {
+		select {
+		case <-time.After(time.Until(start.Add(delay))):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	pick := sc.picker(i)
+	var samples []sample
+	for n := 0; sc.RequestsPerClient == 0 || n < sc.RequestsPerClient; n++ {
+		if ctx.Err() != nil || (!deadline.IsZero() && time.Now().After(deadline)) {
+			break
+		}
+		expr := pick.next()
+		begin := time.Now()
+		res, err := c.Calculate(ctx, expr,
+			calcclient.WithTimeout(sc.Timeout.Duration),
+			calcclient.WithPriority(*sc.Priority),
+			calcclient.WithMaxAttempts(1))
+		s := sample{Latency: time.Since(begin), Code: errorCode(err)}
+		// Interrompido com Ctrl+C: a expressão em andamento não entra no resultado
+		if ctx.Err() != nil {
+			break
+		}
+		samples = append(samples, s)
+
+		if *verbose {
+			if s.Code != "" {
+				log.Printf("❌ [%s | Cliente %d | Req %d] %s: %v (tempo: %v)", transport, i, n, expr, err, s.Latency)
+			} else {
+				log.Printf("✅ [%s | Cliente %d | Req %d] %s = %v (tempo: %v)", transport, i, n, expr, res.Value, s.Latency)
+			}
+		}
+	}
+	return samples
+}
+
+func errorCode(err error) string {
+	var calcErr *calcclient.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, calcclient.ErrTimeout):
+		return codeTimeout
+	case errors.As(err, &calcErr):
+		return calcErr.Code
+	}
+	return codeTransport
+}
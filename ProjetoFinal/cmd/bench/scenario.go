@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"time"
+)
+
+// Scenario é a carga aplicada, igual, a cada transporte: os mesmos clientes, a mesma
+// rampa e a mesma sequência de expressões (sorteada com Seed)
+type Scenario struct {
+	Name              string       `json:"name,omitempty"`
+	Clients           int          `json:"clients"`
+	RequestsPerClient int          `json:"requests_per_client,omitempty"` // zero: envia até Duration
+	Duration          Duration     `json:"duration,omitzero"`             // zero: envia RequestsPerClient
+	RampUp            Duration     `json:"ramp_up,omitzero"`              // os clientes iniciam espaçados ao longo da rampa
+	Timeout           Duration     `json:"timeout,omitzero"`              // prazo de cada expressão (padrão: 30s)
+	Priority          *int         `json:"priority,omitempty"`            // ausente: -priority
+	Seed              uint64       `json:"seed,omitempty"`
+	Expressions       []Expression `json:"expressions"`
+}
+
+// Expression é uma expressão do mix; Weight é a frequência relativa (padrão: 1)
+type Expression struct {
+	Expression string `json:"expression"`
+	Weight     int    `json:"weight,omitempty"`
+}
+
+// Duration é um time.Duration escrito no JSON como texto (ex: "30s", "1m30s")
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duração deve ser um texto como \"30s\": %s", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// loadScenario lê o cenário de um arquivo JSON
+func loadScenario(path string) (*Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var sc Scenario
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sc); err != nil {
+		return nil, fmt.Errorf("cenário inválido em %s: %w", path, err)
+	}
+	if sc.Name == "" {
+		sc.Name = path
+	}
+	return &sc, nil
+}
+
+// withDefaults preenche os valores zero do cenário e valida o resultado
+func (sc *Scenario) withDefaults(priority int) error {
+	if sc.Clients <= 0 {
+		return errors.New("clients deve ser maior que zero")
+	}
+	if sc.RequestsPerClient < 0 || sc.Duration.Duration < 0 || sc.RampUp.Duration < 0 {
+		return errors.New("requests_per_client, duration e ramp_up não podem ser negativos")
+	}
+	if sc.RequestsPerClient == 0 && sc.Duration.Duration == 0 {
+		return errors.New("informe requests_per_client ou duration")
+	}
+	if len(sc.Expressions) == 0 {
+		return errors.New("o cenário não tem expressões")
+	}
+	for i, e := range sc.Expressions {
+		if strings.TrimSpace(e.Expression) == "" {
+			return fmt.Errorf("expressão %d vazia", i+1)
+		}
+		if e.Weight < 0 {
+			return fmt.Errorf("peso negativo na expressão %q", e.Expression)
+		}
+		if e.Weight == 0 {
+			sc.Expressions[i].Weight = 1
+		}
+	}
+	if sc.Timeout.Duration <= 0 {
+		sc.Timeout.Duration = 30 * time.Second
+	}
+	if sc.Priority == nil {
+		sc.Priority = &priority
+	}
+	if sc.Seed == 0 {
+		sc.Seed = 1
+	}
+	return nil
+}
+
+// String resume o cenário no cabeçalho do relatório
+func (sc *Scenario) String() string {
+	var load string
+	switch {
+	case sc.RequestsPerClient > 0 && sc.Duration.Duration > 0:
+		load = fmt.Sprintf("%d requisições por cliente (no máximo %v)", sc.RequestsPerClient, sc.Duration)
+	case sc.RequestsPerClient > 0:
+		load = fmt.Sprintf("%d requisições por cliente", sc.RequestsPerClient)
+	default:
+		load = fmt.Sprintf("%v", sc.Duration)
+	}
+	s := fmt.Sprintf("%d clientes, %s", sc.Clients, load)
+	if sc.RampUp.Duration > 0 {
+		s += fmt.Sprintf(", rampa de %v", sc.RampUp)
+	}
+	return s + fmt.Sprintf(", %d expressões, semente %d", len(sc.Expressions), sc.Seed)
+}
+
+// picker sorteia as expressões do mix conforme os pesos. Cada cliente tem o seu
+// gerador, derivado da semente e do índice do cliente, então o cliente i envia a mesma
+// sequência em todos os transportes.
+type picker struct {
+	exprs []Expression
+	total int
+	rng   *rand.Rand
+}
+
+func (sc *Scenario) picker(client int) *picker {
+	p := &picker{exprs: sc.Expressions, rng: rand.New(rand.NewPCG(sc.Seed, uint64(client)))}
+	for _, e := range sc.Expressions {
+		p.total += e.Weight
+	}
+	return p
+}
+
+func (p *picker) next() string {
+	n := p.rng.IntN(p.total)
+	for _, e := range p.exprs {
+		if n < e.Weight {
+			return e.Expression
+		}
+		n -= e.Weight
+	}
+	return p.exprs[len(p.exprs)-1].Expression
+}
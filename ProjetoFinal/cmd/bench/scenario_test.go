@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScenarioWithDefaults(t *testing.T) {
+	valid := func() Scenario {
+		return Scenario{Clients: 2, RequestsPerClient: 10, Expressions: []Expression{{Expression: "1+1"}, {Expression: "2*3", Weight: 4}}}
+	}
+	tests := []struct {
+		name    string
+		change  func(*Scenario)
+		wantErr bool
+	}{
+		{name: "válido", change: func(*Scenario) {}},
+		{name: "só duração", change: func(sc *Scenario) { sc.RequestsPerClient = 0; sc.Duration.Duration = time.Second }},
+		{name: "sem clientes", change: func(sc *Scenario) { sc.Clients = 0 }, wantErr: true},
+		{name: "requisições negativas", change: func(sc *Scenario) { sc.RequestsPerClient = -1 }, wantErr: true},
+		{name: "rampa negativa", change: func(sc *Scenario) { sc.RampUp.Duration = -time.Second }, wantErr: true},
+		{name: "sem requisições nem duração", change: func(sc *Scenario) { sc.RequestsPerClient = 0 }, wantErr: true},
+		{name: "sem expressões", change: func(sc *Scenario) { sc.Expressions = nil }, wantErr: true},
+		{name: "expressão vazia", change: func(sc *Scenario) { sc.Expressions[0].Expression = "  " }, wantErr: true},
+		{name: "peso negativo", change: func(sc *Scenario) { sc.Expressions[1].Weight = -1 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := valid()
+			tt.change(&sc)
+			err := sc.withDefaults(1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("erro = %v, esperado erro: %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	sc := valid()
+	if err := sc.withDefaults(3); err != nil {
+		t.Fatal(err)
+	}
+	if sc.Expressions[0].Weight != 1 || sc.Expressions[1].Weight != 4 {
+		t.Errorf("pesos = %+v, esperado 1 e 4", sc.Expressions)
+	}
+	if sc.Timeout.Duration != 30*time.Second || *sc.Priority != 3 || sc.Seed != 1 {
+		t.Errorf("padrões = timeout %v, prioridade %d, semente %d", sc.Timeout, *sc.Priority, sc.Seed)
+	}
+	// Prioridade do arquivo tem precedência sobre -priority
+	zero := 0
+	sc = valid()
+	sc.Priority = &zero
+	sc.withDefaults(3)
+	if *sc.Priority != 0 {
+		t.Errorf("prioridade = %d, esperado 0 do cenário", *sc.Priority)
+	}
+}
+
+func TestLoadScenario(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	sc, err := loadScenario(write("ok.json", `{"clients": 3, "duration": "1m30s", "ramp_up": "5s", "expressions": [{"expression": "1+1"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.Name != filepath.Join(dir, "ok.json") || sc.Duration.Duration != 90*time.Second || sc.RampUp.Duration != 5*time.Second {
+		t.Errorf("cenário = %+v", sc)
+	}
+	if _, err := loadScenario(write("campo.json", `{"clients": 1, "clientes": 2}`)); err == nil {
+		t.Error("campo desconhecido aceito")
+	}
+	if _, err := loadScenario(write("duracao.json", `{"clients": 1, "duration": 30}`)); err == nil {
+		t.Error("duração numérica aceita")
+	}
+
+	// Duration volta ao JSON como texto
+	b, _ := json.Marshal(Duration{1500 * time.Millisecond})
+	if string(b) != `"1.5s"` {
+		t.Errorf("Marshal = %s, esperado \"1.5s\"", b)
+	}
+}
+
+func TestPickerWeights(t *testing.T) {
+	sc := Scenario{Clients: 1, RequestsPerClient: 1, Seed: 42, Expressions: []Expression{
+		{Expression: "a", Weight: 1},
+		{Expression: "b", Weight: 3},
+		{Expression: "c", Weight: 6},
+	}}
+	if err := sc.withDefaults(1); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20000
+	counts := map[string]int{}
+	p := sc.picker(0)
+	for range n {
+		counts[p.next()]++
+	}
+	for _, e := range sc.Expressions {
+		want := float64(n*e.Weight) / 10
+		if got := float64(counts[e.Expression]); math.Abs(got-want) > want*0.1 {
+			t.Errorf("%s sorteada %v vezes, esperado ~%v", e.Expression, got, want)
+		}
+	}
+}
+
+func TestPickerIsDeterministicPerClient(t *testing.T) {
+	sc := Scenario{Clients: 2, RequestsPerClient: 1, Expressions: []Expression{{Expression: "a"}, {Expression: "b"}, {Expression: "c"}}}
+	if err := sc.withDefaults(1); err != nil {
+		t.Fatal(err)
+	}
+	sequence := func(client int) []string {
+		p := sc.picker(client)
+		var s []string
+		for range 50 {
+			s = append(s, p.next())
+		}
+		return s
+	}
+	// O mesmo cliente envia a mesma sequência em todos os transportes
+	if !slices.Equal(sequence(0), sequence(0)) {
+		t.Error("o mesmo cliente sorteou sequências diferentes")
+	}
+	if slices.Equal(sequence(0), sequence(1)) {
+		t.Error("clientes diferentes sortearam a mesma sequência")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// sample é o resultado de uma expressão enviada pelo benchmark
+type sample struct {
+	Latency time.Duration
+	Code    string // vazio: sucesso; senão o código do erro
+}
+
+// Statistics resume a execução do cenário em um transporte; as latências consideram
+// apenas as expressões calculadas com sucesso
+type Statistics struct {
+	TotalRequests  int
+	SuccessfulReqs int
+	FailedReqs     int
+	Errors         map[string]int // falhas por código
+	TotalDuration  time.Duration
+	AverageLatency time.Duration
+	MinLatency     time.Duration
+	MaxLatency     time.Duration
+	P50Latency     time.Duration
+	P95Latency     time.Duration
+	P99Latency     time.Duration
+	Throughput     float64 // expressões calculadas com sucesso por segundo
+}
+
+func calculateStatistics(samples []sample, totalDuration time.Duration) Statistics {
+	stats := Statistics{TotalRequests: len(samples), TotalDuration: totalDuration, Errors: map[string]int{}}
+
+	var durations []time.Duration
+	for _, s := range samples {
+		if s.Code != "" {
+			stats.FailedReqs++
+			stats.Errors[s.Code]++
+			continue
+		}
+		stats.SuccessfulReqs++
+		durations = append(durations, s.Latency)
+	}
+	if len(durations) == 0 {
+		return stats
+	}
+
+	slices.Sort(durations)
+	stats.MinLatency = durations[0]
+	stats.MaxLatency = durations[len(durations)-1]
+
+	var sum time.Duration
+	for _, d := range durations {
+		sum += d
+	}
+	stats.AverageLatency = sum / time.Duration(len(durations))
+
+	stats.P50Latency = percentile(durations, 50)
+	stats.P95Latency = percentile(durations, 95)
+	stats.P99Latency = percentile(durations, 99)
+
+	if totalDuration > 0 {
+		stats.Throughput = float64(stats.SuccessfulReqs) / totalDuration.Seconds()
+	}
+	return stats
+}
+
+// percentile retorna o percentil p das durações ordenadas
+func percentile(sorted []time.Duration, p int) time.Duration {
+	return sorted[min(len(sorted)*p/100, len(sorted)-1)]
+}
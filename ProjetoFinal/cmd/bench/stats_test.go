@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	tests := []struct {
+		sorted []time.Duration
+		p      int
+		want   time.Duration
+	}{
+		{sorted, 50, 51 * time.Millisecond},
+		{sorted, 95, 96 * time.Millisecond},
+		{sorted, 99, 100 * time.Millisecond},
+		{sorted, 100, 100 * time.Millisecond},
+		{sorted, 0, time.Millisecond},
+		{sorted[:1], 99, time.Millisecond},
+		{sorted[:3], 50, 2 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%d valores, %d) = %v, esperado %v", len(tt.sorted), tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateStatistics(t *testing.T) {
+	samples := []sample{
+		{Latency: 30 * time.Millisecond},
+		{Latency: 10 * time.Millisecond},
+		{Latency: 500 * time.Millisecond, Code: codeTimeout},
+		{Latency: 20 * time.Millisecond},
+		{Latency: time.Millisecond, Code: "DIV_BY_ZERO"},
+		{Latency: time.Millisecond, Code: "DIV_BY_ZERO"},
+	}
+	stats := calculateStatistics(samples, 2*time.Second)
+
+	if stats.TotalRequests != 6 || stats.SuccessfulReqs != 3 || stats.FailedReqs != 3 {
+		t.Errorf("total/sucesso/falha = %d/%d/%d, esperado 6/3/3", stats.TotalRequests, stats.SuccessfulReqs, stats.FailedReqs)
+	}
+	if stats.Errors[codeTimeout] != 1 || stats.Errors["DIV_BY_ZERO"] != 2 || len(stats.Errors) != 2 {
+		t.Errorf("Errors = %v", stats.Errors)
+	}
+	// As latências das falhas não entram nas estatísticas
+	if stats.MinLatency != 10*time.Millisecond || stats.MaxLatency != 30*time.Millisecond {
+		t.Errorf("min/max = %v/%v, esperado 10ms/30ms", stats.MinLatency, stats.MaxLatency)
+	}
+	if stats.AverageLatency != 20*time.Millisecond || stats.P50Latency != 20*time.Millisecond {
+		t.Errorf("média/p50 = %v/%v, esperado 20ms", stats.AverageLatency, stats.P50Latency)
+	}
+	if stats.Throughput != 1.5 {
+		t.Errorf("Throughput = %v, esperado 1.5", stats.Throughput)
+	}
+	if samples[0].Latency != 30*time.Millisecond {
+		t.Error("calculateStatistics alterou as amostras")
+	}
+}
+
+func TestCalculateStatisticsWithoutSuccess(t *testing.T) {
+	stats := calculateStatistics([]sample{{Latency: time.Second, Code: codeTransport}}, 0)
+	if stats.FailedReqs != 1 || stats.SuccessfulReqs != 0 {
+		t.Errorf("sucesso/falha = %d/%d, esperado 0/1", stats.SuccessfulReqs, stats.FailedReqs)
+	}
+	if stats.MaxLatency != 0 || stats.Throughput != 0 {
+		t.Errorf("estatísticas sem sucesso = %+v", stats)
+	}
+	if stats := calculateStatistics(nil, time.Second); stats.TotalRequests != 0 || stats.Throughput != 0 {
+		t.Errorf("estatísticas vazias = %+v", stats)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/repl"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/telemetry"
+)
+
+// Transporte, TLS, histórico e demais opções da sessão
+var flags = repl.BindFlags(flag.CommandLine, repl.TransportGRPC)
+
+func main() {
+	flag.Parse()
+
+	// Gera ID único para este cliente
+	clientID := core.NewID()
+
+	logger := logging.Setup("client").With(slog.String(logging.KeyClientID, clientID))
+	logger.Info("Cliente Calculadora", slog.String("transport", flags.Transport))
+
+	// Configura o tracing distribuído
+	shutdownTracer, err := telemetry.InitTracer("calc-client")
+	if err != nil {
+		logger.Error("Erro ao configurar tracing", logging.Err(err))
+		os.Exit(repl.ExitUsage)
+	}
+	defer shutdownTracer(context.Background())
+
+	// Credenciais (CALC_TOKEN ou CALC_API_KEY) usadas nos dois transportes
+	creds, err := auth.CredentialsFromEnv()
+	if err != nil {
+		logger.Error("Erro nas credenciais", logging.Err(err))
+		os.Exit(repl.ExitUsage)
+	}
+
+	cfg, err := flags.Config(clientID, creds, logger)
+	if err != nil {
+		logger.Error("Erro na configuração", logging.Err(err))
+		os.Exit(repl.ExitUsage)
+	}
+	session, err := repl.New(cfg)
+	if err != nil {
+		logger.Error("Erro ao conectar", logging.Err(err))
+		os.Exit(repl.ExitUsage)
+	}
+	defer session.Close()
+
+	// Modo não interativo: um resultado por expressão e o código de saída reflete os erros
+	if flags.Batch.Enabled() {
+		code, err := session.RunBatch(flags.Batch)
+		if err != nil {
+			logger.Error("Erro no modo não interativo", logging.Err(err))
+		}
+		session.Close()
+		shutdownTracer(context.Background())
+		os.Exit(code)
+	}
+
+	if err := session.Run(); err != nil {
+		logger.Error("Erro ao ler entrada", logging.Err(err))
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	outDir = flag.String("out", "certs", "Diretório de saída")
+	names  = flag.String("names", "dispatcher,add,subtract,multiply,divide,client,gateway", "Certificados a gerar (um por componente)")
+	hosts  = flag.String("hosts", "localhost,127.0.0.1", "Hosts/IPs incluídos como SAN em todos os certificados")
+	days   = flag.Int("days", 365, "Validade dos certificados em dias")
+)
+
+// Gera uma CA local e certificados de desenvolvimento assinados por ela.
+// Cada certificado serve tanto como servidor quanto como cliente (mTLS),
+// com o nome do componente no CN e nos SANs.
+func main() {
+	flag.Parse()
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fail("Erro ao criar diretório: %v", err)
+	}
+
+	validity := time.Duration(*days) * 24 * time.Hour
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		fail("Erro ao gerar chave da CA: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          serial(),
+		Subject:               pkix.Name{CommonName: "Calculadora Distribuída - CA local"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		fail("Erro ao criar CA: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		fail("Erro ao ler CA: %v", err)
+	}
+	writeCert("ca", caDER, caKey)
+
+	for _, name := range strings.Split(*names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			fail("Erro ao gerar chave de %s: %v", name, err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: serial(),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(validity),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			DNSNames:     []string{name},
+		}
+		for _, host := range strings.Split(*hosts, ",") {
+			host = strings.TrimSpace(host)
+			if ip := net.ParseIP(host); ip != nil {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			} else if host != "" {
+				template.DNSNames = append(template.DNSNames, host)
+			}
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			fail("Erro ao criar certificado de %s: %v", name, err)
+		}
+		writeCert(name, der, key)
+	}
+
+	fmt.Printf("Certificados gerados em %s\n", *outDir)
+}
+
+func writeCert(name string, der []byte, key *ecdsa.PrivateKey) {
+	certPath := filepath.Join(*outDir, name+".pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		fail("Erro ao gravar %s: %v", certPath, err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		fail("Erro ao serializar chave de %s: %v", name, err)
+	}
+	keyPath := filepath.Join(*outDir, name+"-key.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		fail("Erro ao gravar %s: %v", keyPath, err)
+	}
+
+	fmt.Printf("  %s, %s\n", certPath, keyPath)
+}
+
+func serial() *big.Int {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		fail("Erro ao gerar número de série: %v", err)
+	}
+	return n
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -2,51 +2,94 @@ package main
 
 import (
 	"context"
-	"log"
+	"flag"
+	"log/slog"
 	"net"
-	"strings"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
 	grpcOps "github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/grpc"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/telemetry"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/tlsconfig"
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	port = ":50052"
+	port      = ":50052"
 	operation = "add"
 )
 
+var (
+	metricsAddr = flag.String("metrics-addr", ":9102", "Endereço do endpoint /metrics (vazio desativa)")
+	dedupWindow = flag.Duration("dedup-window", 2*time.Minute, "Janela em que um StepID repetido recebe a resposta anterior sem nova execução (0 desativa)")
+)
+
+// TLS do servidor; com -tls-client-auth só aceita dispatchers com certificado assinado pela CA
+var tlsOpts = tlsconfig.BindServerFlags(flag.CommandLine)
+
 // OperationServer implementa o serviço OperationService
 type OperationServer struct {
 	pb.UnimplementedOperationServiceServer
 	operation string
+	instance  string
+	logger    *slog.Logger
 }
 
 // NewOperationServer cria um novo servidor de operação
 func NewOperationServer(op string) *OperationServer {
 	return &OperationServer{
 		operation: op,
+		instance:  core.InstanceID(op),
+		logger:    logging.Component(op),
 	}
 }
 
 // Execute executa a operação
 func (s *OperationServer) Execute(ctx context.Context, req *pb.OperationRequest) (*pb.OperationResponse, error) {
-	// Extrai clientID do expressionID
-	clientID := "UNKNOWN"
-	parts := strings.Split(req.ExpressionId, "_expr_")
-	if len(parts) > 0 {
-		clientID = parts[0]
+	// Step de uma expressão cancelada enquanto aguardava execução: descarta
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
 	}
 
-	serverName := strings.ToUpper(s.operation)
-	log.Printf("[%s] [%s] Recebida operação: %s(%v) [Step: %s]", serverName, clientID, req.Operation, req.Numbers, req.StepId)
+	clientID := grpcOps.IncomingPrincipal(ctx, core.ClientIDFromExpressionID(req.ExpressionId))
+
+	// Enriquece o span criado pelo interceptor otelgrpc
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		telemetry.AttrExpressionID.String(req.ExpressionId),
+		telemetry.AttrStepID.String(req.StepId),
+		telemetry.AttrOperation.String(req.Operation),
+	)
+
+	s.logger.DebugContext(ctx, "Recebida operação",
+		slog.String(logging.KeyExpressionID, req.ExpressionId),
+		slog.String(logging.KeyStepID, req.StepId),
+		slog.String(logging.KeyClientID, clientID),
+		slog.String(logging.KeyOperation, req.Operation),
+		slog.Any("numbers", req.Numbers))
 
 	// Valida operação
 	if req.Operation != s.operation {
-		log.Printf("[%s] [%s] Operação inválida: esperado %s, recebido %s", serverName, clientID, s.operation, req.Operation)
+		s.logger.WarnContext(ctx, "Operação inválida",
+			slog.String(logging.KeyStepID, req.StepId),
+			slog.String(logging.KeyClientID, clientID),
+			slog.String("expected", s.operation),
+			slog.String(logging.KeyOperation, req.Operation))
+		span.SetStatus(codes.Error, "INVALID_OPERATION")
 		return &pb.OperationResponse{
 			ExpressionId: req.ExpressionId,
 			StepId:       req.StepId,
+			Server:       s.instance,
 			Error: &pb.ErrorInfo{
 				Code:    "INVALID_OPERATION",
 				Message: "Este servidor só processa operações " + s.operation,
@@ -57,14 +100,21 @@ func (s *OperationServer) Execute(ctx context.Context, req *pb.OperationRequest)
 	// Executa operação
 	result, err := grpcOps.ExecuteOperation(req.Operation, req.Numbers)
 	if err != nil {
-		log.Printf("[%s] [%s] Erro ao executar operação: %v", serverName, clientID, err)
+		s.logger.WarnContext(ctx, "Erro ao executar operação",
+			slog.String(logging.KeyStepID, req.StepId),
+			slog.String(logging.KeyClientID, clientID),
+			slog.String(logging.KeyOperation, req.Operation),
+			logging.Err(err))
 		errorCode := "EXECUTION_ERROR"
 		if err.Error() == "divisão por zero" {
 			errorCode = "DIV_BY_ZERO"
 		}
+		span.SetAttributes(telemetry.AttrErrorCode.String(errorCode))
+		span.SetStatus(codes.Error, err.Error())
 		return &pb.OperationResponse{
 			ExpressionId: req.ExpressionId,
 			StepId:       req.StepId,
+			Server:       s.instance,
 			Error: &pb.ErrorInfo{
 				Code:    errorCode,
 				Message: err.Error(),
@@ -72,30 +122,70 @@ func (s *OperationServer) Execute(ctx context.Context, req *pb.OperationRequest)
 		}, nil
 	}
 
-	log.Printf("[%s] [%s] Operação executada com sucesso: %f", serverName, clientID, result)
+	s.logger.DebugContext(ctx, "Operação executada com sucesso",
+		slog.String(logging.KeyStepID, req.StepId),
+		slog.String(logging.KeyClientID, clientID),
+		slog.String(logging.KeyOperation, req.Operation),
+		slog.Float64("result", result))
 	return &pb.OperationResponse{
 		ExpressionId: req.ExpressionId,
 		StepId:       req.StepId,
 		Result:       result,
+		Server:       s.instance,
 	}, nil
 }
 
 func main() {
-	serverName := strings.ToUpper(operation)
-	log.Printf("[%s] Iniciando servidor de operação: %s", serverName, operation)
+	flag.Parse()
+
+	logger := logging.Setup(operation)
+	logger.Info("Iniciando servidor de operação", slog.String(logging.KeyOperation, operation))
+
+	// Configura o tracing distribuído
+	shutdownTracer, err := telemetry.InitTracer("grpc-" + operation + "-server")
+	if err != nil {
+		logger.Error("Erro ao configurar tracing", logging.Err(err))
+		os.Exit(1)
+	}
+	defer shutdownTracer(context.Background())
+
+	// Expõe métricas Prometheus
+	metrics.Serve(*metricsAddr)
 
 	// Cria listener
 	lis, err := net.Listen("tcp", port)
 	if err != nil {
-		log.Fatalf("[%s] Falha ao criar listener: %v", serverName, err)
+		logger.Error("Falha ao criar listener", logging.Err(err))
+		os.Exit(1)
+	}
+
+	// Credenciais de transporte (TLS/mTLS)
+	creds, err := tlsOpts.ServerCredentials()
+	if err != nil {
+		logger.Error("Erro ao configurar TLS", logging.Err(err))
+		os.Exit(1)
 	}
 
 	// Cria servidor gRPC
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(grpcOps.OperationMetricsInterceptor(), grpcOps.StepDedupInterceptor(*dedupWindow)),
+	)
 	pb.RegisterOperationServiceServer(grpcServer, NewOperationServer(operation))
 
-	log.Printf("[%s] Servidor %s escutando em %s", serverName, operation, port)
+	// Encerra graciosamente ao receber sinal, descarregando os spans pendentes
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		<-sigs
+		logger.Info("Encerrando servidor...")
+		grpcServer.GracefulStop()
+	}()
+
+	logger.Info("Servidor escutando", slog.String("addr", port), slog.String("tls", tlsOpts.ServerMode()))
 	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("Falha ao servir: %v", err)
+		logger.Error("Falha ao servir", logging.Err(err))
+		os.Exit(1)
 	}
 }
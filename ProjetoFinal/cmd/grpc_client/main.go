@@ -1,102 +1,69 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"fmt"
-	"log"
+	"flag"
+	"log/slog"
 	"os"
-	"strings"
-	"time"
 
-	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
-	"google.golang.org/grpc"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/repl"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/telemetry"
 )
 
-const (
-	dispatcherAddr = "localhost:50051"
-	defaultTimeout = 30000 // 30 segundos em milissegundos
-)
+// Mesmas opções do cmd/calc, começando pelo transporte gRPC
+var flags = repl.BindFlags(flag.CommandLine, repl.TransportGRPC)
 
 func main() {
+	flag.Parse()
+
 	// Gera ID único para este cliente
-	clientID := fmt.Sprintf("CLIENT-%d", time.Now().Unix()%10000)
+	clientID := core.NewID()
 
-	log.Printf("[%s] Cliente Calculadora gRPC", clientID)
-	log.Printf("[%s] ========================", clientID)
+	logger := logging.Setup("client").With(slog.String(logging.KeyClientID, clientID))
+	logger.Info("Cliente Calculadora gRPC")
 
-	// Conecta ao dispatcher
-	conn, err := grpc.Dial(dispatcherAddr, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	// Configura o tracing distribuído
+	shutdownTracer, err := telemetry.InitTracer("grpc-client")
 	if err != nil {
-		log.Fatalf("[%s] Falha ao conectar ao dispatcher: %v", clientID, err)
+		logger.Error("Erro ao configurar tracing", logging.Err(err))
+		os.Exit(repl.ExitUsage)
 	}
-	defer conn.Close()
-
-	client := pb.NewCalculatorServiceClient(conn)
-	log.Printf("[%s] Conectado ao dispatcher em %s\n", clientID, dispatcherAddr)
-
-	// Loop de interação
-	scanner := bufio.NewScanner(os.Stdin)
-	expressionCounter := 0
-
-	fmt.Println("\nDigite uma expressão matemática (ou 'sair' para encerrar):")
-	fmt.Println("Exemplos: ((4+3)*2)/5, 10+20*3, (15-5)/2")
-
-	for {
-		fmt.Print("\n> ")
-		if !scanner.Scan() {
-			break
-		}
-
-		input := strings.TrimSpace(scanner.Text())
-		if input == "" {
-			continue
-		}
-
-		if strings.ToLower(input) == "sair" || strings.ToLower(input) == "exit" {
-			fmt.Println("Encerrando cliente...")
-			break
-		}
-
-		// Prepara requisição
-		expressionCounter++
-		expressionID := fmt.Sprintf("%s_expr_%d", clientID, expressionCounter)
+	defer shutdownTracer(context.Background())
 
-		req := &pb.ExpressionRequest{
-			ExpressionId: expressionID,
-			Expression:   input,
-			DeadlineMs:   defaultTimeout,
-		}
-
-		// Cria contexto com timeout
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(defaultTimeout)*time.Millisecond)
-
-		log.Printf("[%s] Enviando expressão: %s (ID: %s)", clientID, input, expressionID)
+	// Credenciais (CALC_TOKEN ou CALC_API_KEY) usadas nos dois transportes
+	creds, err := auth.CredentialsFromEnv()
+	if err != nil {
+		logger.Error("Erro nas credenciais", logging.Err(err))
+		os.Exit(repl.ExitUsage)
+	}
 
-		// Envia requisição
-		startTime := time.Now()
-		resp, err := client.Calculate(ctx, req)
-		duration := time.Since(startTime)
-		cancel()
+	cfg, err := flags.Config(clientID, creds, logger)
+	if err != nil {
+		logger.Error("Erro na configuração", logging.Err(err))
+		os.Exit(repl.ExitUsage)
+	}
+	session, err := repl.New(cfg)
+	if err != nil {
+		logger.Error("Erro ao conectar", logging.Err(err))
+		os.Exit(repl.ExitUsage)
+	}
+	defer session.Close()
 
+	// Modo não interativo: um resultado por expressão e o código de saída reflete os erros
+	if flags.Batch.Enabled() {
+		code, err := session.RunBatch(flags.Batch)
 		if err != nil {
-			fmt.Printf("❌ Erro ao calcular: %v\n", err)
-			log.Printf("[%s] Erro: %v", clientID, err)
-			continue
-		}
-
-		// Processa resposta
-		if resp.Error != nil {
-			fmt.Printf("❌ Erro: [%s] %s\n", resp.Error.Code, resp.Error.Message)
-			log.Printf("[%s] Erro retornado: [%s] %s", clientID, resp.Error.Code, resp.Error.Message)
-		} else {
-			fmt.Printf("✅ Resultado: %s = %f\n", input, resp.Result)
-			fmt.Printf("⏱️  Tempo de execução: %v\n", duration)
-			log.Printf("[%s] Resultado: %f (tempo: %v)", clientID, resp.Result, duration)
+			logger.Error("Erro no modo não interativo", logging.Err(err))
 		}
+		session.Close()
+		shutdownTracer(context.Background())
+		os.Exit(code)
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Printf("Erro ao ler entrada: %v", err)
+	if err := session.Run(); err != nil {
+		logger.Error("Erro ao ler entrada", logging.Err(err))
 	}
 }
@@ -2,31 +2,76 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
-	"strings"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
-	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
 	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	grpcOps "github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/grpc"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/instrument"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/quota"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/results"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/sched"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/store"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/telemetry"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/tlsconfig"
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	grpcCodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	port = ":50051"
 )
 
+var (
+	metricsAddr = flag.String("metrics-addr", ":9101", "Endereço do endpoint /metrics (vazio desativa)")
+	maxPending  = flag.Int("max-pending", 10000, "Máximo de expressões em andamento antes de rejeitar novas (0 desativa)")
+	maxSteps    = flag.Int("max-concurrent-steps", 256, "Steps executados em paralelo; os excedentes aguardam por prioridade (0 desativa)")
+	resultsFile = flag.String("results-file", "", "Arquivo bbolt com o estado e os resultados das expressões (vazio: apenas em memória)")
+	retention   = flag.Duration("result-retention", 10*time.Minute, "Tempo que os resultados ficam disponíveis para GetResult/WaitResult")
+)
+
+var (
+	// TLS das conexões dos clientes com o dispatcher
+	tlsOpts = tlsconfig.BindServerFlags(flag.CommandLine)
+	// TLS das conexões do dispatcher com os servidores de operação (-upstream-tls-*)
+	upstreamTLS = tlsconfig.BindClientFlags(flag.CommandLine, "upstream-")
+)
+
 // DispatcherServer implementa o serviço CalculatorService
 type DispatcherServer struct {
 	pb.UnimplementedCalculatorServiceServer
-	parser        *core.Parser
-	serverAddrs   map[string]string
-	serverClients map[string]pb.OperationServiceClient
+	parser      *core.Parser
+	serverAddrs map[string]string
+	executor    *grpcOps.Executor
+	engine      *core.Engine
+	quota       *quota.Limiter
+	results     *results.Store // Estado das expressões para Submit/GetResult/WaitResult
+	maxPending  int64
+	pending     atomic.Int64 // Expressões em andamento
+	cancelMu    sync.Mutex
+	cancels     map[string]*context.CancelCauseFunc // ExpressionID -> cancelamento da execução em andamento
+	logger      *slog.Logger
 }
 
 // NewDispatcherServer cria um novo servidor dispatcher
-func NewDispatcherServer() *DispatcherServer {
+func NewDispatcherServer(limits core.Limits, limiter *quota.Limiter, resultStore *results.Store, maxPending, maxConcurrentSteps int) *DispatcherServer {
 	serverAddrs := map[string]string{
 		"add":      "localhost:50052",
 		"subtract": "localhost:50053",
@@ -34,167 +79,487 @@ func NewDispatcherServer() *DispatcherServer {
 		"divide":   "localhost:50055",
 	}
 
+	// Steps executados em paralelo; os excedentes aguardam vaga por prioridade
+	executor := grpcOps.NewExecutor(sched.NewScheduler(maxConcurrentSteps))
+
 	return &DispatcherServer{
-		parser:        core.NewParser(),
-		serverAddrs:   serverAddrs,
-		serverClients: make(map[string]pb.OperationServiceClient),
+		parser:      core.NewParserWithLimits(limits),
+		serverAddrs: serverAddrs,
+		executor:    executor,
+		engine:      core.NewEngine(executor, instrument.StepHook("grpc")),
+		quota:       limiter,
+		results:     resultStore,
+		maxPending:  int64(maxPending),
+		cancels:     make(map[string]*context.CancelCauseFunc),
+		logger:      logging.Component("dispatcher"),
 	}
 }
 
 // connectToServers estabelece conexões com os servidores de operação
-func (s *DispatcherServer) connectToServers() error {
+func (s *DispatcherServer) connectToServers(creds credentials.TransportCredentials) error {
 	for operation, addr := range s.serverAddrs {
-		conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
-		if err != nil {
-			return fmt.Errorf("falha ao conectar ao servidor %s: %v", operation, err)
+		if err := s.executor.Connect(operation, addr, creds); err != nil {
+			return err
 		}
-		s.serverClients[operation] = pb.NewOperationServiceClient(conn)
-		log.Printf("Conectado ao servidor %s em %s", operation, addr)
+		s.logger.Info("Conectado ao servidor", slog.String(logging.KeyOperation, operation), slog.String("addr", addr))
 	}
 	return nil
 }
 
 // Calculate processa uma expressão matemática
 func (s *DispatcherServer) Calculate(ctx context.Context, req *pb.ExpressionRequest) (*pb.ExpressionResponse, error) {
-	// Extrai o clientID do expressionID (formato: CLIENT-XXXX_expr_Y)
-	clientID := "UNKNOWN"
-	parts := strings.Split(req.ExpressionId, "_expr_")
-	if len(parts) > 0 {
-		clientID = parts[0]
+	if resp, err := s.deduplicate(ctx, req); resp != nil || err != nil {
+		return resp, err
 	}
+	ctx, done := s.cancellable(ctx, req.ExpressionId)
+	defer done()
+	return s.calculate(ctx, req, nil), nil
+}
 
-	log.Printf("[DISPATCHER] [%s] Recebida expressão: %s (ID: %s)", clientID, req.Expression, req.ExpressionId)
+// CalculateWithProgress processa a expressão enviando um evento a cada step despachado
+// e concluído; o último evento traz a resposta final
+func (s *DispatcherServer) CalculateWithProgress(req *pb.ExpressionRequest, stream pb.CalculatorService_CalculateWithProgressServer) error {
+	// Uma repetição recebe apenas o evento final
+	if resp, err := s.deduplicate(stream.Context(), req); resp != nil || err != nil {
+		if err != nil {
+			return err
+		}
+		return stream.Send(&pb.ProgressEvent{
+			ExpressionId: req.ExpressionId,
+			Event:        &pb.ProgressEvent_Result{Result: resp},
+		})
+	}
+	ctx, done := s.cancellable(stream.Context(), req.ExpressionId)
+	defer done()
+	var sendErr error
+	resp := s.calculate(ctx, req, func(e core.StepEvent) {
+		if sendErr != nil {
+			return
+		}
+		sendErr = stream.Send(&pb.ProgressEvent{
+			ExpressionId: req.ExpressionId,
+			Event:        &pb.ProgressEvent_Step{Step: grpcOps.StepProgressToProto(e)},
+		})
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	return stream.Send(&pb.ProgressEvent{
+		ExpressionId: req.ExpressionId,
+		Event:        &pb.ProgressEvent_Result{Result: resp},
+	})
+}
 
-	// Parse da expressão
-	steps, rpnStr, err := s.parser.ParseWithRPN(req.Expression)
+// Submit registra a expressão e a executa em segundo plano; o resultado fica no
+// result store, consultado por GetResult e WaitResult com o ticket (o expressionID)
+func (s *DispatcherServer) Submit(ctx context.Context, req *pb.ExpressionRequest) (*pb.SubmitResponse, error) {
+	if req.ExpressionId == "" {
+		return nil, status.Error(grpcCodes.InvalidArgument, "expression_id é obrigatório (é o ticket da expressão)")
+	}
+	// Reenvio de uma expressão em andamento ou concluída: retorna o mesmo ticket
+	rec, duplicate, err := s.results.Begin(req.ExpressionId, principalFrom(ctx, req.ClientId, req.ExpressionId).ID, req.Expression)
+	if err != nil {
+		return nil, status.Error(grpcCodes.AlreadyExists, err.Error())
+	}
+	if duplicate {
+		metrics.DuplicateExpressionsTotal.WithLabelValues("grpc", duplicateOutcome(rec)).Inc()
+		return &pb.SubmitResponse{Ticket: rec.ExpressionID, State: stateToProto(rec.Status)}, nil
+	}
+
+	// A execução continua mesmo que o cliente desconecte. O cancelamento é registrado
+	// antes de responder, para que um Cancel logo após o Submit encontre a expressão.
+	calcCtx, done := s.cancellable(context.WithoutCancel(ctx), req.ExpressionId)
+	go func() {
+		defer done()
+		s.calculate(calcCtx, req, nil)
+	}()
+	return &pb.SubmitResponse{Ticket: rec.ExpressionID, State: stateToProto(rec.Status)}, nil
+}
+
+// Cancel interrompe uma expressão em andamento (inclusive enviada por Submit): os steps
+// restantes não são despachados, o step em execução é abandonado e a expressão termina
+// com CANCELLED. Aguarda a resposta ser registrada para informar o estado final.
+func (s *DispatcherServer) Cancel(ctx context.Context, req *pb.CancelRequest) (*pb.CancelResponse, error) {
+	rec, ok := s.results.Get(req.ExpressionId)
+	if !ok || rec.Owner != principalFrom(ctx, req.ClientId, req.ExpressionId).ID {
+		return nil, status.Errorf(grpcCodes.NotFound, "expressão desconhecida ou expirada: %s", req.ExpressionId)
+	}
+
+	var cancelled bool
+	if !rec.Status.Terminal() {
+		s.cancelMu.Lock()
+		cancel, ok := s.cancels[req.ExpressionId]
+		s.cancelMu.Unlock()
+		if ok {
+			(*cancel)(core.ErrCancelled)
+			s.logger.InfoContext(ctx, "Cancelamento solicitado", slog.String(logging.KeyExpressionID, req.ExpressionId))
+			rec, _ = s.results.Wait(ctx, req.ExpressionId)
+			// A expressão pode ter terminado antes do cancelamento
+			cancelled = rec.Error != nil && rec.Error.Code == core.CodeCancelled
+		}
+	}
+	return &pb.CancelResponse{ExpressionId: req.ExpressionId, Cancelled: cancelled, State: stateToProto(rec.Status)}, nil
+}
+
+// cancellable registra o cancelamento da execução da expressão, antes de calculate;
+// a função retornada remove o registro ao fim da execução
+func (s *DispatcherServer) cancellable(ctx context.Context, expressionID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	if expressionID == "" {
+		return ctx, func() { cancel(nil) }
+	}
+	s.cancelMu.Lock()
+	s.cancels[expressionID] = &cancel
+	s.cancelMu.Unlock()
+	return ctx, func() {
+		s.cancelMu.Lock()
+		if s.cancels[expressionID] == &cancel {
+			delete(s.cancels, expressionID)
+		}
+		s.cancelMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// deduplicate registra a expressão no result store. Se ela repete uma em andamento ou
+// concluída (ex: retry do cliente após timeout), retorna a resposta da execução original,
+// aguardando-a se necessário; retorna nil se a expressão deve ser executada.
+func (s *DispatcherServer) deduplicate(ctx context.Context, req *pb.ExpressionRequest) (*pb.ExpressionResponse, error) {
+	rec, duplicate, err := s.results.Begin(req.ExpressionId, principalFrom(ctx, req.ClientId, req.ExpressionId).ID, req.Expression)
 	if err != nil {
-		log.Printf("[DISPATCHER] [%s] Erro ao fazer parse da expressão: %v", clientID, err)
 		return &pb.ExpressionResponse{
 			ExpressionId: req.ExpressionId,
-			Error: &pb.ErrorInfo{
-				Code:    "PARSE_ERROR",
-				Message: fmt.Sprintf("Erro ao fazer parse da expressão: %v", err),
-			},
+			Error:        &pb.ErrorInfo{Code: results.CodeDuplicateExpressionID, Message: err.Error()},
+			ClientId:     req.ClientId,
 		}, nil
 	}
+	if !duplicate {
+		return nil, nil
+	}
 
-	log.Printf("[DISPATCHER] [%s] RPN: %s", clientID, rpnStr)
-	log.Printf("[DISPATCHER] [%s] Expressão parseada em %d steps", clientID, len(steps))
+	metrics.DuplicateExpressionsTotal.WithLabelValues("grpc", duplicateOutcome(rec)).Inc()
+	s.logger.InfoContext(ctx, "Expressão repetida, usando a execução original",
+		slog.String(logging.KeyExpressionID, req.ExpressionId),
+		slog.String("status", string(rec.Status)))
 
-	// Executa cada step
-	results := make(map[string]float64)
-	for i, step := range steps {
-		// Substitui referências a resultados anteriores
-		numbers := make([]float64, len(step.Numbers))
-		copy(numbers, step.Numbers)
+	rec, _ = s.results.Wait(ctx, req.ExpressionId)
+	if !rec.Status.Terminal() {
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	resp := responseFromRecord(rec)
+	resp.ClientId = req.ClientId
+	return resp, nil
+}
 
-		// Substitui resultados anteriores usando as dependências
-		for _, dep := range step.DependsOn {
-			stepID := fmt.Sprintf("%s_%s", req.ExpressionId, dep.Reference[len("result_"):])
-			if result, ok := results[stepID]; ok {
-				numbers[dep.Position] = result
-			}
-		}
+// duplicateOutcome é o rótulo da métrica de repetições: aguardou a execução ou recebeu a resposta guardada
+func duplicateOutcome(rec results.Record) string {
+	if rec.Status.Terminal() {
+		return "replayed"
+	}
+	return "attached"
+}
 
-		// Cria contexto com timeout
-		deadline := time.Duration(req.DeadlineMs) * time.Millisecond
-		stepCtx, cancel := context.WithTimeout(ctx, deadline)
+// GetResult retorna o estado atual da expressão
+func (s *DispatcherServer) GetResult(ctx context.Context, req *pb.ResultRequest) (*pb.ExpressionStatus, error) {
+	rec, ok := s.results.Get(req.Ticket)
+	return s.expressionStatus(ctx, req, rec, ok)
+}
+
+// WaitResult aguarda a expressão terminar (até timeout_ms ou o deadline da chamada)
+func (s *DispatcherServer) WaitResult(ctx context.Context, req *pb.ResultRequest) (*pb.ExpressionStatus, error) {
+	// Confere o dono antes de aguardar
+	if _, err := s.GetResult(ctx, req); err != nil {
+		return nil, err
+	}
+	if req.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.TimeoutMs)*time.Millisecond)
 		defer cancel()
+	}
+	rec, ok := s.results.Wait(ctx, req.Ticket)
+	return s.expressionStatus(ctx, req, rec, ok)
+}
 
-		// Prepara requisição de operação
-		opReq := &pb.OperationRequest{
-			ExpressionId: req.ExpressionId,
-			StepId:       fmt.Sprintf("%s_step%d", req.ExpressionId, i),
-			Operation:    step.Operation,
-			Numbers:      numbers,
-			DeadlineMs:   req.DeadlineMs,
-		}
+// expressionStatus converte o registro; tickets de outro principal são tratados como inexistentes
+func (s *DispatcherServer) expressionStatus(ctx context.Context, req *pb.ResultRequest, rec results.Record, ok bool) (*pb.ExpressionStatus, error) {
+	if !ok || rec.Owner != principalFrom(ctx, req.ClientId, req.Ticket).ID {
+		return nil, status.Errorf(grpcCodes.NotFound, "ticket desconhecido ou expirado: %s", req.Ticket)
+	}
+	st := &pb.ExpressionStatus{Ticket: rec.ExpressionID, State: stateToProto(rec.Status)}
+	if rec.Status.Terminal() {
+		st.Response = responseFromRecord(rec)
+	}
+	return st, nil
+}
 
-		log.Printf("[DISPATCHER] [%s] Executando step %d: %s(%v)", clientID, i, step.Operation, numbers)
-
-		// Obtém cliente do servidor de operação
-		client, ok := s.serverClients[step.Operation]
-		if !ok {
-			return &pb.ExpressionResponse{
-				ExpressionId: req.ExpressionId,
-				Error: &pb.ErrorInfo{
-					Code:    "UNKNOWN_OPERATION",
-					Message: fmt.Sprintf("Operação desconhecida: %s", step.Operation),
-				},
-			}, nil
-		}
+func responseFromRecord(rec results.Record) *pb.ExpressionResponse {
+	return &pb.ExpressionResponse{
+		ExpressionId: rec.ExpressionID,
+		Result:       rec.Result,
+		Error:        grpcOps.ErrorToProto(rec.Error),
+		Trace:        grpcOps.TraceToProto(rec.Trace),
+	}
+}
 
-		// Executa operação
-		opResp, err := client.Execute(stepCtx, opReq)
-		if err != nil {
-			log.Printf("[DISPATCHER] [%s] Erro ao executar step %d: %v", clientID, i, err)
-			return &pb.ExpressionResponse{
-				ExpressionId: req.ExpressionId,
-				Error: &pb.ErrorInfo{
-					Code:    "EXECUTION_ERROR",
-					Message: fmt.Sprintf("Erro ao executar operação: %v", err),
-				},
-			}, nil
-		}
+func stateToProto(st results.Status) pb.ExpressionState {
+	return pb.ExpressionState(pb.ExpressionState_value[string(st)])
+}
 
-		if opResp.Error != nil {
-			log.Printf("[DISPATCHER] [%s] Erro retornado pelo servidor: %s - %s", clientID, opResp.Error.Code, opResp.Error.Message)
-			return &pb.ExpressionResponse{
-				ExpressionId: req.ExpressionId,
-				Error:        opResp.Error,
-			}, nil
-		}
+// principalFrom retorna o principal autenticado ou, sem autenticação, o client_id da
+// requisição (em clientes antigos, o embutido no expressionID)
+func principalFrom(ctx context.Context, clientID, expressionID string) auth.Principal {
+	if principal, ok := auth.FromContext(ctx); ok {
+		return principal
+	}
+	return auth.Unauthenticated(core.ResolveClientID(clientID, expressionID))
+}
+
+// calculate executa a expressão, registrando o resultado no result store; ctx vem de
+// cancellable. onEvent (opcional) recebe o progresso de cada step.
+func (s *DispatcherServer) calculate(ctx context.Context, req *pb.ExpressionRequest, onEvent func(core.StepEvent)) *pb.ExpressionResponse {
+	// O principal autenticado substitui o client_id informado na requisição
+	principal := principalFrom(ctx, req.ClientId, req.ExpressionId)
+	clientID := principal.ID
+	metrics.ClientExpressionsTotal.WithLabelValues("grpc", principal.MetricLabel()).Inc()
+
+	logger := s.logger.With(
+		slog.String(logging.KeyExpressionID, req.ExpressionId),
+		slog.String(logging.KeyClientID, clientID),
+	)
+
+	// Span da expressão: os spans de cada step ficam abaixo dele
+	ctx, exprSpan := telemetry.Tracer().Start(ctx, "expression", trace.WithAttributes(
+		telemetry.AttrExpressionID.String(req.ExpressionId),
+		telemetry.AttrClientID.String(clientID),
+	))
+	defer exprSpan.End()
+
+	logger.InfoContext(ctx, "Recebida expressão", slog.String("expression", logging.Truncate(req.Expression, 256)))
 
-		results[opReq.StepId] = opResp.Result
-		log.Printf("[DISPATCHER] [%s] Step %d completado: resultado = %f", clientID, i, opResp.Result)
+	start := time.Now()
+	pendingGauge := metrics.PendingExpressions.WithLabelValues("grpc")
+	pendingGauge.Inc()
+	defer pendingGauge.Dec()
+	pending := s.pending.Add(1)
+	defer s.pending.Add(-1)
 
-		// Se for o último step, retorna o resultado
-		if i == len(steps)-1 {
-			log.Printf("[DISPATCHER] [%s] Expressão calculada com sucesso: %f", clientID, opResp.Result)
-			return &pb.ExpressionResponse{
-				ExpressionId: req.ExpressionId,
-				Result:       opResp.Result,
-			}, nil
+	// Trace de execução (apenas se solicitado pelo cliente)
+	var stepTraces []*pb.StepTrace
+	respond := func(resp *pb.ExpressionResponse) *pb.ExpressionResponse {
+		code := metrics.CodeOK
+		if resp.Error != nil {
+			code = resp.Error.Code
+			exprSpan.SetAttributes(telemetry.AttrErrorCode.String(resp.Error.Code))
+			exprSpan.SetStatus(codes.Error, resp.Error.Message)
 		}
+		metrics.ExpressionsTotal.WithLabelValues("grpc", code).Inc()
+		metrics.ExpressionDuration.WithLabelValues("grpc").Observe(time.Since(start).Seconds())
+		if req.IncludeTrace {
+			resp.Trace = stepTraces
+		}
+		resp.ClientId = req.ClientId
+		s.results.Finish(core.ExpressionResponse{
+			ExpressionID: resp.ExpressionId,
+			Result:       resp.Result,
+			Error:        grpcOps.ErrorFromProto(resp.Error),
+			Trace:        grpcOps.TraceFromProto(resp.Trace),
+		})
+		return resp
 	}
 
-	// Fallback (não deveria chegar aqui)
-	return &pb.ExpressionResponse{
+	// Controle de admissão: rejeita novas expressões acima do limite de pendentes
+	if s.maxPending > 0 && pending > s.maxPending {
+		logger.WarnContext(ctx, "Dispatcher sobrecarregado", slog.Int64("pending", pending))
+		return respond(&pb.ExpressionResponse{
+			ExpressionId: req.ExpressionId,
+			Error: &pb.ErrorInfo{
+				Code:         core.CodeOverloaded,
+				Message:      fmt.Sprintf("Dispatcher sobrecarregado: %d expressões em andamento", pending-1),
+				RetryAfterMs: core.OverloadRetryAfterMs,
+			},
+		})
+	}
+
+	// Cotas do cliente: taxa (token bucket) e expressões simultâneas
+	release, limitErr := s.quota.Acquire(principal.ID, time.Now())
+	if limitErr != nil {
+		logger.WarnContext(ctx, "Expressão rejeitada por cota",
+			slog.String("code", limitErr.Code),
+			slog.Duration("retry_after", limitErr.RetryAfter))
+		metrics.QuotaRejectionsTotal.WithLabelValues("grpc", principal.MetricLabel(), limitErr.Code).Inc()
+		return respond(&pb.ExpressionResponse{
+			ExpressionId: req.ExpressionId,
+			Error: &pb.ErrorInfo{
+				Code:         limitErr.Code,
+				Message:      limitErr.Message,
+				RetryAfterMs: limitErr.RetryAfterMs(),
+			},
+		})
+	}
+	defer release()
+
+	// Parse da expressão
+	steps, rpnStr, err := s.parser.ParseWithRPN(req.Expression)
+	if err != nil {
+		logger.WarnContext(ctx, "Erro ao fazer parse da expressão", logging.Err(err))
+		return respond(&pb.ExpressionResponse{
+			ExpressionId: req.ExpressionId,
+			Error: &pb.ErrorInfo{
+				Code:    core.ParseErrorCode(err),
+				Message: fmt.Sprintf("Erro ao fazer parse da expressão: %v", err),
+			},
+		})
+	}
+
+	logger.DebugContext(ctx, "Expressão parseada", slog.String("rpn", rpnStr), slog.Int("steps", len(steps)))
+
+	// Executa os steps (o principal é repassado aos servidores de operação)
+	s.results.Running(req.ExpressionId)
+	resp := s.engine.Run(auth.NewContext(ctx, principal), &core.Execution{
+		ExpressionID: req.ExpressionId,
+		Steps:        steps,
+		DeadlineMs:   req.DeadlineMs,
+		Priority:     core.ClampPriority(int(req.Priority)),
+		IncludeTrace: req.IncludeTrace,
+		Logger:       logger,
+		OnEvent:      onEvent,
+	})
+	stepTraces = grpcOps.TraceToProto(resp.Trace)
+	if resp.Error != nil {
+		return respond(&pb.ExpressionResponse{
+			ExpressionId: req.ExpressionId,
+			Error:        grpcOps.ErrorToProto(resp.Error),
+		})
+	}
+
+	logger.InfoContext(ctx, "Expressão calculada com sucesso", slog.Float64("result", resp.Result))
+	return respond(&pb.ExpressionResponse{
 		ExpressionId: req.ExpressionId,
-		Error: &pb.ErrorInfo{
-			Code:    "INTERNAL_ERROR",
-			Message: "Erro interno ao processar expressão",
-		},
-	}, nil
+		Result:       resp.Result,
+	})
 }
 
 func main() {
-	log.Println("Iniciando Dispatcher gRPC...")
+	flag.Parse()
+
+	logger := logging.Setup("dispatcher")
+	logger.Info("Iniciando Dispatcher gRPC...")
+
+	// Configura o tracing distribuído
+	shutdownTracer, err := telemetry.InitTracer("grpc-dispatcher")
+	if err != nil {
+		logger.Error("Erro ao configurar tracing", logging.Err(err))
+		os.Exit(1)
+	}
+	defer shutdownTracer(context.Background())
+
+	// Expõe métricas Prometheus
+	metrics.Serve(*metricsAddr)
+
+	// Configura a autenticação (API keys e/ou JWT)
+	authenticator, err := auth.NewAuthenticatorFromEnv()
+	if err != nil {
+		logger.Error("Erro ao configurar autenticação", logging.Err(err))
+		os.Exit(1)
+	}
+	if !authenticator.Enabled() {
+		logger.Warn("Autenticação desativada: defina CALC_API_KEYS ou CALC_JWT_SECRET")
+	}
+
+	// Configura as cotas por cliente
+	quotaCfg, err := quota.ConfigFromEnv()
+	if err != nil {
+		logger.Error("Erro ao configurar cotas", logging.Err(err))
+		os.Exit(1)
+	}
+	if quotaCfg.Enabled() {
+		logger.Info("Cotas por cliente ativas", slog.Int("clients", len(quotaCfg.PerClient)))
+	}
+
+	// Limites de complexidade das expressões
+	limits, err := core.LimitsFromEnv()
+	if err != nil {
+		logger.Error("Erro ao configurar limites de complexidade", logging.Err(err))
+		os.Exit(1)
+	}
+
+	// Result store das expressões (persistido com -results-file)
+	var db *store.Store
+	if *resultsFile != "" {
+		db, err = store.Open(*resultsFile)
+		if err != nil {
+			logger.Error("Erro ao abrir arquivo de resultados", logging.Err(err))
+			os.Exit(1)
+		}
+		defer db.Close()
+	}
+	resultStore, err := results.NewStore(*retention, db)
+	if err != nil {
+		logger.Error("Erro ao carregar resultados persistidos", logging.Err(err))
+		os.Exit(1)
+	}
+	// O dispatcher gRPC não retoma expressões: as interrompidas por um reinício falham
+	if lost := resultStore.FailUnfinished(nil); lost > 0 {
+		logger.Warn("Expressões interrompidas pelo reinício", slog.Int("count", lost))
+	}
+	go resultStore.PruneEvery(context.Background(), time.Minute)
 
 	// Cria o servidor
-	server := NewDispatcherServer()
+	server := NewDispatcherServer(limits, quota.NewLimiter(quotaCfg), resultStore, *maxPending, *maxSteps)
 
 	// Aguarda um pouco para os servidores de operação iniciarem
-	log.Println("Aguardando servidores de operação...")
+	logger.Info("Aguardando servidores de operação...")
 	time.Sleep(2 * time.Second)
 
 	// Conecta aos servidores de operação
-	if err := server.connectToServers(); err != nil {
-		log.Fatalf("Erro ao conectar aos servidores: %v", err)
+	upstreamCreds, err := upstreamTLS.ClientCredentials()
+	if err != nil {
+		logger.Error("Erro ao configurar TLS dos servidores de operação", logging.Err(err))
+		os.Exit(1)
+	}
+	if err := server.connectToServers(upstreamCreds); err != nil {
+		logger.Error("Erro ao conectar aos servidores", logging.Err(err))
+		os.Exit(1)
 	}
 
 	// Cria listener
 	lis, err := net.Listen("tcp", port)
 	if err != nil {
-		log.Fatalf("Falha ao criar listener: %v", err)
+		logger.Error("Falha ao criar listener", logging.Err(err))
+		os.Exit(1)
+	}
+
+	// Credenciais de transporte (TLS/mTLS)
+	creds, err := tlsOpts.ServerCredentials()
+	if err != nil {
+		logger.Error("Erro ao configurar TLS", logging.Err(err))
+		os.Exit(1)
 	}
 
 	// Cria servidor gRPC
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.Creds(creds),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.UnaryInterceptor(grpcOps.AuthInterceptor(authenticator)),
+		grpc.StreamInterceptor(grpcOps.AuthStreamInterceptor(authenticator)),
+	)
 	pb.RegisterCalculatorServiceServer(grpcServer, server)
 
-	log.Printf("Dispatcher escutando em %s", port)
+	// Encerra graciosamente ao receber sinal, descarregando os spans pendentes
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		<-sigs
+		logger.Info("Encerrando dispatcher...")
+		grpcServer.GracefulStop()
+	}()
+
+	logger.Info("Dispatcher escutando",
+		slog.String("addr", port),
+		slog.String("tls", tlsOpts.ServerMode()),
+		slog.String("upstream_tls", upstreamTLS.ClientMode()))
 	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("Falha ao servir: %v", err)
+		logger.Error("Falha ao servir", logging.Err(err))
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/telemetry"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/tlsconfig"
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultDeadline = 30000   // 30 segundos em milissegundos
+	maxBodyBytes    = 1 << 20 // limite do corpo das requisições
+
+	// headerClientID identifica o dono das expressões quando a autenticação está desativada
+	headerClientID = "X-Client-Id"
+)
+
+var (
+	addr             = flag.String("addr", ":8080", "Endereço HTTP do gateway")
+	dispatcherAddr   = flag.String("dispatcher", "localhost:50051", "Endereço gRPC do dispatcher")
+	metricsAddr      = flag.String("metrics-addr", ":9106", "Endereço do endpoint /metrics (vazio desativa)")
+	maxBatch         = flag.Int("max-batch", 100, "Máximo de expressões por requisição de lote")
+	batchConcurrency = flag.Int("batch-concurrency", 16, "Expressões de um lote calculadas em paralelo")
+	priority         = flag.Int("priority", core.PriorityInteractive, "Prioridade usada quando a requisição não informa (0 a 9)")
+	httpCert         = flag.String("http-tls-cert", "", "Arquivo PEM do certificado HTTPS do gateway (ativa HTTPS)")
+	httpKey          = flag.String("http-tls-key", "", "Arquivo PEM da chave privada do certificado HTTPS")
+)
+
+// TLS da conexão com o dispatcher
+var tlsOpts = tlsconfig.BindClientFlags(flag.CommandLine, "")
+
+var (
+	unmarshalOpts = protojson.UnmarshalOptions{}
+	marshalOpts   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+)
+
+// gateway traduz as requisições HTTP/JSON em chamadas CalculatorService.Calculate
+type gateway struct {
+	client pb.CalculatorServiceClient
+	logger *slog.Logger
+	secure bool // conexão com o dispatcher usa TLS: só então o Authorization é repassado
+}
+
+// caller identifica quem fez a requisição HTTP. O dispatcher decide o dono de cada
+// expressão: o principal autenticado pelo Authorization ou, sem autenticação, o client_id.
+type caller struct {
+	authorization string
+	clientID      string // header X-Client-Id; vazio se não informado
+}
+
+func callerFrom(r *http.Request) caller {
+	return caller{authorization: r.Header.Get("Authorization"), clientID: r.Header.Get(headerClientID)}
+}
+
+// outgoing repassa o header Authorization ao dispatcher
+func (c caller) outgoing(ctx context.Context) context.Context {
+	if c.authorization != "" {
+		return metadata.AppendToOutgoingContext(ctx, auth.HeaderAuthorization, c.authorization)
+	}
+	return ctx
+}
+
+func main() {
+	flag.Parse()
+
+	logger := logging.Setup("gateway")
+	logger.Info("Gateway HTTP da Calculadora")
+
+	// Configura o tracing distribuído
+	shutdownTracer, err := telemetry.InitTracer("http-gateway")
+	if err != nil {
+		logger.Error("Erro ao configurar tracing", logging.Err(err))
+		os.Exit(1)
+	}
+	defer shutdownTracer(context.Background())
+
+	if (*httpCert == "") != (*httpKey == "") {
+		logger.Error("Informe -http-tls-cert e -http-tls-key juntos")
+		os.Exit(1)
+	}
+
+	// Conecta ao dispatcher; as credenciais vêm de cada requisição HTTP
+	transportCreds, err := tlsOpts.ClientCredentials()
+	if err != nil {
+		logger.Error("Erro ao configurar TLS", logging.Err(err))
+		os.Exit(1)
+	}
+	conn, err := grpc.Dial(*dispatcherAddr,
+		grpc.WithTransportCredentials(transportCreds),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5*time.Second),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+	if err != nil {
+		logger.Error("Falha ao conectar ao dispatcher", logging.Err(err))
+		os.Exit(1)
+	}
+	defer conn.Close()
+	logger.Info("Conectado ao dispatcher", slog.String("addr", *dispatcherAddr), slog.String("tls", tlsOpts.ClientMode()))
+	if !tlsOpts.ClientEnabled() {
+		logger.Warn("Conexão com o dispatcher sem TLS: requisições com Authorization serão recusadas")
+	}
+
+	g := &gateway{
+		client: pb.NewCalculatorServiceClient(conn),
+		logger: logger,
+		secure: tlsOpts.ClientEnabled(),
+	}
+
+	openAPI, err := json.MarshalIndent(openAPIDocument(), "", "  ")
+	if err != nil {
+		logger.Error("Erro ao gerar documento OpenAPI", logging.Err(err))
+		os.Exit(1)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/calculate", g.handleCalculate)
+	mux.HandleFunc("POST /v1/calculate:batch", g.handleBatch)
+	mux.HandleFunc("POST /v1/calculate:stream", g.handleStream)
+	mux.HandleFunc("GET /v1/expressions/{id}", g.handleGetExpression)
+	mux.HandleFunc("DELETE /v1/expressions/{id}", g.handleCancelExpression)
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPI)
+	})
+
+	metrics.Serve(*metricsAddr)
+
+	server := &http.Server{Addr: *addr, Handler: g.requireSecureCredentials(mux)}
+	go func() {
+		var err error
+		if *httpCert != "" {
+			logger.Info("Gateway escutando", slog.String("addr", *addr), slog.String("tls", "https"))
+			err = server.ListenAndServeTLS(*httpCert, *httpKey)
+		} else {
+			logger.Info("Gateway escutando", slog.String("addr", *addr), slog.String("tls", "desativado"))
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Erro no servidor HTTP", logging.Err(err))
+			os.Exit(1)
+		}
+	}()
+
+	// Aguarda sinal de encerramento e conclui as requisições em andamento
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+
+	logger.Info("Encerrando gateway...")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+}
+
+// requireSecureCredentials recusa as requisições com Authorization quando a conexão com
+// o dispatcher não usa TLS: o token ou o segredo da API key seguiria em texto puro
+func (g *gateway) requireSecureCredentials(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !g.secure && r.Header.Get("Authorization") != "" {
+			writeError(w, "", codeUnauthenticated, "Credenciais exigem TLS entre o gateway e o dispatcher (-tls-ca)")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleCalculate trata POST /v1/calculate
+func (g *gateway) handleCalculate(w http.ResponseWriter, r *http.Request) {
+	req, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+
+	resp := g.calculate(r.Context(), callerFrom(r), req)
+	writeResponse(w, resp)
+}
+
+// readRequest lê o ExpressionRequest do corpo; em caso de erro, já responde 400
+func readRequest(w http.ResponseWriter, r *http.Request) (*pb.ExpressionRequest, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		writeError(w, "", codeInvalidRequest, fmt.Sprintf("Erro ao ler requisição: %v", err))
+		return nil, false
+	}
+	req, err := decodeRequest(body)
+	if err != nil {
+		writeError(w, "", codeInvalidRequest, fmt.Sprintf("JSON inválido: %v", err))
+		return nil, false
+	}
+	return req, true
+}
+
+// requestPresence indica os campos do corpo cuja ausência é diferente do valor zero
+type requestPresence struct {
+	Priority *json.RawMessage `json:"priority"`
+}
+
+// decodeRequest lê um ExpressionRequest em JSON. Sem priority (ou com null), usa
+// -priority; priority 0 é mantida.
+func decodeRequest(body []byte) (*pb.ExpressionRequest, error) {
+	req := &pb.ExpressionRequest{}
+	if err := unmarshalOpts.Unmarshal(body, req); err != nil {
+		return nil, err
+	}
+	var presence requestPresence
+	if err := json.Unmarshal(body, &presence); err != nil {
+		return nil, err
+	}
+	if presence.Priority == nil {
+		req.Priority = int32(*priority)
+	}
+	return req, nil
+}
+
+// batchRequest é o corpo de POST /v1/calculate:batch; cada item é um ExpressionRequest
+type batchRequest struct {
+	Requests []json.RawMessage `json:"requests"`
+}
+
+// handleBatch trata POST /v1/calculate:batch: as expressões são calculadas em paralelo
+// e cada resposta traz seu próprio erro, na ordem das requisições
+func (g *gateway) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var batch batchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&batch); err != nil {
+		writeError(w, "", codeInvalidRequest, fmt.Sprintf("JSON inválido: %v", err))
+		return
+	}
+	if len(batch.Requests) == 0 {
+		writeError(w, "", codeInvalidRequest, "Lote vazio")
+		return
+	}
+	if len(batch.Requests) > *maxBatch {
+		writeError(w, "", codeBatchTooLarge, fmt.Sprintf("Lote com %d expressões excede o limite de %d", len(batch.Requests), *maxBatch))
+		return
+	}
+
+	c := callerFrom(r)
+	responses := make([]json.RawMessage, len(batch.Requests))
+	sem := make(chan struct{}, max(*batchConcurrency, 1))
+	var wg sync.WaitGroup
+	for i, raw := range batch.Requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var resp *pb.ExpressionResponse
+			req, err := decodeRequest(raw)
+			if err != nil {
+				resp = errorResponse("", codeInvalidRequest, fmt.Sprintf("JSON inválido: %v", err))
+			} else {
+				resp = g.calculate(r.Context(), c, req)
+			}
+			responses[i], _ = marshalOpts.Marshal(resp)
+		}()
+	}
+	wg.Wait()
+
+	writeJSON(w, http.StatusOK, struct {
+		Responses []json.RawMessage `json:"responses"`
+	}{responses})
+}
+
+// handleGetExpression trata GET /v1/expressions/{id} consultando o result store do
+// dispatcher (GetResult), que só responde ao dono da expressão
+func (g *gateway) handleGetExpression(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	c := callerFrom(r)
+	st, err := g.client.GetResult(c.outgoing(r.Context()), &pb.ResultRequest{Ticket: id, ClientId: c.clientID})
+	switch {
+	case status.Code(err) == codes.NotFound:
+		writeError(w, id, codeNotFound, "Expressão não encontrada (ou já expirada)")
+	case err != nil:
+		writeError(w, id, rpcErrorCode(err), status.Convert(err).Message())
+	case st.Response == nil:
+		// Ainda em andamento: QUEUED ou RUNNING
+		writeJSON(w, http.StatusAccepted, st)
+	default:
+		writeResponse(w, st.Response)
+	}
+}
+
+// handleCancelExpression cancela uma expressão em andamento (Cancel no dispatcher)
+func (g *gateway) handleCancelExpression(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	c := callerFrom(r)
+	resp, err := g.client.Cancel(c.outgoing(r.Context()), &pb.CancelRequest{ExpressionId: id, ClientId: c.clientID})
+	switch {
+	case status.Code(err) == codes.NotFound:
+		writeError(w, id, codeNotFound, "Expressão não encontrada (ou já expirada)")
+	case err != nil:
+		writeError(w, id, rpcErrorCode(err), status.Convert(err).Message())
+	default:
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// calculate chama o dispatcher, repassando o header Authorization.
+// Falhas da chamada gRPC viram uma resposta com erro, como os erros de negócio.
+func (g *gateway) calculate(ctx context.Context, c caller, req *pb.ExpressionRequest) *pb.ExpressionResponse {
+	ctx, cancel := g.prepare(ctx, c, req)
+	defer cancel()
+
+	resp, err := g.client.Calculate(ctx, req)
+	if err != nil {
+		resp = g.rpcError(ctx, req, err)
+	}
+	return resp
+}
+
+// prepare completa a requisição com os padrões do gateway e cria o contexto da chamada.
+// Sem client_id no corpo nem no header X-Client-Id, a expressão recebe um client_id
+// novo, devolvido na resposta: só quem o conhece consulta ou cancela a expressão.
+func (g *gateway) prepare(ctx context.Context, c caller, req *pb.ExpressionRequest) (context.Context, context.CancelFunc) {
+	if req.ExpressionId == "" {
+		req.ExpressionId = core.NewID()
+	}
+	if req.ClientId == "" {
+		req.ClientId = c.clientID
+	}
+	if req.ClientId == "" {
+		req.ClientId = core.NewID()
+	}
+	if req.DeadlineMs <= 0 {
+		req.DeadlineMs = defaultDeadline
+	}
+	return context.WithTimeout(c.outgoing(ctx), time.Duration(req.DeadlineMs)*time.Millisecond)
+}
+
+// rpcError converte a falha da chamada ao dispatcher em uma resposta com erro
+func (g *gateway) rpcError(ctx context.Context, req *pb.ExpressionRequest, err error) *pb.ExpressionResponse {
+	g.logger.WarnContext(ctx, "Erro ao calcular",
+		slog.String(logging.KeyExpressionID, req.ExpressionId), logging.Err(err))
+	resp := errorResponse(req.ExpressionId, rpcErrorCode(err), status.Convert(err).Message())
+	resp.ClientId = req.ClientId
+	return resp
+}
+
+func errorResponse(expressionID, code, message string) *pb.ExpressionResponse {
+	return &pb.ExpressionResponse{
+		ExpressionId: expressionID,
+		Error:        &pb.ErrorInfo{Code: code, Message: message},
+	}
+}
+
+// writeResponse escreve a resposta com o status HTTP correspondente ao código de erro
+func writeResponse(w http.ResponseWriter, resp *pb.ExpressionResponse) {
+	if retryAfter := resp.GetError().GetRetryAfterMs(); retryAfter > 0 {
+		// Retry-After é em segundos; arredonda para cima
+		w.Header().Set("Retry-After", fmt.Sprint((retryAfter+999)/1000))
+	}
+	writeJSON(w, httpStatus(resp.GetError().GetCode()), resp)
+}
+
+func writeError(w http.ResponseWriter, expressionID, code, message string) {
+	writeResponse(w, errorResponse(expressionID, code, message))
+}
+
+// writeJSON serializa mensagens protobuf com protojson e os demais valores com encoding/json
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	var body []byte
+	var err error
+	if m, ok := v.(proto.Message); ok {
+		body, err = marshalOpts.Marshal(m)
+	} else {
+		body, err = json.Marshal(v)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecodeRequestPriority(t *testing.T) {
+	tests := []struct {
+		body string
+		want int32
+	}{
+		{`{"expression": "1+1"}`, int32(*priority)},
+		{`{"expression": "1+1", "priority": null}`, int32(*priority)},
+		{`{"expression": "1+1", "priority": 0}`, 0},
+		{`{"expression": "1+1", "priority": 3}`, 3},
+		{`{"expression": "1+1", "priority": "5"}`, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			req, err := decodeRequest([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Priority != tt.want {
+				t.Errorf("Priority = %d, esperado %d", req.Priority, tt.want)
+			}
+		})
+	}
+	if _, err := decodeRequest([]byte(`{"expression": 1}`)); err == nil {
+		t.Error("JSON inválido aceito")
+	}
+}
+
+func TestRequireSecureCredentials(t *testing.T) {
+	tests := []struct {
+		name          string
+		secure        bool
+		authorization string
+		want          int
+	}{
+		{"sem TLS e sem credenciais", false, "", http.StatusOK},
+		{"sem TLS com credenciais", false, "Bearer token", http.StatusUnauthorized},
+		{"com TLS e credenciais", true, "Bearer token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &gateway{secure: tt.secure}
+			h := g.requireSecureCredentials(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			r := httptest.NewRequest(http.MethodPost, "/v1/calculate", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, esperado %d", w.Code, tt.want)
+			}
+		})
+	}
+}
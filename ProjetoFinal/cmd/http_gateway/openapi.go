@@ -0,0 +1,191 @@
+package main
+
+import (
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// openAPIDocument gera o documento OpenAPI 3 a partir dos descritores de calculator.proto:
+// os schemas são as mensagens alcançáveis a partir do CalculatorService, com os nomes de
+// campo do proto (os mesmos usados no JSON do gateway).
+func openAPIDocument() map[string]any {
+	service := pb.File_proto_calculator_proto.Services().ByName("CalculatorService")
+	method := service.Methods().ByName("Calculate")
+
+	schemas := map[string]any{}
+	for i := 0; i < service.Methods().Len(); i++ {
+		m := service.Methods().Get(i)
+		addSchema(schemas, m.Input())
+		addSchema(schemas, m.Output())
+	}
+
+	request := ref(method.Input())
+	response := ref(method.Output())
+	schemas["BatchRequest"] = map[string]any{
+		"type":       "object",
+		"required":   []string{"requests"},
+		"properties": map[string]any{"requests": map[string]any{"type": "array", "items": request}},
+	}
+	schemas["BatchResponse"] = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"responses": map[string]any{"type": "array", "items": response}},
+	}
+
+	jsonBody := func(schema any) map[string]any {
+		return map[string]any{"content": map[string]any{"application/json": map[string]any{"schema": schema}}}
+	}
+	errorResponse := func(description string) map[string]any {
+		r := jsonBody(response)
+		r["description"] = description
+		return r
+	}
+	calculateResponses := map[string]any{
+		"200": errorResponse("Resultado da expressão"),
+		"400": errorResponse("PARSE_ERROR, EXPRESSION_TOO_COMPLEX ou requisição inválida"),
+		"401": errorResponse("UNAUTHENTICATED"),
+		"409": errorResponse("DUPLICATE_EXPRESSION_ID (expression_id em uso por outro cliente ou outra expressão) ou CANCELLED"),
+		"422": errorResponse("DIV_BY_ZERO, INVALID_OPERATION ou UNKNOWN_OPERATION"),
+		"429": errorResponse("RATE_LIMITED ou RESOURCE_EXHAUSTED (com Retry-After)"),
+		"502": errorResponse("EXECUTION_ERROR ou falha do dispatcher"),
+		"503": errorResponse("OVERLOADED ou dispatcher indisponível (com Retry-After)"),
+		"504": errorResponse("DEADLINE_EXCEEDED"),
+	}
+
+	// Rejeições antes do primeiro step seguem /v1/calculate; depois, o erro vem no evento "result"
+	streamResponses := map[string]any{
+		"200": map[string]any{
+			"description": "Stream de eventos",
+			"content":     map[string]any{"text/event-stream": map[string]any{"schema": map[string]any{"type": "string"}}},
+		},
+	}
+	for code, r := range calculateResponses {
+		if code != "200" {
+			streamResponses[code] = r
+		}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Calculadora Distribuída - Gateway HTTP",
+			"version":     "v1",
+			"description": "Gerado a partir de " + pb.File_proto_calculator_proto.Path() + " (" + string(service.FullName()) + ")",
+		},
+		"paths": map[string]any{
+			"/v1/calculate": map[string]any{
+				"post": map[string]any{
+					"operationId": "Calculate",
+					"summary":     "Calcula uma expressão (" + string(method.FullName()) + ")",
+					"requestBody": jsonBody(request),
+					"responses":   calculateResponses,
+				},
+			},
+			"/v1/calculate:batch": map[string]any{
+				"post": map[string]any{
+					"operationId": "CalculateBatch",
+					"summary":     "Calcula várias expressões em paralelo; cada item traz seu próprio erro",
+					"requestBody": jsonBody(ref("BatchRequest")),
+					"responses": map[string]any{
+						"200": map[string]any{"description": "Respostas na ordem das requisições", "content": jsonBody(ref("BatchResponse"))["content"]},
+						"400": errorResponse("Requisição inválida"),
+						"413": errorResponse("BATCH_TOO_LARGE"),
+					},
+				},
+			},
+			"/v1/calculate:stream": map[string]any{
+				"post": map[string]any{
+					"operationId": "CalculateWithProgress",
+					"summary":     "Calcula uma expressão enviando o progresso como Server-Sent Events: \"step\" (StepProgress) e, por último, \"result\" (ExpressionResponse)",
+					"requestBody": jsonBody(request),
+					"responses":   streamResponses,
+				},
+			},
+			"/v1/expressions/{id}": map[string]any{
+				"parameters": []any{
+					map[string]any{"name": "id", "in": "path", "required": true, "schema": map[string]any{"type": "string"}},
+					map[string]any{
+						"name": headerClientID, "in": "header", "schema": map[string]any{"type": "string"},
+						"description": "client_id da expressão (sem autenticação, identifica o dono)",
+					},
+				},
+				"get": map[string]any{
+					"operationId": "GetExpression",
+					"summary":     "Resultado de uma expressão no result store do dispatcher (GetResult)",
+					"responses": map[string]any{
+						"200": errorResponse("Resultado armazenado (erros da expressão seguem o mapeamento de /v1/calculate)"),
+						"202": map[string]any{"description": "Expressão ainda em andamento (QUEUED ou RUNNING)", "content": jsonBody(ref("ExpressionStatus"))["content"]},
+						"404": errorResponse("NOT_FOUND"),
+					},
+				},
+				"delete": map[string]any{
+					"operationId": "CancelExpression",
+					"summary":     "Cancela uma expressão em andamento (Cancel); ela termina com CANCELLED",
+					"responses": map[string]any{
+						"200": map[string]any{"description": "Estado após o pedido (cancelled = false se a expressão já havia terminado)", "content": jsonBody(ref("CancelResponse"))["content"]},
+						"404": errorResponse("NOT_FOUND"),
+					},
+				},
+			},
+		},
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+// ref aponta para o schema de uma mensagem (descritor) ou de um nome
+func ref(target any) map[string]any {
+	name, ok := target.(string)
+	if !ok {
+		name = string(target.(protoreflect.MessageDescriptor).Name())
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// addSchema adiciona o schema da mensagem e, recursivamente, das mensagens que ela usa
+func addSchema(schemas map[string]any, md protoreflect.MessageDescriptor) {
+	name := string(md.Name())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+	properties := map[string]any{}
+	schemas[name] = map[string]any{"type": "object", "properties": properties}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		schema := fieldSchema(schemas, fd)
+		if fd.IsList() {
+			schema = map[string]any{"type": "array", "items": schema}
+		}
+		properties[string(fd.Name())] = schema
+	}
+}
+
+// fieldSchema segue o mapeamento JSON do protobuf (int64 é serializado como string)
+func fieldSchema(schemas map[string]any, fd protoreflect.FieldDescriptor) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.DoubleKind:
+		return map[string]any{"type": "number", "format": "double"}
+	case protoreflect.FloatKind:
+		return map[string]any{"type": "number", "format": "float"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]string, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind:
+		addSchema(schemas, fd.Message())
+		return ref(fd.Message())
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/quota"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/results"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Códigos de erro próprios do gateway
+const (
+	codeInvalidRequest  = "INVALID_REQUEST"
+	codeNotFound        = "NOT_FOUND"
+	codeBatchTooLarge   = "BATCH_TOO_LARGE"
+	codeUnauthenticated = "UNAUTHENTICATED"
+	codeDeadline        = "DEADLINE_EXCEEDED"
+	codeUnavailable     = "UNAVAILABLE"
+	codeUpstream        = "UPSTREAM_ERROR"
+)
+
+// httpStatus traduz ErrorInfo.Code para o status HTTP da resposta
+func httpStatus(code string) int {
+	switch code {
+	case "":
+		return http.StatusOK
+	case "PARSE_ERROR", core.CodeExpressionTooComplex, codeInvalidRequest:
+		return http.StatusBadRequest
+	case codeUnauthenticated:
+		return http.StatusUnauthorized
+	case codeNotFound:
+		return http.StatusNotFound
+	case results.CodeDuplicateExpressionID, core.CodeCancelled:
+		return http.StatusConflict
+	case codeBatchTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case "DIV_BY_ZERO", "INVALID_OPERATION", "UNKNOWN_OPERATION":
+		return http.StatusUnprocessableEntity
+	case quota.CodeRateLimited, quota.CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case core.CodeOverloaded, codeUnavailable:
+		return http.StatusServiceUnavailable
+	case core.CodeExecutionError, codeUpstream:
+		return http.StatusBadGateway
+	case codeDeadline:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// rpcErrorCode traduz a falha da chamada gRPC ao dispatcher em um código de erro
+func rpcErrorCode(err error) string {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		return codeUnauthenticated
+	case codes.DeadlineExceeded:
+		return codeDeadline
+	case codes.Canceled:
+		// Cancelada por DELETE/Cancel ou pela desconexão do cliente, não por prazo
+		return core.CodeCancelled
+	case codes.Unavailable:
+		return codeUnavailable
+	default:
+		return codeUpstream
+	}
+}
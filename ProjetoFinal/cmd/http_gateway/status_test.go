@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRPCErrorCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   string
+		wantStatus int
+	}{
+		{"não autenticado", status.Error(codes.Unauthenticated, "token inválido"), codeUnauthenticated, http.StatusUnauthorized},
+		{"prazo", status.Error(codes.DeadlineExceeded, "prazo"), codeDeadline, http.StatusGatewayTimeout},
+		{"prazo do contexto", status.FromContextError(context.DeadlineExceeded).Err(), codeDeadline, http.StatusGatewayTimeout},
+		{"cancelada", status.Error(codes.Canceled, "cancelada"), core.CodeCancelled, http.StatusConflict},
+		{"cancelamento do contexto", status.FromContextError(context.Canceled).Err(), core.CodeCancelled, http.StatusConflict},
+		{"indisponível", status.Error(codes.Unavailable, "conexão recusada"), codeUnavailable, http.StatusServiceUnavailable},
+		{"interno", status.Error(codes.Internal, "falha"), codeUpstream, http.StatusBadGateway},
+		{"erro sem status", errors.New("falha"), codeUpstream, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := rpcErrorCode(tt.err)
+			if code != tt.wantCode {
+				t.Fatalf("rpcErrorCode() = %q, esperado %q", code, tt.wantCode)
+			}
+			if got := httpStatus(code); got != tt.wantStatus {
+				t.Errorf("httpStatus(%q) = %d, esperado %d", code, got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code string
+		want int
+	}{
+		{"", http.StatusOK},
+		{"PARSE_ERROR", http.StatusBadRequest},
+		{core.CodeExpressionTooComplex, http.StatusBadRequest},
+		{codeInvalidRequest, http.StatusBadRequest},
+		{codeNotFound, http.StatusNotFound},
+		{"DUPLICATE_EXPRESSION_ID", http.StatusConflict},
+		{codeBatchTooLarge, http.StatusRequestEntityTooLarge},
+		{"DIV_BY_ZERO", http.StatusUnprocessableEntity},
+		{"UNKNOWN_OPERATION", http.StatusUnprocessableEntity},
+		{"RATE_LIMITED", http.StatusTooManyRequests},
+		{"RESOURCE_EXHAUSTED", http.StatusTooManyRequests},
+		{core.CodeOverloaded, http.StatusServiceUnavailable},
+		{core.CodeExecutionError, http.StatusBadGateway},
+		{"INTERNAL_ERROR", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := httpStatus(tt.code); got != tt.want {
+				t.Errorf("httpStatus(%q) = %d, esperado %d", tt.code, got, tt.want)
+			}
+		})
+	}
+}
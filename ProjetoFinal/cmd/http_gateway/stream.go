@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// handleStream trata POST /v1/calculate:stream: repassa o progresso de
+// CalculateWithProgress como Server-Sent Events. Cada step gera eventos "step"
+// (StepProgress) ao ser despachado e ao terminar; o último evento é "result"
+// (ExpressionResponse).
+func (g *gateway) handleStream(w http.ResponseWriter, r *http.Request) {
+	req, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	flusher, canFlush := w.(http.Flusher)
+	if !canFlush {
+		writeError(w, req.ExpressionId, codeUpstream, "Streaming não suportado pela conexão")
+		return
+	}
+
+	ctx, cancel := g.prepare(r.Context(), callerFrom(r), req)
+	defer cancel()
+
+	// Falhas antes do primeiro step (ex: UNAUTHENTICATED) ainda podem usar o status HTTP
+	stream, err := g.client.CalculateWithProgress(ctx, req)
+	var event *pb.ProgressEvent
+	if err == nil {
+		event, err = stream.Recv()
+	}
+	if err != nil {
+		writeResponse(w, g.rpcError(ctx, req, err))
+		return
+	}
+	// Rejeitada antes de executar qualquer step (ex: PARSE_ERROR): resposta JSON comum
+	if result := event.GetResult(); result != nil {
+		writeResponse(w, result)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		switch e := event.Event.(type) {
+		case *pb.ProgressEvent_Step:
+			writeEvent(w, "step", e.Step)
+		case *pb.ProgressEvent_Result:
+			writeEvent(w, "result", e.Result)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		if event, err = stream.Recv(); err != nil {
+			// Stream interrompido sem resposta final
+			writeEvent(w, "result", g.rpcError(ctx, req, err))
+			flusher.Flush()
+			return
+		}
+	}
+}
+
+// writeEvent escreve um evento SSE com a mensagem serializada em uma linha
+func writeEvent(w io.Writer, name string, m proto.Message) {
+	data, err := marshalOpts.Marshal(m)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
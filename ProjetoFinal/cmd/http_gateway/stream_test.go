@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// fakeStream entrega os eventos em ordem; depois deles, hold mantém o stream aberto
+// até o contexto terminar, e sem hold o stream termina com err
+type fakeStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	events []*pb.ProgressEvent
+	hold   bool
+	err    error
+}
+
+func (s *fakeStream) Recv() (*pb.ProgressEvent, error) {
+	if len(s.events) > 0 {
+		e := s.events[0]
+		s.events = s.events[1:]
+		return e, nil
+	}
+	if s.hold {
+		<-s.ctx.Done()
+		return nil, status.FromContextError(s.ctx.Err()).Err()
+	}
+	return nil, s.err
+}
+
+// fakeCalculator responde CalculateWithProgress com um fakeStream
+type fakeCalculator struct {
+	pb.CalculatorServiceClient
+	stream *fakeStream
+	err    error
+	ctx    chan context.Context // contexto de cada chamada
+}
+
+func (c *fakeCalculator) CalculateWithProgress(ctx context.Context, req *pb.ExpressionRequest, _ ...grpc.CallOption) (grpc.ServerStreamingClient[pb.ProgressEvent], error) {
+	if c.ctx != nil {
+		c.ctx <- ctx
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.stream.ctx = ctx
+	return c.stream, nil
+}
+
+func stepEvent(index int32, state pb.StepState) *pb.ProgressEvent {
+	return &pb.ProgressEvent{Event: &pb.ProgressEvent_Step{Step: &pb.StepProgress{
+		StepIndex: index, TotalSteps: 2, StepId: "s", Operation: "add", Operands: []float64{1, 2}, State: state,
+	}}}
+}
+
+func resultEvent(result float64, errInfo *pb.ErrorInfo) *pb.ProgressEvent {
+	return &pb.ProgressEvent{Event: &pb.ProgressEvent_Result{Result: &pb.ExpressionResponse{ExpressionId: "e1", Result: result, Error: errInfo}}}
+}
+
+// sseEvent é um evento lido do corpo SSE
+type sseEvent struct {
+	name string
+	data string
+}
+
+func readEvents(t *testing.T, r io.Reader) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			events = append(events, current)
+			current = sseEvent{}
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if current.data != "" {
+				t.Errorf("evento com mais de uma linha de dados: %q", line)
+			}
+			current.data = strings.TrimPrefix(line, "data: ")
+		default:
+			t.Errorf("linha SSE inesperada: %q", line)
+		}
+	}
+	return events
+}
+
+func newTestGateway(client pb.CalculatorServiceClient) *gateway {
+	return &gateway{client: client, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+func streamRequest(body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/v1/calculate:stream", strings.NewReader(body))
+}
+
+func TestHandleStreamEvents(t *testing.T) {
+	stream := &fakeStream{events: []*pb.ProgressEvent{
+		stepEvent(0, pb.StepState_STEP_DISPATCHED),
+		stepEvent(0, pb.StepState_STEP_COMPLETED),
+		resultEvent(3, nil),
+		stepEvent(1, pb.StepState_STEP_DISPATCHED), // depois do resultado: não é enviado
+	}}
+	w := httptest.NewRecorder()
+	newTestGateway(&fakeCalculator{stream: stream}).handleStream(w, streamRequest(`{"expression": "1+2"}`))
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !w.Flushed {
+		t.Error("eventos não foram enviados com Flush")
+	}
+	events := readEvents(t, w.Body)
+	if len(events) != 3 {
+		t.Fatalf("eventos = %+v, esperado 3", events)
+	}
+	for i, name := range []string{"step", "step", "result"} {
+		if events[i].name != name {
+			t.Errorf("evento %d = %q, esperado %q", i, events[i].name, name)
+		}
+	}
+	var step pb.StepProgress
+	if err := protojson.Unmarshal([]byte(events[1].data), &step); err != nil || step.State != pb.StepState_STEP_COMPLETED {
+		t.Errorf("step = %v, erro = %v", &step, err)
+	}
+	var result pb.ExpressionResponse
+	if err := protojson.Unmarshal([]byte(events[2].data), &result); err != nil || result.Result != 3 {
+		t.Errorf("resultado = %v, erro = %v", &result, err)
+	}
+}
+
+func TestHandleStreamInterrupted(t *testing.T) {
+	// Stream interrompido sem resposta final: o último evento é um result com erro
+	stream := &fakeStream{
+		events: []*pb.ProgressEvent{stepEvent(0, pb.StepState_STEP_DISPATCHED)},
+		err:    status.Error(codes.Unavailable, "dispatcher reiniciado"),
+	}
+	w := httptest.NewRecorder()
+	newTestGateway(&fakeCalculator{stream: stream}).handleStream(w, streamRequest(`{"expression": "1+2"}`))
+
+	events := readEvents(t, w.Body)
+	if len(events) != 2 || events[1].name != "result" {
+		t.Fatalf("eventos = %+v", events)
+	}
+	var result pb.ExpressionResponse
+	if err := protojson.Unmarshal([]byte(events[1].data), &result); err != nil || result.GetError().GetCode() != codeUnavailable {
+		t.Errorf("resultado = %v, erro = %v", &result, err)
+	}
+}
+
+func TestHandleStreamRejectedBeforeFirstStep(t *testing.T) {
+	tests := []struct {
+		name     string
+		client   *fakeCalculator
+		wantCode int
+	}{
+		{"erro de parse", &fakeCalculator{stream: &fakeStream{events: []*pb.ProgressEvent{
+			resultEvent(0, &pb.ErrorInfo{Code: "PARSE_ERROR", Message: "token inválido"}),
+		}}}, http.StatusBadRequest},
+		{"não autenticado", &fakeCalculator{err: status.Error(codes.Unauthenticated, "sem credenciais")}, http.StatusUnauthorized},
+		{"falha no primeiro evento", &fakeCalculator{stream: &fakeStream{err: status.Error(codes.Unavailable, "indisponível")}}, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newTestGateway(tt.client).handleStream(w, streamRequest(`{"expression": "1+"}`))
+			if w.Code != tt.wantCode || w.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("status = %d (%s), esperado %d em JSON", w.Code, w.Header().Get("Content-Type"), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestHandleStreamClientDisconnect(t *testing.T) {
+	calls := make(chan context.Context, 1)
+	client := &fakeCalculator{
+		stream: &fakeStream{events: []*pb.ProgressEvent{stepEvent(0, pb.StepState_STEP_DISPATCHED)}, hold: true},
+		ctx:    calls,
+	}
+	g := newTestGateway(client)
+	handlerDone := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(handlerDone)
+		g.handleStream(w, r)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader(`{"expression": "1+2"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// O primeiro step chega ao cliente antes do fim do stream (Flush)
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "event: step\n" {
+		t.Fatalf("primeira linha = %q, erro = %v", line, err)
+	}
+
+	cancel()
+	upstream := <-calls
+	select {
+	case <-handlerDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler continuou após a desconexão do cliente")
+	}
+	if upstream.Err() == nil {
+		t.Error("chamada ao dispatcher não foi cancelada com a desconexão")
+	}
+}
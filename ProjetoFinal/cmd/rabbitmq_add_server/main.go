@@ -1,11 +1,23 @@
 package main
 
 import (
-	"encoding/json"
-	"log"
-	"strings"
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
 
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
 	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/rabbitmq"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/telemetry"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -13,117 +25,297 @@ const (
 	operation   = "add"
 )
 
+var (
+	metricsAddr = flag.String("metrics-addr", ":9202", "Endereço do endpoint /metrics (vazio desativa)")
+	queueFlag   = flag.String("queue", "", "Fila consumida (vazio usa a fila ligada à rota da operação na topologia)")
+	workers     = flag.Int("workers", runtime.NumCPU(), "Goroutines processando entregas em paralelo")
+	prefetch    = flag.Int("prefetch", 0, "Máximo de entregas não confirmadas (0 usa 2x workers)")
+	dedupWindow = flag.Duration("dedup-window", 2*time.Minute, "Janela em que um StepID repetido recebe a resposta anterior sem nova execução (0 desativa)")
+)
+
+// OperationServer processa as operações recebidas pela fila
+type OperationServer struct {
+	conn      *rabbitmq.Connection
+	operation string
+	instance  string
+	dedup     *core.StepDedup[rabbitmq.OperationResponse]
+	cancelled *rabbitmq.CancelledExpressions
+	logger    *slog.Logger
+}
+
+// NewOperationServer cria um novo servidor de operação
+func NewOperationServer(conn *rabbitmq.Connection, op string, dedupWindow time.Duration) *OperationServer {
+	return &OperationServer{
+		conn:      conn,
+		operation: op,
+		instance:  core.InstanceID(op),
+		dedup:     core.NewStepDedup[rabbitmq.OperationResponse](dedupWindow),
+		cancelled: rabbitmq.NewCancelledExpressions(),
+		logger:    logging.Component(op),
+	}
+}
+
+// handle processa uma mensagem da fila de operação
+func (s *OperationServer) handle(msg amqp.Delivery) {
+	var req rabbitmq.OperationRequest
+	if err := rabbitmq.Decode(msg.ContentType, msg.Body, &req); err != nil {
+		s.logger.Error("Erro ao decodificar requisição", logging.Err(err))
+		msg.Nack(false, false)
+		return
+	}
+
+	// Continua o trace iniciado pelo dispatcher
+	ctx := rabbitmq.ExtractContext(context.Background(), msg.Headers)
+	ctx, span := telemetry.Tracer().Start(ctx, "execute "+s.operation,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			telemetry.AttrExpressionID.String(req.ExpressionID),
+			telemetry.AttrStepID.String(req.StepID),
+			telemetry.AttrOperation.String(req.Operation),
+		))
+	defer span.End()
+
+	start := time.Now()
+	defer func() {
+		metrics.OperationDuration.WithLabelValues("rabbitmq", s.operation).Observe(time.Since(start).Seconds())
+	}()
+
+	clientID := rabbitmq.PrincipalFromHeaders(msg.Headers, core.ClientIDFromExpressionID(req.ExpressionID))
+
+	s.logger.DebugContext(ctx, "Recebida operação",
+		slog.String(logging.KeyExpressionID, req.ExpressionID),
+		slog.String(logging.KeyStepID, req.StepID),
+		slog.String(logging.KeyClientID, clientID),
+		slog.String(logging.KeyOperation, req.Operation),
+		slog.Any("numbers", req.Numbers))
+
+	// Step de uma expressão cancelada enquanto aguardava na fila: descarta sem responder
+	if s.cancelled.Cancelled(req.ExpressionID, msg.Headers) {
+		s.logger.InfoContext(ctx, "Step de expressão cancelada descartado",
+			slog.String(logging.KeyStepID, req.StepID),
+			slog.String(logging.KeyClientID, clientID))
+		metrics.CancelledStepsTotal.WithLabelValues("rabbitmq", s.operation).Inc()
+		msg.Ack(false)
+		return
+	}
+
+	// Steps repetidos (retry do dispatcher ou reentrega) recebem a resposta da primeira execução
+	resp, duplicate, _ := s.dedup.Do(ctx, req.StepID, req.Operation, req.Numbers, func() (rabbitmq.OperationResponse, error) {
+		return s.execute(ctx, req, clientID), nil
+	})
+	if duplicate {
+		metrics.DuplicateStepsTotal.WithLabelValues("rabbitmq", s.operation).Inc()
+		s.logger.InfoContext(ctx, "Step repetido, reenviando resposta anterior",
+			slog.String(logging.KeyStepID, req.StepID),
+			slog.String(logging.KeyClientID, clientID))
+	}
+	s.sendResponse(ctx, rabbitmq.ReplyQueue(req), msg.ContentType, resp)
+	msg.Ack(false)
+}
+
+// execute valida e executa a operação do step
+func (s *OperationServer) execute(ctx context.Context, req rabbitmq.OperationRequest, clientID string) rabbitmq.OperationResponse {
+	span := trace.SpanFromContext(ctx)
+
+	// Valida operação
+	if req.Operation != s.operation {
+		s.logger.WarnContext(ctx, "Operação inválida",
+			slog.String(logging.KeyStepID, req.StepID),
+			slog.String(logging.KeyClientID, clientID),
+			slog.String("expected", s.operation),
+			slog.String(logging.KeyOperation, req.Operation))
+		span.SetStatus(codes.Error, "INVALID_OPERATION")
+		return rabbitmq.OperationResponse{
+			ExpressionID: req.ExpressionID,
+			StepID:       req.StepID,
+			Server:       s.instance,
+			Error: &rabbitmq.ErrorInfo{
+				Code:    "INVALID_OPERATION",
+				Message: "Este servidor só processa operações " + s.operation,
+			},
+		}
+	}
+
+	// Executa operação
+	result, err := rabbitmq.ExecuteOperation(req.Operation, req.Numbers)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Erro ao executar operação",
+			slog.String(logging.KeyStepID, req.StepID),
+			slog.String(logging.KeyClientID, clientID),
+			slog.String(logging.KeyOperation, req.Operation),
+			logging.Err(err))
+		errorCode := "EXECUTION_ERROR"
+		if err.Error() == "divisão por zero" {
+			errorCode = "DIV_BY_ZERO"
+		}
+		resp := rabbitmq.OperationResponse{
+			ExpressionID: req.ExpressionID,
+			StepID:       req.StepID,
+			Server:       s.instance,
+			Error: &rabbitmq.ErrorInfo{
+				Code:    errorCode,
+				Message: err.Error(),
+			},
+		}
+		span.SetAttributes(telemetry.AttrErrorCode.String(errorCode))
+		span.SetStatus(codes.Error, err.Error())
+		return resp
+	}
+
+	s.logger.DebugContext(ctx, "Operação executada com sucesso",
+		slog.String(logging.KeyStepID, req.StepID),
+		slog.String(logging.KeyClientID, clientID),
+		slog.String(logging.KeyOperation, req.Operation),
+		slog.Float64("result", result))
+
+	return rabbitmq.OperationResponse{
+		ExpressionID: req.ExpressionID,
+		StepID:       req.StepID,
+		Result:       result,
+		Server:       s.instance,
+	}
+}
+
+// handleCancel registra um cancelamento repassado pelo dispatcher (calculator.cancel.steps)
+func (s *OperationServer) handleCancel(msg amqp.Delivery) {
+	var req rabbitmq.CancelRequest
+	if err := rabbitmq.Decode(msg.ContentType, msg.Body, &req); err != nil {
+		s.logger.Error("Erro ao decodificar cancelamento", logging.Err(err))
+		msg.Nack(false, false)
+		return
+	}
+	s.logger.Debug("Expressão cancelada", slog.String(logging.KeyExpressionID, req.ExpressionID))
+	s.cancelled.Add(req.ExpressionID, msg.Headers)
+	msg.Ack(false)
+}
+
+// sendResponse publica o resultado na fila de resultados do dispatcher que enviou o step,
+// na mesma codificação da requisição
+func (s *OperationServer) sendResponse(ctx context.Context, queue, contentType string, resp rabbitmq.OperationResponse) {
+	code := metrics.CodeOK
+	if resp.Error != nil {
+		code = resp.Error.Code
+	}
+	metrics.OperationsTotal.WithLabelValues("rabbitmq", s.operation, code).Inc()
+
+	respBytes, err := rabbitmq.Encode(contentType, &resp)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Erro ao serializar resposta", slog.String(logging.KeyStepID, resp.StepID), logging.Err(err))
+		return
+	}
+
+	if err := s.conn.Send(ctx, rabbitmq.Message{RoutingKey: queue, Body: respBytes, ContentType: contentType}); err != nil {
+		s.logger.ErrorContext(ctx, "Erro ao publicar resposta", slog.String(logging.KeyStepID, resp.StepID), logging.Err(err))
+	}
+}
+
 func main() {
-	serverName := strings.ToUpper(operation)
-	log.Printf("[%s] Iniciando servidor de operação: %s", serverName, operation)
+	flag.Parse()
+
+	logger := logging.Setup(operation)
+	logger.Info("Iniciando servidor de operação", slog.String(logging.KeyOperation, operation))
+
+	// Configura o tracing distribuído
+	shutdownTracer, err := telemetry.InitTracer("rabbitmq-" + operation + "-server")
+	if err != nil {
+		logger.Error("Erro ao configurar tracing", logging.Err(err))
+		os.Exit(1)
+	}
+	defer shutdownTracer(context.Background())
+
+	// Expõe métricas Prometheus
+	metrics.Serve(*metricsAddr)
 
 	// Conecta ao RabbitMQ
 	conn, err := rabbitmq.NewConnection(rabbitmqURL)
 	if err != nil {
-		log.Fatalf("[%s] Erro ao conectar ao RabbitMQ: %v", serverName, err)
+		logger.Error("Erro ao conectar ao RabbitMQ", logging.Err(err))
+		os.Exit(1)
 	}
 	defer conn.Close()
 
-	// Declara fila de operação
-	queue := rabbitmq.GetQueueForOperation(operation)
-	if err := conn.DeclareQueue(queue); err != nil {
-		log.Fatalf("[%s] Erro ao declarar fila: %v", serverName, err)
+	// Declara o exchange e as filas de operação da topologia
+	topology, err := rabbitmq.LoadTopology()
+	if err != nil {
+		logger.Error("Erro ao carregar topologia", logging.Err(err))
+		os.Exit(1)
+	}
+	if err := rabbitmq.DeclareTopology(conn, topology); err != nil {
+		logger.Error("Erro ao declarar topologia", logging.Err(err))
+		os.Exit(1)
+	}
+
+	// Fila consumida: a informada em -queue (ex: consumidor canário) ou a da rota da operação
+	queue := *queueFlag
+	if queue == "" {
+		queue = topology.QueueFor(operation)
+	}
+	if queue == "" {
+		logger.Error("Nenhuma fila da topologia recebe a operação", slog.String(logging.KeyOperation, operation))
+		os.Exit(1)
+	}
+	if err := conn.DeclarePriorityQueue(queue); err != nil {
+		logger.Error("Erro ao declarar fila", slog.String("queue", queue), logging.Err(err))
+		os.Exit(1)
 	}
 
-	// Declara fila de resultados
+	// Declara fila de resultados compartilhada (dispatchers com -instance declaram a própria)
 	if err := conn.DeclareQueue(rabbitmq.ResultsQueue); err != nil {
-		log.Fatalf("[%s] Erro ao declarar fila de resultados: %v", serverName, err)
+		logger.Error("Erro ao declarar fila de resultados", logging.Err(err))
+		os.Exit(1)
+	}
+
+	// Limita as entregas em andamento para que um servidor lento não acumule mensagens
+	*workers, *prefetch = rabbitmq.Concurrency(*workers, *prefetch)
+	if err := conn.SetPrefetch(*prefetch); err != nil {
+		logger.Error("Erro ao configurar prefetch", logging.Err(err))
+		os.Exit(1)
 	}
 
 	// Consome mensagens da fila
 	msgs, err := conn.Consume(queue)
 	if err != nil {
-		log.Fatalf("[%s] Erro ao consumir fila: %v", serverName, err)
+		logger.Error("Erro ao consumir fila", slog.String("queue", queue), logging.Err(err))
+		os.Exit(1)
 	}
 
-	log.Printf("[%s] Servidor %s pronto para receber operações", serverName, operation)
-
-	forever := make(chan bool)
+	// Cancelamentos de expressões: fila temporária ligada ao exchange fanout
+	if err := conn.DeclareExchange(rabbitmq.StepCancelExchange, "fanout"); err != nil {
+		logger.Error("Erro ao declarar exchange de cancelamentos", logging.Err(err))
+		os.Exit(1)
+	}
+	cancelQueue, err := conn.DeclareTemporaryQueue()
+	if err != nil {
+		logger.Error("Erro ao declarar fila de cancelamentos", logging.Err(err))
+		os.Exit(1)
+	}
+	if err := conn.BindQueue(cancelQueue, "", rabbitmq.StepCancelExchange); err != nil {
+		logger.Error("Erro ao ligar fila de cancelamentos", slog.String("queue", cancelQueue), logging.Err(err))
+		os.Exit(1)
+	}
+	cancels, err := conn.Consume(cancelQueue)
+	if err != nil {
+		logger.Error("Erro ao consumir fila de cancelamentos", logging.Err(err))
+		os.Exit(1)
+	}
 
+	server := NewOperationServer(conn, operation, *dedupWindow)
 	go func() {
-		for msg := range msgs {
-			var req rabbitmq.OperationRequest
-			if err := json.Unmarshal(msg.Body, &req); err != nil {
-				log.Printf("[%s] Erro ao decodificar requisição: %v", serverName, err)
-				msg.Nack(false, false)
-				continue
-			}
-
-			// Extrai clientID
-			clientID := "UNKNOWN"
-			parts := strings.Split(req.ExpressionID, "_expr_")
-			if len(parts) > 0 {
-				clientID = parts[0]
-			}
-
-			log.Printf("[%s] [%s] Recebida operação: %s(%v) [Step: %s]", serverName, clientID, req.Operation, req.Numbers, req.StepID)
-
-			// Valida operação
-			if req.Operation != operation {
-				log.Printf("[%s] [%s] Operação inválida: esperado %s, recebido %s", serverName, clientID, operation, req.Operation)
-				resp := rabbitmq.OperationResponse{
-					ExpressionID: req.ExpressionID,
-					StepID:       req.StepID,
-					Error: &rabbitmq.ErrorInfo{
-						Code:    "INVALID_OPERATION",
-						Message: "Este servidor só processa operações " + operation,
-					},
-				}
-				sendResponse(conn, resp, serverName)
-				msg.Ack(false)
-				continue
-			}
-
-			// Executa operação
-			result, err := rabbitmq.ExecuteOperation(req.Operation, req.Numbers)
-			if err != nil {
-				log.Printf("[%s] [%s] Erro ao executar operação: %v", serverName, clientID, err)
-				errorCode := "EXECUTION_ERROR"
-				if err.Error() == "divisão por zero" {
-					errorCode = "DIV_BY_ZERO"
-				}
-				resp := rabbitmq.OperationResponse{
-					ExpressionID: req.ExpressionID,
-					StepID:       req.StepID,
-					Error: &rabbitmq.ErrorInfo{
-						Code:    errorCode,
-						Message: err.Error(),
-					},
-				}
-				sendResponse(conn, resp, serverName)
-				msg.Ack(false)
-				continue
-			}
-
-			log.Printf("[%s] [%s] Operação executada com sucesso: %f", serverName, clientID, result)
-
-			// Envia resultado
-			resp := rabbitmq.OperationResponse{
-				ExpressionID: req.ExpressionID,
-				StepID:       req.StepID,
-				Result:       result,
-			}
-			sendResponse(conn, resp, serverName)
-			msg.Ack(false)
+		for msg := range cancels {
+			server.handleCancel(msg)
 		}
 	}()
 
-	<-forever
-}
+	logger.Info("Servidor pronto para receber operações",
+		slog.String("queue", queue),
+		slog.Int("workers", *workers),
+		slog.Int("prefetch", *prefetch))
 
-func sendResponse(conn *rabbitmq.Connection, resp rabbitmq.OperationResponse, serverName string) {
-	respBytes, err := json.Marshal(resp)
-	if err != nil {
-		log.Printf("[%s] Erro ao serializar resposta: %v", serverName, err)
-		return
-	}
+	rabbitmq.ServeWorkers(msgs, *workers, server.handle)
 
-	if err := conn.Publish(rabbitmq.ResultsQueue, respBytes); err != nil {
-		log.Printf("[%s] Erro ao publicar resposta: %v", serverName, err)
-	}
+	// Aguarda sinal de encerramento para descarregar os spans pendentes
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	<-sigs
+	logger.Info("Encerrando servidor...")
 }
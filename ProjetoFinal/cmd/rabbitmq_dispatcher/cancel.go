@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// processCancelRequest cancela uma expressão em andamento nesta instância: o engine
+// para de despachar steps e a expressão responde CANCELLED em calculator.responses.
+// O pedido chega a todas as instâncias; as que não têm a expressão o ignoram.
+func (d *Dispatcher) processCancelRequest(ctx context.Context, contentType string, headers amqp.Table, msg []byte) {
+	var req rabbitmq.CancelRequest
+	if err := rabbitmq.Decode(contentType, msg, &req); err != nil {
+		d.logger.Error("Erro ao decodificar pedido de cancelamento", logging.Err(err))
+		return
+	}
+	logger := d.logger.With(slog.String(logging.KeyExpressionID, req.ExpressionID))
+
+	d.pendingMutex.RLock()
+	pending, ok := d.pendingSteps[req.ExpressionID]
+	d.pendingMutex.RUnlock()
+	if !ok {
+		logger.DebugContext(ctx, "Cancelamento de expressão que não está em andamento nesta instância")
+		return
+	}
+
+	principal := auth.Unauthenticated(core.ResolveClientID(req.ClientID, req.ExpressionID))
+	if d.auth.Enabled() {
+		var err error
+		principal, err = rabbitmq.Authenticate(d.auth, headers, msg)
+		if err != nil {
+			logger.WarnContext(ctx, "Autenticação do cancelamento falhou", logging.Err(err))
+			metrics.AuthFailuresTotal.WithLabelValues("rabbitmq", auth.Reason(err)).Inc()
+			return
+		}
+	}
+	if principal.ID != pending.Principal.ID {
+		logger.WarnContext(ctx, "Cancelamento de expressão de outro principal ignorado", slog.String(logging.KeyClientID, principal.ID))
+		return
+	}
+
+	logger.InfoContext(ctx, "Cancelamento solicitado")
+	pending.cancel(core.ErrCancelled)
+
+	// Os servidores de operação descartam os steps da expressão ainda na fila
+	body, err := rabbitmq.Encode(pending.ContentType, &req)
+	if err != nil {
+		logger.ErrorContext(ctx, "Erro ao serializar cancelamento dos steps", logging.Err(err))
+		return
+	}
+	if err := d.conn.Send(ctx, rabbitmq.Message{
+		Exchange:    rabbitmq.StepCancelExchange,
+		Body:        body,
+		Headers:     amqp.Table{rabbitmq.HeaderPublishedAt: time.Now().UnixNano()},
+		ContentType: pending.ContentType,
+	}); err != nil {
+		logger.ErrorContext(ctx, "Erro ao publicar cancelamento dos steps", logging.Err(err))
+	}
+}
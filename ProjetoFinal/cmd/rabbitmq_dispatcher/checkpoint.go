@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/rabbitmq"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/results"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/telemetry"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Bucket do estado persistido das expressões em andamento
+const checkpointBucket = "pending_expressions"
+
+// expressionCheckpoint é o estado persistido de uma PendingStep, gravado a cada step concluído
+type expressionCheckpoint struct {
+	ExpressionID string               `json:"expression_id"`
+	Steps        []core.Step          `json:"steps"`
+	Results      map[string]float64   `json:"results"`
+	StartTime    time.Time            `json:"start_time"`
+	DeadlineMs   int64                `json:"deadline_ms"` // timeout de cada step
+	Priority     int                  `json:"priority,omitempty"`
+	ContentType  string               `json:"content_type,omitempty"`
+	ReplyTo      string               `json:"reply_to,omitempty"`
+	IncludeTrace bool                 `json:"include_trace,omitempty"`
+	Trace        []rabbitmq.StepTrace `json:"trace,omitempty"`
+	Principal    auth.Principal       `json:"principal"`
+	ClientID     string               `json:"client_id,omitempty"`
+	TraceContext map[string]string    `json:"trace_context,omitempty"`
+}
+
+// newCheckpoint copia o estado da expressão (chamado pela goroutine que a executa,
+// ou antes de ela iniciar)
+func newCheckpoint(pending *PendingStep) *expressionCheckpoint {
+	x := pending.Exec
+	results := make(map[string]float64, len(x.Results))
+	for k, v := range x.Results {
+		results[k] = v
+	}
+	return &expressionCheckpoint{
+		ExpressionID: x.ExpressionID,
+		Steps:        x.Steps,
+		Results:      results,
+		StartTime:    pending.StartTime,
+		DeadlineMs:   x.DeadlineMs,
+		Priority:     x.Priority,
+		ContentType:  pending.ContentType,
+		ReplyTo:      pending.ReplyTo,
+		IncludeTrace: x.IncludeTrace,
+		Trace:        rabbitmq.TraceFromCore(x.Trace),
+		Principal:    pending.Principal,
+		ClientID:     pending.ClientID,
+		TraceContext: pending.TraceContext,
+	}
+}
+
+func (d *Dispatcher) saveCheckpoint(cp *expressionCheckpoint) {
+	if d.state == nil {
+		return
+	}
+	if err := d.state.Put(checkpointBucket, cp.ExpressionID, cp); err != nil {
+		d.logger.Error("Erro ao gravar checkpoint", slog.String(logging.KeyExpressionID, cp.ExpressionID), logging.Err(err))
+	}
+}
+
+func (d *Dispatcher) deleteCheckpoint(expressionID string) {
+	if d.state == nil {
+		return
+	}
+	if err := d.state.Delete(checkpointBucket, expressionID); err != nil {
+		d.logger.Error("Erro ao remover checkpoint", slog.String(logging.KeyExpressionID, expressionID), logging.Err(err))
+	}
+}
+
+// restore recarrega as expressões persistidas e as retoma a partir do último step concluído.
+// O step em andamento no momento da queda é republicado; se o resultado original ainda
+// estiver na fila, a cópia excedente é descartada pelo executor. DeadlineMs é o timeout de
+// cada step (como no engine), então a idade da expressão não impede a retomada.
+func (d *Dispatcher) restore() error {
+	if d.state == nil {
+		return nil
+	}
+
+	var checkpoints []expressionCheckpoint
+	discarded := make(map[string][]byte)
+	err := d.state.ForEach(checkpointBucket, func(key string, value []byte) error {
+		var cp expressionCheckpoint
+		if err := json.Unmarshal(value, &cp); err != nil {
+			d.logger.Warn("Checkpoint inválido descartado", slog.String(logging.KeyExpressionID, key), logging.Err(err))
+			discarded[key] = value
+			return nil
+		}
+		checkpoints = append(checkpoints, cp)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, value := range discarded {
+		d.deleteCheckpoint(key)
+		d.failCheckpoint(key, value)
+	}
+
+	for _, cp := range checkpoints {
+		logger := d.logger.With(
+			slog.String(logging.KeyExpressionID, cp.ExpressionID),
+			slog.String(logging.KeyClientID, cp.Principal.ID),
+		)
+
+		ctx := telemetry.ExtractMap(context.Background(), cp.TraceContext)
+		_, span := telemetry.Tracer().Start(ctx, "expression", trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				telemetry.AttrExpressionID.String(cp.ExpressionID),
+				telemetry.AttrClientID.String(cp.Principal.ID),
+				telemetry.AttrResumed.Bool(true),
+			))
+
+		pending := &PendingStep{
+			Exec: &core.Execution{
+				ExpressionID: cp.ExpressionID,
+				Steps:        cp.Steps,
+				Results:      cp.Results,
+				DeadlineMs:   cp.DeadlineMs,
+				Priority:     cp.Priority,
+				IncludeTrace: cp.IncludeTrace,
+				Trace:        rabbitmq.TraceToCore(cp.Trace),
+				Logger:       logger,
+			},
+			StartTime:    cp.StartTime,
+			Span:         span,
+			Logger:       logger,
+			Principal:    cp.Principal,
+			ClientID:     core.ResolveClientID(cp.ClientID, cp.ExpressionID),
+			Release:      func() {},
+			ContentType:  cp.ContentType,
+			ReplyTo:      cp.ReplyTo,
+			TraceContext: cp.TraceContext,
+		}
+		d.register(pending)
+
+		logger.Info("Expressão retomada", slog.Int("completed", pending.Exec.Completed()), slog.Int("total", len(cp.Steps)))
+		go d.run(pending)
+	}
+
+	return nil
+}
+
+// failCheckpoint responde FAILED (INTERRUPTED) à expressão de um checkpoint que não pode
+// ser retomado, para que o cliente e o result store não fiquem sem resposta
+func (d *Dispatcher) failCheckpoint(expressionID string, value []byte) {
+	// Aproveita o que for possível ler do checkpoint; sem content type, responde em JSON
+	var partial struct {
+		ClientID    string `json:"client_id"`
+		ContentType string `json:"content_type"`
+		ReplyTo     string `json:"reply_to"`
+	}
+	json.Unmarshal(value, &partial)
+	if !rabbitmq.IsResponseReplyQueue(partial.ReplyTo) {
+		partial.ReplyTo = ""
+	}
+	d.sendErrorResponse(expressionID, core.ResolveClientID(partial.ClientID, expressionID), partial.ContentType, partial.ReplyTo,
+		results.CodeInterrupted, "Expressão interrompida por reinício do dispatcher (checkpoint inválido)")
+}
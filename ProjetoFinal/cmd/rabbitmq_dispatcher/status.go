@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/rabbitmq"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/results"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Código da resposta a consultas de tickets desconhecidos ou expirados
+const codeNotFound = "NOT_FOUND"
+
+// maxStatusWait limita o timeout_ms de WaitResult: cada espera ocupa uma goroutine
+const maxStatusWait = time.Minute
+
+// processStatusRequest responde a uma consulta de resultado (GetResult, ou WaitResult
+// com timeout_ms) na fila reply_to. A consulta chega a todas as instâncias; com
+// várias, só a que tem o ticket responde.
+func (d *Dispatcher) processStatusRequest(ctx context.Context, contentType string, headers amqp.Table, msg []byte) {
+	var req rabbitmq.ResultRequest
+	if err := rabbitmq.Decode(contentType, msg, &req); err != nil {
+		d.logger.Error("Erro ao decodificar consulta de resultado", logging.Err(err))
+		return
+	}
+	// reply_to vem do cliente, mesmo sem autenticação: só aceita filas de resposta de consultas
+	if !rabbitmq.IsStatusReplyQueue(req.ReplyTo) {
+		d.logger.Warn("Consulta de resultado com reply_to inválido",
+			slog.String(logging.KeyExpressionID, req.Ticket), slog.String("reply_to", req.ReplyTo))
+		return
+	}
+
+	principal := auth.Unauthenticated(core.ResolveClientID(req.ClientID, req.Ticket))
+	if d.auth.Enabled() {
+		var err error
+		principal, err = rabbitmq.Authenticate(d.auth, headers, msg)
+		if err != nil {
+			metrics.AuthFailuresTotal.WithLabelValues("rabbitmq", auth.Reason(err)).Inc()
+			d.replyStatus(ctx, contentType, req.ReplyTo, &rabbitmq.ExpressionStatus{
+				Ticket: req.Ticket,
+				Error:  &rabbitmq.ErrorInfo{Code: "UNAUTHENTICATED", Message: fmt.Sprintf("Autenticação falhou: %v", err)},
+			})
+			return
+		}
+	}
+
+	// Tickets de outro principal são tratados como inexistentes
+	rec, ok := d.results.Get(req.Ticket)
+	if !ok || rec.Owner != principal.ID {
+		if d.soleInstance {
+			d.replyStatus(ctx, contentType, req.ReplyTo, &rabbitmq.ExpressionStatus{
+				Ticket: req.Ticket,
+				Error:  &rabbitmq.ErrorInfo{Code: codeNotFound, Message: fmt.Sprintf("Ticket desconhecido ou expirado: %s", req.Ticket)},
+			})
+		}
+		return
+	}
+
+	if req.TimeoutMs <= 0 || rec.Status.Terminal() {
+		d.replyStatus(ctx, contentType, req.ReplyTo, statusFromRecord(rec))
+		return
+	}
+
+	// WaitResult: aguarda fora do consumidor para não bloquear as demais consultas
+	go func() {
+		waitCtx, cancel := context.WithTimeout(ctx, min(time.Duration(req.TimeoutMs)*time.Millisecond, maxStatusWait))
+		defer cancel()
+		rec, _ := d.results.Wait(waitCtx, req.Ticket)
+		d.replyStatus(ctx, contentType, req.ReplyTo, statusFromRecord(rec))
+	}()
+}
+
+func (d *Dispatcher) replyStatus(ctx context.Context, contentType, replyTo string, st *rabbitmq.ExpressionStatus) {
+	body, err := rabbitmq.Encode(contentType, st)
+	if err != nil {
+		d.logger.Error("Erro ao serializar estado da expressão", slog.String(logging.KeyExpressionID, st.Ticket), logging.Err(err))
+		return
+	}
+	if err := d.conn.Send(ctx, rabbitmq.Message{RoutingKey: replyTo, Body: body, ContentType: contentType}); err != nil {
+		d.logger.Error("Erro ao publicar estado da expressão", slog.String(logging.KeyExpressionID, st.Ticket), logging.Err(err))
+	}
+}
+
+func statusFromRecord(rec results.Record) *rabbitmq.ExpressionStatus {
+	st := &rabbitmq.ExpressionStatus{Ticket: rec.ExpressionID, State: string(rec.Status)}
+	if rec.Status.Terminal() {
+		st.Response = &rabbitmq.ExpressionResponse{
+			ExpressionID: rec.ExpressionID,
+			Result:       rec.Result,
+			Error:        rabbitmq.ErrorFromCore(rec.Error),
+			Trace:        rabbitmq.TraceFromCore(rec.Trace),
+		}
+	}
+	return st
+}
+
+// isPending indica se a expressão está em andamento neste dispatcher
+func (d *Dispatcher) isPending(expressionID string) bool {
+	d.pendingMutex.RLock()
+	defer d.pendingMutex.RUnlock()
+	_, ok := d.pendingSteps[expressionID]
+	return ok
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Variáveis de ambiente do lado do servidor (dispatchers)
+const (
+	APIKeysEnv   = "CALC_API_KEYS"   // "<principal>:<segredo>,..." ex: "alice:s3cr3t,bob:0utr0"
+	JWTSecretEnv = "CALC_JWT_SECRET" // segredo HMAC dos tokens JWT (HS256)
+)
+
+// Variáveis de ambiente do lado do cliente
+const (
+	APIKeyEnv = "CALC_API_KEY" // "<principal>:<segredo>"
+	TokenEnv  = "CALC_TOKEN"   // token JWT já emitido
+)
+
+// Métodos de autenticação
+const (
+	MethodNone   = "none"
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Anonymous é o rótulo usado quando a autenticação está desativada
+const Anonymous = "anonymous"
+
+// Erros de autenticação
+var (
+	ErrMissingCredentials = errors.New("credenciais ausentes")
+	ErrInvalidCredentials = errors.New("credenciais inválidas")
+	ErrExpiredToken       = errors.New("token expirado")
+	ErrInvalidSignature   = errors.New("assinatura inválida")
+	ErrReplayed           = errors.New("mensagem repetida (nonce já usado)")
+)
+
+// Principal identifica o cliente autenticado
+type Principal struct {
+	ID     string
+	Method string
+}
+
+// Authenticated indica se o principal foi verificado (e não apenas inferido do expressionID)
+func (p Principal) Authenticated() bool {
+	return p.Method != MethodNone && p.Method != ""
+}
+
+// MetricLabel retorna o rótulo do principal para métricas, com cardinalidade limitada
+// às identidades configuradas
+func (p Principal) MetricLabel() string {
+	if !p.Authenticated() {
+		return Anonymous
+	}
+	return p.ID
+}
+
+// Unauthenticated cria um principal não verificado a partir do clientID informado pelo cliente
+func Unauthenticated(clientID string) Principal {
+	return Principal{ID: clientID, Method: MethodNone}
+}
+
+// Authenticator valida API keys e tokens JWT
+type Authenticator struct {
+	apiKeys   map[string]string // principal -> segredo
+	jwtSecret []byte
+	nonces    nonceCache // nonces das mensagens assinadas já aceitas
+}
+
+// NewAuthenticator cria um autenticador; sem chaves nem segredo JWT, a autenticação fica desativada
+func NewAuthenticator(apiKeys map[string]string, jwtSecret []byte) *Authenticator {
+	return &Authenticator{apiKeys: apiKeys, jwtSecret: jwtSecret}
+}
+
+// NewAuthenticatorFromEnv cria um autenticador a partir de CALC_API_KEYS e CALC_JWT_SECRET
+func NewAuthenticatorFromEnv() (*Authenticator, error) {
+	apiKeys := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv(APIKeysEnv), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		principal, secret, ok := strings.Cut(entry, ":")
+		if !ok || principal == "" || secret == "" {
+			return nil, fmt.Errorf("entrada inválida em %s: %q (esperado principal:segredo)", APIKeysEnv, entry)
+		}
+		apiKeys[principal] = secret
+	}
+
+	return NewAuthenticator(apiKeys, []byte(os.Getenv(JWTSecretEnv))), nil
+}
+
+// Enabled indica se há credenciais configuradas
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (len(a.apiKeys) > 0 || len(a.jwtSecret) > 0)
+}
+
+// AuthenticateToken valida um token de portador: JWT (se configurado) ou o segredo de uma API key
+func (a *Authenticator) AuthenticateToken(token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, ErrMissingCredentials
+	}
+
+	if len(a.jwtSecret) > 0 && strings.Count(token, ".") == 2 {
+		claims, err := ParseJWT(a.jwtSecret, token, time.Now())
+		if err != nil {
+			return Principal{}, err
+		}
+		return Principal{ID: claims.Subject, Method: MethodJWT}, nil
+	}
+
+	for principal, secret := range a.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1 {
+			return Principal{ID: principal, Method: MethodAPIKey}, nil
+		}
+	}
+	return Principal{}, ErrInvalidCredentials
+}
+
+// VerifySignature valida uma mensagem assinada com o segredo da API key do principal.
+// Cada nonce é aceito uma única vez dentro da tolerância do timestamp.
+func (a *Authenticator) VerifySignature(principal, timestamp, nonce, signature string, body []byte, now time.Time) (Principal, error) {
+	if principal == "" || timestamp == "" || nonce == "" || signature == "" {
+		return Principal{}, ErrMissingCredentials
+	}
+
+	secret, ok := a.apiKeys[principal]
+	if !ok {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	signedAt, err := checkTimestamp(timestamp, now)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	expected := Sign(secret, timestamp, nonce, body)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) != 1 {
+		return Principal{}, ErrInvalidSignature
+	}
+	if !a.nonces.add(principal, nonce, signedAt, now) {
+		return Principal{}, ErrReplayed
+	}
+	return Principal{ID: principal, Method: MethodAPIKey}, nil
+}
+
+// Reason converte um erro de autenticação em rótulo para métricas
+func Reason(err error) string {
+	switch {
+	case errors.Is(err, ErrMissingCredentials):
+		return "missing"
+	case errors.Is(err, ErrExpiredToken):
+		return "expired"
+	case errors.Is(err, ErrInvalidSignature):
+		return "bad_signature"
+	case errors.Is(err, ErrReplayed):
+		return "replayed"
+	default:
+		return "invalid"
+	}
+}
+
+type principalKey struct{}
+
+// NewContext associa o principal ao contexto
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext retorna o principal associado ao contexto
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Credentials são as credenciais usadas pelos clientes
+type Credentials struct {
+	Principal string // principal da API key
+	Secret    string // segredo da API key
+	Token     string // token JWT
+}
+
+// CredentialsFromEnv lê as credenciais do cliente de CALC_TOKEN ou CALC_API_KEY
+func CredentialsFromEnv() (Credentials, error) {
+	if token := os.Getenv(TokenEnv); token != "" {
+		return Credentials{Token: token}, nil
+	}
+	if key := os.Getenv(APIKeyEnv); key != "" {
+		principal, secret, ok := strings.Cut(key, ":")
+		if !ok || principal == "" || secret == "" {
+			return Credentials{}, fmt.Errorf("%s inválida (esperado principal:segredo)", APIKeyEnv)
+		}
+		return Credentials{Principal: principal, Secret: secret}, nil
+	}
+	return Credentials{}, nil
+}
+
+// Empty indica que nenhuma credencial foi configurada
+func (c Credentials) Empty() bool {
+	return c.Token == "" && c.Secret == ""
+}
+
+// BearerToken retorna o valor enviado no header authorization
+func (c Credentials) BearerToken() string {
+	if c.Token != "" {
+		return c.Token
+	}
+	return c.Secret
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testNow    = time.Unix(1_700_000_000, 0)
+	testSecret = []byte("segredo-jwt")
+)
+
+func mustSignJWT(t *testing.T, secret []byte, subject string, ttl time.Duration, now time.Time) string {
+	t.Helper()
+	token, err := SignJWT(secret, subject, ttl, now)
+	if err != nil {
+		t.Fatalf("SignJWT: %v", err)
+	}
+	return token
+}
+
+func TestParseJWT(t *testing.T) {
+	valid := mustSignJWT(t, testSecret, "alice", time.Hour, testNow)
+	parts := strings.Split(valid, ".")
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	noSubject := mustSignJWT(t, testSecret, "", 0, testNow)
+
+	tests := []struct {
+		name    string
+		token   string
+		now     time.Time
+		wantSub string
+		wantErr error
+	}{
+		{"válido", valid, testNow, "alice", nil},
+		{"sem expiração", mustSignJWT(t, testSecret, "bob", 0, testNow), testNow.Add(24 * time.Hour), "bob", nil},
+		{"expirado", valid, testNow.Add(time.Hour), "", ErrExpiredToken},
+		{"outro segredo", mustSignJWT(t, []byte("outro"), "alice", time.Hour, testNow), testNow, "", ErrInvalidSignature},
+		{"payload alterado", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory"}`)) + "." + parts[2], testNow, "", ErrInvalidSignature},
+		{"alg none", noneHeader + "." + parts[1] + ".", testNow, "", ErrInvalidCredentials},
+		{"partes a menos", parts[0] + "." + parts[1], testNow, "", ErrInvalidCredentials},
+		{"sem subject", noSubject, testNow, "", ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseJWT(testSecret, tt.token, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("erro = %v, esperado %v", err, tt.wantErr)
+			}
+			if claims.Subject != tt.wantSub {
+				t.Errorf("sub = %q, esperado %q", claims.Subject, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	a := NewAuthenticator(map[string]string{"alice": "s3cr3t", "bob": "0utr0"}, testSecret)
+
+	tests := []struct {
+		name       string
+		token      string
+		wantID     string
+		wantMethod string
+		wantErr    error
+	}{
+		{"api key", "0utr0", "bob", MethodAPIKey, nil},
+		{"jwt", mustSignJWT(t, testSecret, "carol", time.Hour, time.Now()), "carol", MethodJWT, nil},
+		{"jwt expirado", mustSignJWT(t, testSecret, "carol", time.Minute, time.Now().Add(-time.Hour)), "", "", ErrExpiredToken},
+		{"segredo desconhecido", "errado", "", "", ErrInvalidCredentials},
+		{"vazio", "", "", "", ErrMissingCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.AuthenticateToken(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("erro = %v, esperado %v", err, tt.wantErr)
+			}
+			if p.ID != tt.wantID || p.Method != tt.wantMethod {
+				t.Errorf("principal = %+v, esperado %s/%s", p, tt.wantID, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorEnabled(t *testing.T) {
+	var nilAuth *Authenticator
+	if nilAuth.Enabled() || NewAuthenticator(nil, nil).Enabled() {
+		t.Error("autenticador sem credenciais deveria estar desativado")
+	}
+	if !NewAuthenticator(map[string]string{"alice": "x"}, nil).Enabled() || !NewAuthenticator(nil, testSecret).Enabled() {
+		t.Error("autenticador com credenciais deveria estar ativado")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"expression":"2+2"}`)
+	ts := Timestamp(testNow)
+
+	tests := []struct {
+		name      string
+		principal string
+		timestamp string
+		nonce     string
+		signature string
+		body      []byte
+		wantErr   error
+	}{
+		{"válida", "alice", ts, "n1", Sign("s3cr3t", ts, "n1", body), body, nil},
+		{"outro segredo", "alice", ts, "n2", Sign("errado", ts, "n2", body), body, ErrInvalidSignature},
+		{"corpo alterado", "alice", ts, "n3", Sign("s3cr3t", ts, "n3", body), []byte(`{"expression":"9+9"}`), ErrInvalidSignature},
+		{"nonce alterado", "alice", ts, "n4", Sign("s3cr3t", ts, "outro", body), body, ErrInvalidSignature},
+		{"principal desconhecido", "mallory", ts, "n5", Sign("s3cr3t", ts, "n5", body), body, ErrInvalidCredentials},
+		{"timestamp antigo", "alice", Timestamp(testNow.Add(-MaxClockSkew - time.Second)), "n6", Sign("s3cr3t", Timestamp(testNow.Add(-MaxClockSkew-time.Second)), "n6", body), body, ErrExpiredToken},
+		{"timestamp inválido", "alice", "ontem", "n7", Sign("s3cr3t", "ontem", "n7", body), body, ErrInvalidSignature},
+		{"sem nonce", "alice", ts, "", Sign("s3cr3t", ts, "", body), body, ErrMissingCredentials},
+		{"sem assinatura", "alice", ts, "n8", "", body, ErrMissingCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuthenticator(map[string]string{"alice": "s3cr3t"}, nil)
+			p, err := a.VerifySignature(tt.principal, tt.timestamp, tt.nonce, tt.signature, tt.body, testNow)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("erro = %v, esperado %v", err, tt.wantErr)
+			}
+			if err == nil && (p.ID != tt.principal || p.Method != MethodAPIKey) {
+				t.Errorf("principal = %+v", p)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureReplay(t *testing.T) {
+	a := NewAuthenticator(map[string]string{"alice": "s3cr3t", "bob": "0utr0"}, nil)
+	body := []byte("corpo")
+	ts := Timestamp(testNow)
+	verify := func(principal, secret, nonce string, now time.Time) error {
+		_, err := a.VerifySignature(principal, ts, nonce, Sign(secret, ts, nonce, body), body, now)
+		return err
+	}
+
+	if err := verify("alice", "s3cr3t", "n1", testNow); err != nil {
+		t.Fatalf("primeira mensagem: %v", err)
+	}
+	if err := verify("alice", "s3cr3t", "n1", testNow.Add(time.Second)); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("reenvio: erro = %v, esperado %v", err, ErrReplayed)
+	}
+	if err := verify("bob", "0utr0", "n1", testNow); err != nil {
+		t.Fatalf("mesmo nonce de outro principal: %v", err)
+	}
+	// Uma assinatura inválida não consome o nonce
+	if _, err := a.VerifySignature("alice", ts, "n2", "invalida", body, testNow); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("assinatura inválida: %v", err)
+	}
+	if err := verify("alice", "s3cr3t", "n2", testNow); err != nil {
+		t.Fatalf("nonce após assinatura inválida: %v", err)
+	}
+}
+
+func TestNonceCachePrune(t *testing.T) {
+	var c nonceCache
+	if !c.add("alice", "n1", testNow, testNow) {
+		t.Fatal("primeiro uso recusado")
+	}
+	// Após a tolerância, o timestamp seria recusado de qualquer forma e o nonce é descartado
+	later := testNow.Add(MaxClockSkew + time.Second)
+	c.add("alice", "n2", later, later)
+	if _, ok := c.seen["alice/n1"]; ok {
+		t.Error("nonce expirado não foi removido")
+	}
+	if len(c.seen) != 1 {
+		t.Errorf("len(seen) = %d, esperado 1", len(c.seen))
+	}
+}
+
+func TestNewNonce(t *testing.T) {
+	a, b := NewNonce(), NewNonce()
+	if len(a) != 32 || a == b {
+		t.Errorf("nonces %q e %q", a, b)
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Claims são os campos do JWT usados pela calculadora
+type Claims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// SignJWT emite um token HS256 para o subject informado (ttl zero não expira)
+func SignJWT(secret []byte, subject string, ttl time.Duration, now time.Time) (string, error) {
+	claims := Claims{Subject: subject, IssuedAt: now.Unix()}
+	if ttl > 0 {
+		claims.ExpiresAt = now.Add(ttl).Unix()
+	}
+
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	return signingInput + "." + b64.EncodeToString(hs256(secret, signingInput)), nil
+}
+
+// ParseJWT valida a assinatura HS256 e a validade temporal de um token
+func ParseJWT(secret []byte, token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidCredentials
+	}
+
+	headerBytes, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return Claims{}, ErrInvalidCredentials
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil || header.Alg != "HS256" {
+		return Claims{}, ErrInvalidCredentials
+	}
+
+	signature, err := b64.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, hs256(secret, parts[0]+"."+parts[1])) {
+		return Claims{}, ErrInvalidSignature
+	}
+
+	payload, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidCredentials
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	if claims.Subject == "" {
+		return Claims{}, ErrInvalidCredentials
+	}
+	if claims.ExpiresAt != 0 && now.Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrExpiredToken
+	}
+	if claims.NotBefore != 0 && now.Unix() < claims.NotBefore {
+		return Claims{}, ErrInvalidCredentials
+	}
+	return claims, nil
+}
+
+func hs256(secret []byte, input string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return mac.Sum(nil)
+}
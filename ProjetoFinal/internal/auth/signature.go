@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers usados na autenticação das mensagens
+const (
+	HeaderAuthorization = "authorization"
+	HeaderPrincipal     = "x-calc-principal"
+	HeaderTimestamp     = "x-calc-timestamp"
+	HeaderNonce         = "x-calc-nonce"
+	HeaderSignature     = "x-calc-signature"
+)
+
+// MaxClockSkew é a diferença máxima aceita entre o timestamp assinado e o relógio do dispatcher
+const MaxClockSkew = 5 * time.Minute
+
+// Sign calcula a assinatura HMAC-SHA256 de "<timestamp>.<nonce>.<corpo>" com o segredo da API key
+func Sign(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewNonce gera o valor único de uma mensagem assinada
+func NewNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Timestamp formata o instante usado na assinatura (segundos Unix)
+func Timestamp(now time.Time) string {
+	return strconv.FormatInt(now.Unix(), 10)
+}
+
+// checkTimestamp valida o timestamp assinado e retorna o instante correspondente
+func checkTimestamp(timestamp string, now time.Time) (time.Time, error) {
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidSignature
+	}
+	signedAt := time.Unix(sec, 0)
+	skew := now.Sub(signedAt)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > MaxClockSkew {
+		return time.Time{}, ErrExpiredToken
+	}
+	return signedAt, nil
+}
+
+// nonceCache guarda os nonces aceitos enquanto o timestamp assinado estiver dentro da
+// tolerância, para recusar a mesma mensagem reenviada (replay)
+type nonceCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time // principal/nonce -> fim da tolerância
+	nextPrune time.Time
+}
+
+// add registra o nonce; retorna false se ele já foi usado pelo principal
+func (c *nonceCache) add(principal, nonce string, signedAt, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seen == nil {
+		c.seen = make(map[string]time.Time)
+	}
+	if !now.Before(c.nextPrune) {
+		for k, until := range c.seen {
+			if now.After(until) {
+				delete(c.seen, k)
+			}
+		}
+		c.nextPrune = now.Add(MaxClockSkew / 5)
+	}
+
+	key := principal + "/" + nonce
+	if _, ok := c.seen[key]; ok {
+		return false
+	}
+	c.seen[key] = signedAt.Add(MaxClockSkew)
+	return true
+}
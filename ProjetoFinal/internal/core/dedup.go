@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StepDedup evita executar de novo um step repetido (mesmo StepID, operação e
+// operandos) dentro da janela: uma repetição de um step em execução aguarda a
+// execução original e uma repetição de um step concluído recebe a resposta guardada.
+// Acontece quando o dispatcher reenvia um step (retry após timeout) ou quando o
+// broker reentrega uma mensagem não confirmada.
+type StepDedup[T any] struct {
+	mu       sync.Mutex
+	entries  map[string]*stepEntry[T]
+	finished []finishedStep[T] // steps concluídos em ordem de conclusão, para expirar os mais antigos
+	window   time.Duration
+}
+
+// finishedStep aponta para a entrada concluída; o StepID pode ter sido reutilizado depois
+type finishedStep[T any] struct {
+	stepID string
+	entry  *stepEntry[T]
+}
+
+type stepEntry[T any] struct {
+	key  string // operação e operandos
+	done chan struct{}
+	resp T
+	err  error
+	at   time.Time // conclusão
+}
+
+// NewStepDedup cria a janela de deduplicação; window <= 0 desativa
+func NewStepDedup[T any](window time.Duration) *StepDedup[T] {
+	return &StepDedup[T]{entries: make(map[string]*stepEntry[T]), window: window}
+}
+
+// Do executa fn uma única vez por step. duplicate indica que a resposta veio de
+// uma execução anterior. Um erro de fn não é guardado: é repassado a quem aguardava
+// a execução, e a próxima repetição executa de novo. err também é retornado se ctx
+// expirar aguardando a execução anterior.
+func (d *StepDedup[T]) Do(ctx context.Context, stepID, operation string, numbers []float64, fn func() (T, error)) (resp T, duplicate bool, err error) {
+	if d == nil || d.window <= 0 || stepID == "" {
+		resp, err = fn()
+		return resp, false, err
+	}
+	key := fmt.Sprint(operation, numbers)
+	now := time.Now()
+
+	d.mu.Lock()
+	d.expire(now)
+	if e, ok := d.entries[stepID]; ok && e.key == key {
+		d.mu.Unlock()
+		select {
+		case <-e.done:
+			return e.resp, true, e.err
+		case <-ctx.Done():
+			return resp, true, ctx.Err()
+		}
+	}
+	// StepID novo, ou reutilizado com outros operandos: executa e substitui
+	e := &stepEntry[T]{key: key, done: make(chan struct{})}
+	d.entries[stepID] = e
+	d.mu.Unlock()
+
+	e.resp, e.err = fn()
+
+	d.mu.Lock()
+	e.at = time.Now()
+	switch {
+	case e.err == nil:
+		d.finished = append(d.finished, finishedStep[T]{stepID: stepID, entry: e})
+	case d.entries[stepID] == e:
+		delete(d.entries, stepID)
+	}
+	close(e.done)
+	d.mu.Unlock()
+	return e.resp, false, e.err
+}
+
+// expire remove os steps concluídos há mais que a janela (chamado com mu travado).
+// Steps em execução não estão em finished, então um step travado não impede a
+// expiração dos demais.
+func (d *StepDedup[T]) expire(now time.Time) {
+	n := 0
+	for _, f := range d.finished {
+		if now.Sub(f.entry.at) <= d.window {
+			break
+		}
+		if d.entries[f.stepID] == f.entry {
+			delete(d.entries, f.stepID)
+		}
+		n++
+	}
+	d.finished = d.finished[n:]
+}
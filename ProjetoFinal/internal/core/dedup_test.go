@@ -0,0 +1,159 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// counter conta as execuções de fn e devolve sempre o mesmo resultado
+type counter struct{ calls atomic.Int32 }
+
+func (c *counter) fn(result float64) func() (float64, error) {
+	return func() (float64, error) {
+		c.calls.Add(1)
+		return result, nil
+	}
+}
+
+func TestStepDedupReplaysFinishedStep(t *testing.T) {
+	d := NewStepDedup[float64](time.Minute)
+	var c counter
+	ctx := context.Background()
+
+	if resp, dup, err := d.Do(ctx, "s1", "add", []float64{1, 2}, c.fn(3)); resp != 3 || dup || err != nil {
+		t.Fatalf("primeira execução = %v, %v, %v", resp, dup, err)
+	}
+	if resp, dup, err := d.Do(ctx, "s1", "add", []float64{1, 2}, c.fn(99)); resp != 3 || !dup || err != nil {
+		t.Fatalf("repetição = %v, %v, %v; esperado a resposta guardada", resp, dup, err)
+	}
+	// Mesmo StepID com outros operandos executa de novo
+	if resp, dup, _ := d.Do(ctx, "s1", "add", []float64{2, 2}, c.fn(4)); resp != 4 || dup {
+		t.Fatalf("StepID reutilizado = %v, %v", resp, dup)
+	}
+	if c.calls.Load() != 2 {
+		t.Errorf("fn executada %d vezes, esperado 2", c.calls.Load())
+	}
+}
+
+func TestStepDedupAttachesToInFlightStep(t *testing.T) {
+	d := NewStepDedup[float64](time.Minute)
+	var c counter
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go d.Do(context.Background(), "s1", "mul", []float64{3, 4}, func() (float64, error) {
+		close(started)
+		<-release
+		return c.fn(12)()
+	})
+	<-started
+
+	type result struct {
+		resp float64
+		dup  bool
+		err  error
+	}
+	out := make(chan result, 1)
+	go func() {
+		resp, dup, err := d.Do(context.Background(), "s1", "mul", []float64{3, 4}, c.fn(99))
+		out <- result{resp, dup, err}
+	}()
+
+	select {
+	case r := <-out:
+		t.Fatalf("repetição não aguardou a execução original: %+v", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if r := <-out; r.resp != 12 || !r.dup || r.err != nil {
+		t.Fatalf("repetição = %+v, esperado 12 da execução original", r)
+	}
+	if c.calls.Load() != 1 {
+		t.Errorf("fn executada %d vezes, esperado 1", c.calls.Load())
+	}
+
+	// Quem desiste de aguardar recebe o erro do contexto
+	block := make(chan struct{})
+	defer close(block)
+	started = make(chan struct{})
+	go d.Do(context.Background(), "s2", "mul", []float64{1, 1}, func() (float64, error) {
+		close(started)
+		<-block
+		return 1, nil
+	})
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, dup, err := d.Do(ctx, "s2", "mul", []float64{1, 1}, c.fn(1)); !dup || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("dup = %v, erro = %v, esperado %v", dup, err, context.DeadlineExceeded)
+	}
+}
+
+func TestStepDedupFailedStepRunsAgain(t *testing.T) {
+	d := NewStepDedup[float64](time.Minute)
+	failed := errors.New("servidor indisponível")
+	if _, _, err := d.Do(context.Background(), "s1", "div", []float64{1, 2}, func() (float64, error) { return 0, failed }); !errors.Is(err, failed) {
+		t.Fatalf("erro = %v, esperado %v", err, failed)
+	}
+	if _, ok := d.entries["s1"]; ok {
+		t.Fatal("step com erro continua guardado")
+	}
+	var c counter
+	if resp, dup, err := d.Do(context.Background(), "s1", "div", []float64{1, 2}, c.fn(0.5)); resp != 0.5 || dup || err != nil {
+		t.Fatalf("nova tentativa = %v, %v, %v", resp, dup, err)
+	}
+}
+
+func TestStepDedupExpiry(t *testing.T) {
+	const window = 20 * time.Millisecond
+	d := NewStepDedup[float64](window)
+	var c counter
+	ctx := context.Background()
+
+	// Um step travado não impede a expiração dos concluídos depois dele
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	go d.Do(ctx, "travado", "add", []float64{0, 0}, func() (float64, error) {
+		close(started)
+		<-block
+		return 0, nil
+	})
+	<-started
+
+	d.Do(ctx, "s1", "add", []float64{1, 1}, c.fn(2))
+	d.Do(ctx, "s2", "add", []float64{2, 2}, c.fn(4))
+	if len(d.entries) != 3 {
+		t.Fatalf("entradas = %d, esperado 3", len(d.entries))
+	}
+
+	time.Sleep(2 * window)
+	if _, dup, _ := d.Do(ctx, "s1", "add", []float64{1, 1}, c.fn(2)); dup {
+		t.Error("step expirado foi tratado como repetição")
+	}
+	if _, ok := d.entries["s2"]; ok {
+		t.Error("step concluído depois do travado não expirou")
+	}
+	if _, ok := d.entries["travado"]; !ok {
+		t.Error("step em execução foi removido")
+	}
+	if len(d.finished) != 1 {
+		t.Errorf("concluídos = %d, esperado 1 (a nova execução de s1)", len(d.finished))
+	}
+}
+
+func TestStepDedupDisabled(t *testing.T) {
+	var c counter
+	for _, d := range []*StepDedup[float64]{nil, NewStepDedup[float64](0)} {
+		for range 2 {
+			if _, dup, _ := d.Do(context.Background(), "s1", "add", []float64{1, 1}, c.fn(2)); dup {
+				t.Fatal("deduplicação desativada tratou step como repetição")
+			}
+		}
+	}
+	if c.calls.Load() != 4 {
+		t.Errorf("fn executada %d vezes, esperado 4", c.calls.Load())
+	}
+}
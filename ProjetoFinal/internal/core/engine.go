@@ -0,0 +1,263 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+)
+
+// CodeExecutionError indica falha do transporte ao executar um step
+const CodeExecutionError = "EXECUTION_ERROR"
+
+// CodeCancelled indica uma expressão interrompida por cancelamento
+const CodeCancelled = "CANCELLED"
+
+// ErrCancelled é a causa do cancelamento pedido pelo cliente (Cancel / calculator.cancel)
+var ErrCancelled = errors.New("cancelada pelo cliente")
+
+// OperationExecutor executa um step em um servidor de operação. Cada transporte
+// (gRPC, RabbitMQ, ...) implementa apenas este adaptador; a ordem dos steps e a
+// substituição de resultados ficam no Engine.
+//
+// Erros de negócio (ex: DIV_BY_ZERO) voltam em OperationResponse.Error; o erro
+// retornado indica falha do próprio transporte (timeout, conexão, ...).
+type OperationExecutor interface {
+	Execute(ctx context.Context, req OperationRequest) (OperationResponse, error)
+}
+
+// Execution é o estado de uma expressão em execução. Results guarda os resultados
+// dos steps já concluídos (por StepID), o que permite retomar uma execução.
+type Execution struct {
+	ExpressionID string
+	Steps        []Step
+	Results      map[string]float64
+	DeadlineMs   int64 // timeout de cada step (zero: sem timeout)
+	Priority     int
+	IncludeTrace bool
+	Trace        []StepTrace
+	Logger       *slog.Logger
+
+	// OnStep é chamado após cada step concluído com sucesso (ex: gravar checkpoint)
+	OnStep func(*Execution)
+
+	// OnEvent é chamado quando cada step é despachado e quando termina (ex: progresso)
+	OnEvent func(StepEvent)
+}
+
+// StepState é a fase de um step informada em StepEvent
+type StepState int
+
+const (
+	StepDispatched StepState = iota + 1 // enviado ao servidor de operação
+	StepCompleted                       // concluído com sucesso
+	StepFailed                          // concluído com erro
+)
+
+// StepEvent descreve o progresso de um step da expressão
+type StepEvent struct {
+	ExpressionID string
+	Index        int // índice do step (0 a Total-1)
+	Total        int // total de steps da expressão
+	StepID       string
+	Operation    string
+	Operands     []float64
+	State        StepState
+	Result       float64 // resultado parcial (StepCompleted)
+	Error        *ErrorInfo
+}
+
+// Completed retorna quantos steps já foram concluídos
+func (x *Execution) Completed() int {
+	return len(x.Results)
+}
+
+func (x *Execution) emit(event StepEvent) {
+	if x.OnEvent != nil {
+		x.OnEvent(event)
+	}
+}
+
+// StepHook observa cada step executado pelo Engine (ex: tracing e métricas, em
+// internal/instrument), sem que o core dependa dessas bibliotecas. É chamado antes do
+// step e retorna o contexto da execução e a função chamada ao fim do step, com o erro
+// (nil em sucesso) e a latência.
+type StepHook func(ctx context.Context, index int, req OperationRequest) (context.Context, func(errInfo *ErrorInfo, latency time.Duration))
+
+// Engine executa os steps de uma expressão, em ordem, sobre um OperationExecutor
+type Engine struct {
+	executor OperationExecutor
+	hook     StepHook // nil: sem observação
+}
+
+// NewEngine cria um engine sobre o executor; hook pode ser nil
+func NewEngine(executor OperationExecutor, hook StepHook) *Engine {
+	return &Engine{executor: executor, hook: hook}
+}
+
+// StepID retorna o identificador do step de índice i (ex: "<expressionID>_step0")
+func StepID(expressionID string, i int) string {
+	return fmt.Sprintf("%s_step%d", expressionID, i)
+}
+
+// ResolveOperands substitui as referências a resultados anteriores pelos valores calculados
+func ResolveOperands(expressionID string, step Step, results map[string]float64) []float64 {
+	numbers := make([]float64, len(step.Numbers))
+	copy(numbers, step.Numbers)
+	for _, dep := range step.DependsOn {
+		// "result_step0" -> "<expressionID>_step0"
+		stepID := fmt.Sprintf("%s_%s", expressionID, dep.Reference[len("result_"):])
+		if result, ok := results[stepID]; ok {
+			numbers[dep.Position] = result
+		}
+	}
+	return numbers
+}
+
+// Run executa os steps restantes e retorna a resposta da expressão (sucesso ou erro).
+// Se ctx for cancelado, nenhum step novo é despachado e a resposta é CANCELLED.
+func (e *Engine) Run(ctx context.Context, x *Execution) ExpressionResponse {
+	if x.Results == nil {
+		x.Results = make(map[string]float64)
+	}
+	logger := x.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	var result float64
+	for i := x.Completed(); i < len(x.Steps); i++ {
+		if errInfo := cancelled(ctx); errInfo != nil {
+			logger.InfoContext(ctx, "Expressão cancelada", slog.Int("completed", i), slog.Int("steps", len(x.Steps)))
+			return ExpressionResponse{ExpressionID: x.ExpressionID, Error: errInfo, Trace: x.Trace}
+		}
+		step := x.Steps[i]
+		req := OperationRequest{
+			ExpressionID: x.ExpressionID,
+			StepID:       StepID(x.ExpressionID, i),
+			Operation:    step.Operation,
+			Numbers:      ResolveOperands(x.ExpressionID, step, x.Results),
+			DeadlineMs:   x.DeadlineMs,
+			Priority:     x.Priority,
+		}
+
+		event := StepEvent{
+			ExpressionID: x.ExpressionID,
+			Index:        i,
+			Total:        len(x.Steps),
+			StepID:       req.StepID,
+			Operation:    req.Operation,
+			Operands:     req.Numbers,
+			State:        StepDispatched,
+		}
+		x.emit(event)
+
+		resp, errInfo := e.runStep(ctx, logger, i, req)
+		if errInfo != nil && errInfo.Code == CodeExecutionError {
+			// O step em andamento foi abandonado pelo cancelamento
+			if c := cancelled(ctx); c != nil {
+				errInfo = c
+			}
+		}
+		event.State, event.Result, event.Error = StepCompleted, resp.Result, errInfo
+		if errInfo != nil {
+			event.State = StepFailed
+		}
+		x.emit(event)
+		if x.IncludeTrace {
+			x.Trace = append(x.Trace, StepTrace{
+				StepID:    req.StepID,
+				Operation: req.Operation,
+				Operands:  req.Numbers,
+				Result:    resp.Result,
+				Server:    resp.Server,
+				LatencyUs: resp.latency.Microseconds(),
+				Error:     errInfo,
+			})
+		}
+		if errInfo != nil {
+			return ExpressionResponse{ExpressionID: x.ExpressionID, Error: errInfo, Trace: x.Trace}
+		}
+
+		x.Results[req.StepID] = resp.Result
+		result = resp.Result
+		if x.OnStep != nil && i < len(x.Steps)-1 {
+			x.OnStep(x)
+		}
+	}
+
+	// Expressão sem operações (não deveria chegar aqui: o parser as rejeita)
+	if len(x.Steps) == 0 {
+		return ExpressionResponse{
+			ExpressionID: x.ExpressionID,
+			Error:        &ErrorInfo{Code: "INTERNAL_ERROR", Message: "Erro interno ao processar expressão"},
+		}
+	}
+	return ExpressionResponse{ExpressionID: x.ExpressionID, Result: result, Trace: x.Trace}
+}
+
+// cancelled retorna o erro CANCELLED se ctx foi cancelado (prazo expirado não conta)
+func cancelled(ctx context.Context) *ErrorInfo {
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		return nil
+	}
+	message := "Expressão cancelada pelo cliente"
+	if cause := context.Cause(ctx); !errors.Is(cause, ErrCancelled) {
+		message = fmt.Sprintf("Expressão cancelada: %v", cause)
+	}
+	return &ErrorInfo{Code: CodeCancelled, Message: message}
+}
+
+// stepResult é a resposta do step acrescida da latência medida pelo engine
+type stepResult struct {
+	OperationResponse
+	latency time.Duration
+}
+
+// runStep executa um step com timeout, logs e o StepHook do engine
+func (e *Engine) runStep(ctx context.Context, logger *slog.Logger, i int, req OperationRequest) (stepResult, *ErrorInfo) {
+	var done func(*ErrorInfo, time.Duration)
+	if e.hook != nil {
+		ctx, done = e.hook(ctx, i, req)
+	}
+
+	if req.DeadlineMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.DeadlineMs)*time.Millisecond)
+		defer cancel()
+	}
+
+	logger.DebugContext(ctx, "Executando step",
+		slog.String(logging.KeyStepID, req.StepID),
+		slog.String(logging.KeyOperation, req.Operation),
+		slog.Any("numbers", req.Numbers))
+
+	start := time.Now()
+	resp, err := e.executor.Execute(ctx, req)
+	res := stepResult{OperationResponse: resp, latency: time.Since(start)}
+
+	errInfo := resp.Error
+	if err != nil {
+		errInfo = &ErrorInfo{Code: CodeExecutionError, Message: fmt.Sprintf("Erro ao executar operação: %v", err)}
+	}
+
+	if errInfo != nil {
+		logger.WarnContext(ctx, "Erro ao executar step",
+			slog.String(logging.KeyStepID, req.StepID),
+			slog.String(logging.KeyOperation, req.Operation),
+			slog.String("code", errInfo.Code),
+			slog.String("message", errInfo.Message))
+	} else {
+		logger.DebugContext(ctx, "Step completado",
+			slog.String(logging.KeyStepID, req.StepID),
+			slog.String(logging.KeyOperation, req.Operation),
+			slog.Float64("result", resp.Result))
+	}
+	if done != nil {
+		done(errInfo, res.latency)
+	}
+	return res, errInfo
+}
@@ -0,0 +1,307 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubExecutor calcula as quatro operações localmente e registra cada requisição;
+// fail permite simular erros de negócio ou do transporte (ok indica que respondeu)
+type stubExecutor struct {
+	mu       sync.Mutex
+	requests []OperationRequest
+	ctxs     []context.Context
+	fail     func(ctx context.Context, req OperationRequest) (OperationResponse, bool, error)
+}
+
+func (s *stubExecutor) Execute(ctx context.Context, req OperationRequest) (OperationResponse, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.ctxs = append(s.ctxs, ctx)
+	s.mu.Unlock()
+	if s.fail != nil {
+		if resp, ok, err := s.fail(ctx, req); ok {
+			return resp, err
+		}
+	}
+	resp := OperationResponse{ExpressionID: req.ExpressionID, StepID: req.StepID, Server: req.Operation + "-1"}
+	a, b := req.Numbers[0], req.Numbers[1]
+	switch req.Operation {
+	case "add":
+		resp.Result = a + b
+	case "subtract":
+		resp.Result = a - b
+	case "multiply":
+		resp.Result = a * b
+	case "divide":
+		if b == 0 {
+			resp.Error = &ErrorInfo{Code: "DIV_BY_ZERO", Message: "divisão por zero"}
+		} else {
+			resp.Result = a / b
+		}
+	}
+	return resp, nil
+}
+
+func (s *stubExecutor) operations() []string {
+	var ops []string
+	for _, r := range s.requests {
+		ops = append(ops, r.Operation)
+	}
+	return ops
+}
+
+func newExecution(t *testing.T, expr string) *Execution {
+	t.Helper()
+	steps, err := NewParser().Parse(expr)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", expr, err)
+	}
+	return &Execution{ExpressionID: "e1", Steps: steps, IncludeTrace: true}
+}
+
+func TestEngineRunsStepsInOrder(t *testing.T) {
+	exec := &stubExecutor{}
+	x := newExecution(t, "(2+3)*4-1")
+	x.DeadlineMs, x.Priority = 1000, 7
+	var events []StepEvent
+	var checkpoints []int
+	x.OnEvent = func(e StepEvent) { events = append(events, e) }
+	x.OnStep = func(x *Execution) { checkpoints = append(checkpoints, x.Completed()) }
+
+	resp := NewEngine(exec, nil).Run(context.Background(), x)
+	if resp.Error != nil || resp.Result != 19 || resp.ExpressionID != "e1" {
+		t.Fatalf("resposta = %+v, esperado 19", resp)
+	}
+	if got, want := exec.operations(), []string{"add", "multiply", "subtract"}; !slices.Equal(got, want) {
+		t.Fatalf("operações = %v, esperado %v", got, want)
+	}
+	// Os resultados anteriores substituem as referências
+	for i, want := range [][]float64{{2, 3}, {5, 4}, {20, 1}} {
+		req := exec.requests[i]
+		if !slices.Equal(req.Numbers, want) {
+			t.Errorf("step %d: operandos = %v, esperado %v", i, req.Numbers, want)
+		}
+		if req.StepID != StepID("e1", i) || req.DeadlineMs != 1000 || req.Priority != 7 {
+			t.Errorf("step %d: requisição = %+v", i, req)
+		}
+		if _, ok := exec.ctxs[i].Deadline(); !ok {
+			t.Errorf("step %d executado sem timeout", i)
+		}
+	}
+
+	if len(events) != 6 {
+		t.Fatalf("eventos = %d, esperado 6", len(events))
+	}
+	for i, e := range events {
+		wantState := StepDispatched
+		if i%2 == 1 {
+			wantState = StepCompleted
+		}
+		if e.Index != i/2 || e.Total != 3 || e.State != wantState {
+			t.Errorf("evento %d = %+v", i, e)
+		}
+	}
+	if events[3].Result != 20 {
+		t.Errorf("resultado parcial = %v, esperado 20", events[3].Result)
+	}
+	// O checkpoint não é gravado após o último step
+	if !slices.Equal(checkpoints, []int{1, 2}) {
+		t.Errorf("OnStep com %v steps concluídos, esperado [1 2]", checkpoints)
+	}
+
+	if len(resp.Trace) != 3 {
+		t.Fatalf("trace = %d steps, esperado 3", len(resp.Trace))
+	}
+	if tr := resp.Trace[1]; tr.Operation != "multiply" || tr.Result != 20 || tr.Server != "multiply-1" || !slices.Equal(tr.Operands, []float64{5, 4}) {
+		t.Errorf("trace do step 1 = %+v", tr)
+	}
+}
+
+func TestEngineWithoutTrace(t *testing.T) {
+	x := newExecution(t, "1+1")
+	x.IncludeTrace = false
+	if resp := NewEngine(&stubExecutor{}, nil).Run(context.Background(), x); resp.Result != 2 || resp.Trace != nil {
+		t.Errorf("resposta = %+v, esperado 2 sem trace", resp)
+	}
+}
+
+func TestEngineResumesFromResults(t *testing.T) {
+	exec := &stubExecutor{}
+	x := newExecution(t, "(2+3)*4")
+	x.Results = map[string]float64{StepID("e1", 0): 5}
+
+	resp := NewEngine(exec, nil).Run(context.Background(), x)
+	if resp.Result != 20 || len(exec.requests) != 1 || !slices.Equal(exec.requests[0].Numbers, []float64{5, 4}) {
+		t.Errorf("resposta = %+v, requisições = %+v", resp, exec.requests)
+	}
+}
+
+func TestEngineStopsOnError(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		fail     func(ctx context.Context, req OperationRequest) (OperationResponse, bool, error)
+		wantCode string
+		wantOps  []string
+	}{
+		{name: "erro de negócio", expr: "(1/0)+2", wantCode: "DIV_BY_ZERO", wantOps: []string{"divide"}},
+		{
+			name: "falha do transporte",
+			expr: "(1+2)*3",
+			fail: func(_ context.Context, req OperationRequest) (OperationResponse, bool, error) {
+				return OperationResponse{}, req.Operation == "multiply", errors.New("conexão recusada")
+			},
+			wantCode: CodeExecutionError,
+			wantOps:  []string{"add", "multiply"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := &stubExecutor{fail: tt.fail}
+			x := newExecution(t, tt.expr)
+			var last StepEvent
+			x.OnEvent = func(e StepEvent) { last = e }
+
+			resp := NewEngine(exec, nil).Run(context.Background(), x)
+			if resp.Error == nil || resp.Error.Code != tt.wantCode {
+				t.Fatalf("erro = %+v, esperado %s", resp.Error, tt.wantCode)
+			}
+			if !slices.Equal(exec.operations(), tt.wantOps) {
+				t.Errorf("operações = %v, esperado %v", exec.operations(), tt.wantOps)
+			}
+			if last.State != StepFailed || last.Error == nil || last.Error.Code != tt.wantCode {
+				t.Errorf("último evento = %+v, esperado StepFailed", last)
+			}
+			if tr := resp.Trace[len(resp.Trace)-1]; tr.Error == nil || tr.Error.Code != tt.wantCode {
+				t.Errorf("trace do step com erro = %+v", tr)
+			}
+		})
+	}
+}
+
+// blockUntilDone faz a operação informada aguardar o fim do contexto do step
+func blockUntilDone(operation string, started chan<- struct{}) func(ctx context.Context, req OperationRequest) (OperationResponse, bool, error) {
+	return func(ctx context.Context, req OperationRequest) (OperationResponse, bool, error) {
+		if req.Operation != operation {
+			return OperationResponse{}, false, nil
+		}
+		if started != nil {
+			close(started)
+		}
+		<-ctx.Done()
+		return OperationResponse{}, true, ctx.Err()
+	}
+}
+
+func TestEngineStepTimeout(t *testing.T) {
+	// Prazo do step expirado é falha de execução, não cancelamento
+	exec := &stubExecutor{fail: blockUntilDone("multiply", nil)}
+	x := newExecution(t, "(1+2)*3")
+	x.DeadlineMs = 10
+
+	resp := NewEngine(exec, nil).Run(context.Background(), x)
+	if resp.Error == nil || resp.Error.Code != CodeExecutionError {
+		t.Fatalf("erro = %+v, esperado %s", resp.Error, CodeExecutionError)
+	}
+}
+
+func TestEngineCancel(t *testing.T) {
+	t.Run("antes do primeiro step", func(t *testing.T) {
+		exec := &stubExecutor{}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(ErrCancelled)
+		resp := NewEngine(exec, nil).Run(ctx, newExecution(t, "1+1"))
+		if resp.Error == nil || resp.Error.Code != CodeCancelled || resp.Error.Message != "Expressão cancelada pelo cliente" {
+			t.Fatalf("erro = %+v, esperado %s", resp.Error, CodeCancelled)
+		}
+		if len(exec.requests) != 0 {
+			t.Errorf("%d steps despachados após o cancelamento", len(exec.requests))
+		}
+	})
+
+	t.Run("durante um step", func(t *testing.T) {
+		started := make(chan struct{})
+		exec := &stubExecutor{fail: blockUntilDone("multiply", started)}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		go func() {
+			<-started
+			cancel(errors.New("dispatcher encerrando"))
+		}()
+		x := newExecution(t, "(1+2)*3-4")
+		var last StepEvent
+		x.OnEvent = func(e StepEvent) { last = e }
+
+		resp := NewEngine(exec, nil).Run(ctx, x)
+		if resp.Error == nil || resp.Error.Code != CodeCancelled || resp.Error.Message != "Expressão cancelada: dispatcher encerrando" {
+			t.Fatalf("erro = %+v, esperado %s com a causa", resp.Error, CodeCancelled)
+		}
+		if last.State != StepFailed || last.Error.Code != CodeCancelled {
+			t.Errorf("último evento = %+v", last)
+		}
+		if !slices.Equal(exec.operations(), []string{"add", "multiply"}) {
+			t.Errorf("operações = %v", exec.operations())
+		}
+	})
+}
+
+func TestEngineHook(t *testing.T) {
+	type hookKey struct{}
+	type call struct {
+		index   int
+		stepID  string
+		errCode string
+		latency time.Duration
+	}
+	var calls []call
+	hook := func(ctx context.Context, index int, req OperationRequest) (context.Context, func(*ErrorInfo, time.Duration)) {
+		ctx = context.WithValue(ctx, hookKey{}, index)
+		return ctx, func(errInfo *ErrorInfo, latency time.Duration) {
+			c := call{index: index, stepID: req.StepID, latency: latency}
+			if errInfo != nil {
+				c.errCode = errInfo.Code
+			}
+			calls = append(calls, c)
+		}
+	}
+
+	exec := &stubExecutor{}
+	resp := NewEngine(exec, hook).Run(context.Background(), newExecution(t, "(8-2)/0"))
+	if resp.Error == nil || resp.Error.Code != "DIV_BY_ZERO" {
+		t.Fatalf("erro = %+v", resp.Error)
+	}
+	if len(calls) != 2 || calls[0].index != 0 || calls[0].errCode != "" || calls[1].stepID != StepID("e1", 1) || calls[1].errCode != "DIV_BY_ZERO" {
+		t.Errorf("chamadas do hook = %+v", calls)
+	}
+	// O contexto retornado pelo hook chega ao executor (ex: o span do step)
+	for i, ctx := range exec.ctxs {
+		if ctx.Value(hookKey{}) != i {
+			t.Errorf("step %d executado sem o contexto do hook", i)
+		}
+	}
+}
+
+func TestEngineWithoutSteps(t *testing.T) {
+	resp := NewEngine(&stubExecutor{}, nil).Run(context.Background(), &Execution{ExpressionID: "e1"})
+	if resp.Error == nil || resp.Error.Code != "INTERNAL_ERROR" {
+		t.Errorf("erro = %+v, esperado INTERNAL_ERROR", resp.Error)
+	}
+}
+
+func TestResolveOperands(t *testing.T) {
+	step := Step{Operation: "add", Numbers: []float64{0, 0}, DependsOn: []StepDependency{
+		{Position: 0, Reference: "result_step0"},
+		{Position: 1, Reference: "result_step1"},
+	}}
+	results := map[string]float64{"e1_step0": 3, "e1_step1": 4}
+	if got := ResolveOperands("e1", step, results); !slices.Equal(got, []float64{3, 4}) {
+		t.Errorf("ResolveOperands = %v, esperado [3 4]", got)
+	}
+	if step.Numbers[0] != 0 {
+		t.Error("ResolveOperands alterou o step")
+	}
+}
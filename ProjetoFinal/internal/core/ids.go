@@ -0,0 +1,33 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// NewID gera um identificador único (UUIDv7), usado como clientID e como expressionID.
+// O prefixo de tempo mantém os IDs ordenados pela criação, e os 74 bits aleatórios
+// evitam colisões entre clientes iniciados no mesmo instante.
+func NewID() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// ResolveClientID retorna o client_id informado na mensagem ou, em clientes antigos que
+// não o enviam, o clientID embutido no expressionID
+func ResolveClientID(clientID, expressionID string) string {
+	if clientID != "" {
+		return clientID
+	}
+	return ClientIDFromExpressionID(expressionID)
+}
+
+// ClientIDFromExpressionID extrai o clientID de um expressionID no formato antigo
+// (CLIENT-XXXX_expr_Y); retorna "UNKNOWN" para os demais formatos
+func ClientIDFromExpressionID(expressionID string) string {
+	clientID, _, found := strings.Cut(expressionID, "_expr_")
+	if !found || clientID == "" {
+		return "UNKNOWN"
+	}
+	return clientID
+}
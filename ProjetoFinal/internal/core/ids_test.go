@@ -0,0 +1,48 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewID(t *testing.T) {
+	const n = 10000
+	seen := make(map[string]bool, n)
+	prev := ""
+	for range n {
+		id := NewID()
+		u, err := uuid.Parse(id)
+		if err != nil || u.Version() != 7 || u.Variant() != uuid.RFC4122 {
+			t.Fatalf("NewID() = %q, esperado um UUIDv7 (erro: %v)", id, err)
+		}
+		if seen[id] {
+			t.Fatalf("NewID() repetiu %q", id)
+		}
+		seen[id] = true
+		// Gerados em sequência, os IDs mantêm a ordem de criação
+		if id <= prev {
+			t.Fatalf("NewID() = %q após %q, esperado ordem crescente", id, prev)
+		}
+		prev = id
+	}
+}
+
+func TestResolveClientID(t *testing.T) {
+	tests := []struct {
+		clientID, expressionID, want string
+	}{
+		{"0190d6f0-7c1e-7a3b-9f00-000000000001", "0190d6f0-7c1e-7a3b-9f00-000000000002", "0190d6f0-7c1e-7a3b-9f00-000000000001"},
+		{"alice", "CLIENT-1234_expr_1", "alice"},         // client_id tem precedência
+		{"", "CLIENT-1234_expr_1", "CLIENT-1234"},        // cliente antigo
+		{"", "CLIENT-1234_expr_1_expr_2", "CLIENT-1234"}, // apenas o primeiro separador
+		{"", "0190d6f0-7c1e-7a3b-9f00-000000000002", "UNKNOWN"},
+		{"", "_expr_1", "UNKNOWN"},
+		{"", "", "UNKNOWN"},
+	}
+	for _, tt := range tests {
+		if got := ResolveClientID(tt.clientID, tt.expressionID); got != tt.want {
+			t.Errorf("ResolveClientID(%q, %q) = %q, esperado %q", tt.clientID, tt.expressionID, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package core
+
+import (
+	"fmt"
+	"os"
+)
+
+// InstanceID identifica a réplica de um componente (ex: "add@host:1234")
+func InstanceID(component string) string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s@%s:%d", component, hostname, os.Getpid())
+}
@@ -0,0 +1,19 @@
+package core
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestInstanceID(t *testing.T) {
+	id := InstanceID("add")
+	component, rest, ok := strings.Cut(id, "@")
+	if !ok || component != "add" {
+		t.Fatalf("InstanceID = %q, esperado add@<host>:<pid>", id)
+	}
+	if i := strings.LastIndex(rest, ":"); i < 0 || rest[i+1:] != strconv.Itoa(os.Getpid()) {
+		t.Errorf("InstanceID = %q, esperado o pid %d no final", id, os.Getpid())
+	}
+}
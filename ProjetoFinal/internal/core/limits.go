@@ -0,0 +1,102 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Códigos de erro do controle de admissão
+const (
+	CodeExpressionTooComplex = "EXPRESSION_TOO_COMPLEX" // expressão excede um limite de complexidade
+	CodeOverloaded           = "OVERLOADED"             // dispatcher acima do limite de expressões pendentes
+)
+
+// OverloadRetryAfterMs é a espera sugerida quando o dispatcher está sobrecarregado
+const OverloadRetryAfterMs = 500
+
+// Variáveis de ambiente dos limites de complexidade
+const (
+	MaxBytesEnv  = "CALC_MAX_EXPR_BYTES"
+	MaxTokensEnv = "CALC_MAX_EXPR_TOKENS"
+	MaxStepsEnv  = "CALC_MAX_EXPR_STEPS"
+	MaxDepthEnv  = "CALC_MAX_EXPR_DEPTH"
+)
+
+// ErrExpressionTooComplex indica que a expressão excede algum limite de complexidade
+var ErrExpressionTooComplex = errors.New("expressão muito complexa")
+
+// Limits define os limites de complexidade aceitos pelo parser (zero desativa o limite)
+type Limits struct {
+	MaxBytes  int // tamanho da expressão em bytes
+	MaxTokens int // números, operadores e parênteses
+	MaxSteps  int // operações (steps) geradas
+	MaxDepth  int // aninhamento de parênteses
+}
+
+// DefaultLimits retorna os limites padrão
+func DefaultLimits() Limits {
+	return Limits{
+		MaxBytes:  4096,
+		MaxTokens: 1024,
+		MaxSteps:  256,
+		MaxDepth:  32,
+	}
+}
+
+// LimitsFromEnv lê os limites das variáveis de ambiente, partindo dos valores padrão
+func LimitsFromEnv() (Limits, error) {
+	limits := DefaultLimits()
+	for env, field := range map[string]*int{
+		MaxBytesEnv:  &limits.MaxBytes,
+		MaxTokensEnv: &limits.MaxTokens,
+		MaxStepsEnv:  &limits.MaxSteps,
+		MaxDepthEnv:  &limits.MaxDepth,
+	} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return Limits{}, fmt.Errorf("%s inválido: %q", env, value)
+		}
+		*field = n
+	}
+	return limits, nil
+}
+
+// ComplexityError detalha o limite excedido
+type ComplexityError struct {
+	Limit string // "bytes", "tokens", "steps" ou "depth"
+	Max   int
+}
+
+// Descrição de cada limite usada nas mensagens de erro
+var limitNames = map[string]string{
+	"bytes":  "bytes",
+	"tokens": "tokens",
+	"steps":  "operações",
+	"depth":  "níveis de parênteses",
+}
+
+func (e *ComplexityError) Error() string {
+	return fmt.Sprintf("%v: limite de %d %s excedido", ErrExpressionTooComplex, e.Max, limitNames[e.Limit])
+}
+
+func (e *ComplexityError) Unwrap() error {
+	return ErrExpressionTooComplex
+}
+
+// ParseErrorCode retorna o código de erro apropriado para uma falha de parse
+func ParseErrorCode(err error) string {
+	if errors.Is(err, ErrExpressionTooComplex) {
+		return CodeExpressionTooComplex
+	}
+	return "PARSE_ERROR"
+}
+
+func exceeds(value, max int) bool {
+	return max > 0 && value > max
+}
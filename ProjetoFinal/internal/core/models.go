@@ -4,7 +4,9 @@ package core
 type ExpressionRequest struct {
 	ExpressionID string
 	Expression   string
-	DeadlineMs   int64
+	DeadlineMs   int64 // timeout de cada step (zero: sem timeout)
+	IncludeTrace bool
+	Priority     int // 0 a MaxPriority; maior é atendida primeiro
 }
 
 // ExpressionResponse representa uma resposta de expressão
@@ -12,6 +14,7 @@ type ExpressionResponse struct {
 	ExpressionID string
 	Result       float64
 	Error        *ErrorInfo
+	Trace        []StepTrace
 }
 
 // OperationRequest representa uma requisição de operação
@@ -21,6 +24,7 @@ type OperationRequest struct {
 	Operation    string
 	Numbers      []float64
 	DeadlineMs   int64
+	Priority     int
 }
 
 // OperationResponse representa uma resposta de operação
@@ -29,10 +33,23 @@ type OperationResponse struct {
 	StepID       string
 	Result       float64
 	Error        *ErrorInfo
+	Server       string
+}
+
+// StepTrace representa o trace de execução de um step
+type StepTrace struct {
+	StepID    string
+	Operation string
+	Operands  []float64
+	Result    float64
+	Server    string
+	LatencyUs int64
+	Error     *ErrorInfo
 }
 
 // ErrorInfo representa informações de erro
 type ErrorInfo struct {
-	Code    string
-	Message string
+	Code         string
+	Message      string
+	RetryAfterMs int64
 }
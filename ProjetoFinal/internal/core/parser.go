@@ -27,16 +27,29 @@ type Step struct {
 }
 
 // Parser implementa o algoritmo Shunting Yard para converter expressões infix para RPN
-type Parser struct{}
+type Parser struct {
+	limits Limits
+}
 
-// NewParser cria um novo parser
+// NewParser cria um novo parser sem limites de complexidade. Os dispatchers, que recebem
+// expressões de clientes, usam NewParserWithLimits com os limites de LimitsFromEnv.
 func NewParser() *Parser {
-	return &Parser{}
+	return NewParserWithLimits(Limits{})
+}
+
+// NewParserWithLimits cria um parser com limites de complexidade próprios
+func NewParserWithLimits(limits Limits) *Parser {
+	return &Parser{limits: limits}
+}
+
+// Limits retorna os limites de complexidade do parser
+func (p *Parser) Limits() Limits {
+	return p.limits
 }
 
 // Parse converte uma expressão infix em uma sequência de steps (RPN)
 func (p *Parser) Parse(expression string) ([]Step, error) {
-	// Tokeniza a expressão
+	// Tokeniza a expressão (aplicando os limites de complexidade)
 	tokens, err := p.tokenize(expression)
 	if err != nil {
 		return nil, err
@@ -55,7 +68,7 @@ func (p *Parser) Parse(expression string) ([]Step, error) {
 
 // ParseWithRPN converte uma expressão infix em steps e retorna também a string RPN
 func (p *Parser) ParseWithRPN(expression string) ([]Step, string, error) {
-	// Tokeniza a expressão
+	// Tokeniza a expressão (aplicando os limites de complexidade)
 	tokens, err := p.tokenize(expression)
 	if err != nil {
 		return nil, "", err
@@ -81,13 +94,22 @@ func (p *Parser) ParseWithRPN(expression string) ([]Step, string, error) {
 	return steps, rpnStr, nil
 }
 
-// tokenize divide a expressão em tokens
+// tokenize divide a expressão em tokens, abortando assim que um limite é excedido
 func (p *Parser) tokenize(expr string) ([]Token, error) {
+	if exceeds(len(expr), p.limits.MaxBytes) {
+		return nil, &ComplexityError{Limit: "bytes", Max: p.limits.MaxBytes}
+	}
+
 	var tokens []Token
 	expr = strings.ReplaceAll(expr, " ", "")
 
+	depth, operators := 0, 0
 	i := 0
 	for i < len(expr) {
+		if exceeds(len(tokens), p.limits.MaxTokens) {
+			return nil, &ComplexityError{Limit: "tokens", Max: p.limits.MaxTokens}
+		}
+
 		ch := expr[i]
 
 		switch {
@@ -103,15 +125,29 @@ func (p *Parser) tokenize(expr string) ([]Token, error) {
 			// Operador
 			tokens = append(tokens, Token{Type: "operator", Value: string(ch)})
 			i++
+			// Cada operador binário gera um step
+			if operators++; exceeds(operators, p.limits.MaxSteps) {
+				return nil, &ComplexityError{Limit: "steps", Max: p.limits.MaxSteps}
+			}
 		case ch == '(' || ch == ')':
 			// Parênteses
 			tokens = append(tokens, Token{Type: "paren", Value: string(ch)})
 			i++
+			if ch == '(' {
+				if depth++; exceeds(depth, p.limits.MaxDepth) {
+					return nil, &ComplexityError{Limit: "depth", Max: p.limits.MaxDepth}
+				}
+			} else if depth > 0 {
+				depth--
+			}
 		default:
 			return nil, fmt.Errorf("caractere inválido: %c", ch)
 		}
 	}
 
+	if exceeds(len(tokens), p.limits.MaxTokens) {
+		return nil, &ComplexityError{Limit: "tokens", Max: p.limits.MaxTokens}
+	}
 	return tokens, nil
 }
 
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeLimits(t *testing.T) {
+	limits := Limits{MaxBytes: 40, MaxTokens: 12, MaxSteps: 3, MaxDepth: 2}
+
+	tests := []struct {
+		name      string
+		expr      string
+		limits    Limits
+		wantLimit string // vazio: aceita
+	}{
+		{"dentro dos limites", "((1+2)*3)-4", limits, ""},
+		{"bytes", strings.Repeat("1", 41), limits, "bytes"},
+		{"bytes contam espaços", "1 +" + strings.Repeat(" ", 40) + "2", limits, "bytes"},
+		{"tokens", "1+2+3+(4)", Limits{MaxTokens: 8}, "tokens"},
+		{"tokens no limite", "1+2+3+(4)", Limits{MaxTokens: 9}, ""},
+		{"steps", "1+2+3+4+5", limits, "steps"},
+		{"steps no limite", "1+2+3+4", limits, ""},
+		{"depth", "(((1+2)))", limits, "depth"},
+		{"depth após fechar parênteses", "((1))+((2))", limits, ""},
+		{"zero desativa os limites", strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100) + strings.Repeat("+1", 500), Limits{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParserWithLimits(tt.limits).tokenize(tt.expr)
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("erro inesperado: %v", err)
+				}
+				return
+			}
+			var ce *ComplexityError
+			if !errors.As(err, &ce) || ce.Limit != tt.wantLimit {
+				t.Fatalf("erro = %v, esperado limite %s", err, tt.wantLimit)
+			}
+			if !errors.Is(err, ErrExpressionTooComplex) || ParseErrorCode(err) != CodeExpressionTooComplex {
+				t.Errorf("erro %v não é classificado como %s", err, CodeExpressionTooComplex)
+			}
+		})
+	}
+}
+
+func TestNewParserIsUnlimited(t *testing.T) {
+	if got := NewParser().Limits(); got != (Limits{}) {
+		t.Fatalf("NewParser().Limits() = %+v, esperado sem limites", got)
+	}
+	expr := strings.Repeat("1+", 2000) + "1"
+	if _, err := NewParser().Parse(expr); err != nil {
+		t.Fatalf("expressão longa rejeitada: %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	steps, rpn, err := NewParser().ParseWithRPN("((4+3)*2)/5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpn != "4 3 + 2 * 5 /" {
+		t.Errorf("rpn = %q", rpn)
+	}
+	if len(steps) != 3 || steps[0].Operation != "add" || steps[2].Operation != "divide" {
+		t.Errorf("steps = %+v", steps)
+	}
+
+	if _, err := NewParser().Parse("2+x"); err == nil || ParseErrorCode(err) != "PARSE_ERROR" {
+		t.Errorf("caractere inválido: erro = %v", err)
+	}
+}
+
+func TestLimitsFromEnv(t *testing.T) {
+	t.Setenv(MaxBytesEnv, "100")
+	t.Setenv(MaxDepthEnv, "0")
+	limits, err := LimitsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultLimits()
+	want.MaxBytes, want.MaxDepth = 100, 0
+	if limits != want {
+		t.Errorf("limits = %+v, esperado %+v", limits, want)
+	}
+
+	for _, value := range []string{"-1", "abc"} {
+		t.Setenv(MaxStepsEnv, value)
+		if _, err := LimitsFromEnv(); err == nil {
+			t.Errorf("%s=%q aceito", MaxStepsEnv, value)
+		}
+	}
+}
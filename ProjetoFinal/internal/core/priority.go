@@ -0,0 +1,13 @@
+package core
+
+// Prioridades das expressões (maior é atendida primeiro)
+const (
+	MaxPriority         = 9 // x-max-priority das filas RabbitMQ
+	PriorityBatch       = 1 // benchmarks e cargas em lote
+	PriorityInteractive = 8 // clientes interativos (CLI)
+)
+
+// ClampPriority restringe a prioridade ao intervalo [0, MaxPriority]
+func ClampPriority(priority int) int {
+	return min(max(priority, 0), MaxPriority)
+}
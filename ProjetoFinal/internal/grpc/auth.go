@@ -0,0 +1,111 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AuthInterceptor autentica as chamadas pelo header authorization ("Bearer <token>"),
+// com JWT ou segredo de API key, e coloca o principal no contexto.
+// Com o autenticador desativado, as chamadas passam sem verificação.
+func AuthInterceptor(a *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !a.Enabled() {
+			return handler(ctx, req)
+		}
+
+		principal, err := a.AuthenticateToken(bearerToken(ctx))
+		if err != nil {
+			metrics.AuthFailuresTotal.WithLabelValues("grpc", auth.Reason(err)).Inc()
+			return nil, status.Errorf(codes.Unauthenticated, "autenticação falhou: %v", err)
+		}
+		return handler(auth.NewContext(ctx, principal), req)
+	}
+}
+
+// AuthStreamInterceptor é o equivalente de AuthInterceptor para chamadas com streaming
+func AuthStreamInterceptor(a *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !a.Enabled() {
+			return handler(srv, ss)
+		}
+
+		principal, err := a.AuthenticateToken(bearerToken(ss.Context()))
+		if err != nil {
+			metrics.AuthFailuresTotal.WithLabelValues("grpc", auth.Reason(err)).Inc()
+			return status.Errorf(codes.Unauthenticated, "autenticação falhou: %v", err)
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: auth.NewContext(ss.Context(), principal)})
+	}
+}
+
+// authenticatedStream substitui o contexto do stream pelo contexto com o principal
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(auth.HeaderAuthorization)
+	if len(values) == 0 {
+		return ""
+	}
+	token, found := strings.CutPrefix(values[0], "Bearer ")
+	if !found {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// OutgoingPrincipal repassa o principal autenticado aos servidores de operação
+func OutgoingPrincipal(ctx context.Context, p auth.Principal) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, auth.HeaderPrincipal, p.ID)
+}
+
+// IncomingPrincipal retorna o principal repassado pelo dispatcher, ou fallback se ausente
+func IncomingPrincipal(ctx context.Context, fallback string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return fallback
+	}
+	if values := md.Get(auth.HeaderPrincipal); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+	return fallback
+}
+
+// TokenCredentials envia o token do cliente em cada chamada
+type TokenCredentials struct {
+	token string
+}
+
+// NewTokenCredentials cria credenciais por chamada a partir das credenciais do cliente
+func NewTokenCredentials(creds auth.Credentials) TokenCredentials {
+	return TokenCredentials{token: creds.BearerToken()}
+}
+
+// GetRequestMetadata implementa credentials.PerRPCCredentials
+func (t TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{auth.HeaderAuthorization: "Bearer " + t.token}, nil
+}
+
+// RequireTransportSecurity implementa credentials.PerRPCCredentials. O token (ou o
+// segredo da API key) trafega no header, então só é enviado em conexões TLS.
+func (t TokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
@@ -0,0 +1,43 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// StepDedupInterceptor atende chamadas repetidas a OperationService.Execute (mesmo StepID,
+// operação e operandos dentro da janela) com a resposta da primeira execução. Erros de
+// RPC (ex: step cancelado) não são guardados.
+func StepDedupInterceptor(window time.Duration) grpc.UnaryServerInterceptor {
+	dedup := core.NewStepDedup[*pb.OperationResponse](window)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		opReq, ok := req.(*pb.OperationRequest)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		resp, duplicate, err := dedup.Do(ctx, opReq.StepId, opReq.Operation, opReq.Numbers, func() (*pb.OperationResponse, error) {
+			resp, err := handler(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			return resp.(*pb.OperationResponse), nil
+		})
+		if err != nil {
+			if _, ok := status.FromError(err); !ok {
+				err = status.FromContextError(err).Err()
+			}
+			return nil, err
+		}
+		if duplicate {
+			metrics.DuplicateStepsTotal.WithLabelValues("grpc", metrics.Operation(opReq.Operation)).Inc()
+		}
+		return resp, nil
+	}
+}
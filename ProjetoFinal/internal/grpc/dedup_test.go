@@ -0,0 +1,124 @@
+package grpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var executeInfo = &grpc.UnaryServerInfo{FullMethod: "/calculator.OperationService/Execute"}
+
+// countingHandler soma os operandos e conta as execuções; err, se definido, é retornado
+type countingHandler struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (h *countingHandler) handle(ctx context.Context, req any) (any, error) {
+	h.calls.Add(1)
+	if h.err != nil {
+		return nil, h.err
+	}
+	r := req.(*pb.OperationRequest)
+	return &pb.OperationResponse{StepId: r.StepId, Result: r.Numbers[0] + r.Numbers[1]}, nil
+}
+
+func opRequest(stepID string, a, b float64) *pb.OperationRequest {
+	return &pb.OperationRequest{ExpressionId: "e1", StepId: stepID, Operation: "add", Numbers: []float64{a, b}}
+}
+
+func TestStepDedupInterceptorReplays(t *testing.T) {
+	interceptor := StepDedupInterceptor(time.Minute)
+	h := &countingHandler{}
+	duplicates := metrics.DuplicateStepsTotal.WithLabelValues("grpc", "add")
+	before := testutil.ToFloat64(duplicates)
+
+	for range 3 {
+		resp, err := interceptor(context.Background(), opRequest("s1", 1, 2), executeInfo, h.handle)
+		if err != nil || resp.(*pb.OperationResponse).Result != 3 {
+			t.Fatalf("resposta = %v, erro = %v", resp, err)
+		}
+	}
+	if h.calls.Load() != 1 {
+		t.Errorf("handler executado %d vezes, esperado 1", h.calls.Load())
+	}
+	if got := testutil.ToFloat64(duplicates) - before; got != 2 {
+		t.Errorf("duplicate_steps_total += %v, esperado 2", got)
+	}
+
+	// Mesmo StepID com outros operandos executa de novo
+	if resp, _ := interceptor(context.Background(), opRequest("s1", 2, 2), executeInfo, h.handle); resp.(*pb.OperationResponse).Result != 4 {
+		t.Errorf("resposta = %v, esperado 4", resp)
+	}
+}
+
+func TestStepDedupInterceptorDoesNotCacheErrors(t *testing.T) {
+	interceptor := StepDedupInterceptor(time.Minute)
+	failed := status.Error(codes.Unavailable, "servidor sobrecarregado")
+	h := &countingHandler{err: failed}
+
+	_, err := interceptor(context.Background(), opRequest("s1", 1, 2), executeInfo, h.handle)
+	if status.Code(err) != codes.Unavailable || status.Convert(err).Message() != "servidor sobrecarregado" {
+		t.Fatalf("erro = %v, esperado o erro do handler", err)
+	}
+	h.err = nil
+	if resp, err := interceptor(context.Background(), opRequest("s1", 1, 2), executeInfo, h.handle); err != nil || resp.(*pb.OperationResponse).Result != 3 {
+		t.Fatalf("nova tentativa = %v, %v", resp, err)
+	}
+	if h.calls.Load() != 2 {
+		t.Errorf("handler executado %d vezes, esperado 2", h.calls.Load())
+	}
+}
+
+func TestStepDedupInterceptorContextError(t *testing.T) {
+	interceptor := StepDedupInterceptor(time.Minute)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	blocking := func(ctx context.Context, req any) (any, error) {
+		close(started)
+		<-release
+		return &pb.OperationResponse{Result: 3}, nil
+	}
+	go interceptor(context.Background(), opRequest("s1", 1, 2), executeInfo, blocking)
+	<-started
+	defer close(release)
+
+	// A repetição que desiste de aguardar a execução original recebe um status gRPC
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := interceptor(ctx, opRequest("s1", 1, 2), executeInfo, (&countingHandler{}).handle)
+	if _, ok := status.FromError(err); !ok || status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("erro = %v, esperado status %s", err, codes.DeadlineExceeded)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := interceptor(ctx, opRequest("s1", 1, 2), executeInfo, (&countingHandler{}).handle); status.Code(err) != codes.Canceled {
+		t.Fatalf("erro = %v, esperado status %s", err, codes.Canceled)
+	}
+}
+
+func TestStepDedupInterceptorIgnoresOtherRequests(t *testing.T) {
+	interceptor := StepDedupInterceptor(time.Minute)
+	var calls int
+	handler := func(ctx context.Context, req any) (any, error) {
+		calls++
+		return req, nil
+	}
+	for range 2 {
+		if _, err := interceptor(context.Background(), &pb.ExpressionRequest{ExpressionId: "e1"}, executeInfo, handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler executado %d vezes, esperado 2", calls)
+	}
+}
@@ -0,0 +1,207 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/sched"
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Executor implementa core.OperationExecutor chamando os servidores de operação via gRPC
+type Executor struct {
+	clients   map[string]pb.OperationServiceClient
+	addrs     map[string]string
+	scheduler *sched.Scheduler // vagas de execução concedidas por prioridade (nil: sem limite)
+}
+
+// NewExecutor cria um executor sem servidores conectados
+func NewExecutor(scheduler *sched.Scheduler) *Executor {
+	return &Executor{
+		clients:   make(map[string]pb.OperationServiceClient),
+		addrs:     make(map[string]string),
+		scheduler: scheduler,
+	}
+}
+
+// Connect conecta ao servidor responsável pela operação
+func (e *Executor) Connect(operation, addr string, creds credentials.TransportCredentials) error {
+	conn, err := grpc.Dial(addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5*time.Second),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+	if err != nil {
+		return fmt.Errorf("falha ao conectar ao servidor %s: %v", operation, err)
+	}
+	e.clients[operation] = pb.NewOperationServiceClient(conn)
+	e.addrs[operation] = addr
+	return nil
+}
+
+// Execute chama o servidor da operação, repassando o principal do contexto
+func (e *Executor) Execute(ctx context.Context, req core.OperationRequest) (core.OperationResponse, error) {
+	client, ok := e.clients[req.Operation]
+	if !ok {
+		return core.OperationResponse{
+			ExpressionID: req.ExpressionID,
+			StepID:       req.StepID,
+			Error: &core.ErrorInfo{
+				Code:    "UNKNOWN_OPERATION",
+				Message: fmt.Sprintf("Operação desconhecida: %s", req.Operation),
+			},
+		}, nil
+	}
+
+	// Com o dispatcher saturado, steps de maior prioridade passam à frente
+	release, err := e.scheduler.Acquire(ctx, req.Priority)
+	if err != nil {
+		return core.OperationResponse{}, err
+	}
+	defer release()
+
+	if principal, ok := auth.FromContext(ctx); ok {
+		ctx = OutgoingPrincipal(ctx, principal)
+	}
+
+	resp, err := client.Execute(ctx, &pb.OperationRequest{
+		ExpressionId: req.ExpressionID,
+		StepId:       req.StepID,
+		Operation:    req.Operation,
+		Numbers:      req.Numbers,
+		DeadlineMs:   req.DeadlineMs,
+	})
+	if err != nil {
+		return core.OperationResponse{}, err
+	}
+
+	server := resp.Server
+	if server == "" {
+		server = e.addrs[req.Operation]
+	}
+	return core.OperationResponse{
+		ExpressionID: resp.ExpressionId,
+		StepID:       resp.StepId,
+		Result:       resp.Result,
+		Error:        ErrorFromProto(resp.Error),
+		Server:       server,
+	}, nil
+}
+
+// ErrorFromProto converte o erro do protocolo para o modelo comum
+func ErrorFromProto(e *pb.ErrorInfo) *core.ErrorInfo {
+	if e == nil {
+		return nil
+	}
+	return &core.ErrorInfo{Code: e.Code, Message: e.Message, RetryAfterMs: e.RetryAfterMs}
+}
+
+// ErrorToProto converte o erro do modelo comum para o protocolo
+func ErrorToProto(e *core.ErrorInfo) *pb.ErrorInfo {
+	if e == nil {
+		return nil
+	}
+	return &pb.ErrorInfo{Code: e.Code, Message: e.Message, RetryAfterMs: e.RetryAfterMs}
+}
+
+// TraceToProto converte o trace de execução para o protocolo
+func TraceToProto(trace []core.StepTrace) []*pb.StepTrace {
+	if len(trace) == 0 {
+		return nil
+	}
+	out := make([]*pb.StepTrace, len(trace))
+	for i, t := range trace {
+		out[i] = &pb.StepTrace{
+			StepId:    t.StepID,
+			Operation: t.Operation,
+			Operands:  t.Operands,
+			Result:    t.Result,
+			Server:    t.Server,
+			LatencyUs: t.LatencyUs,
+			Error:     ErrorToProto(t.Error),
+		}
+	}
+	return out
+}
+
+// StepProgressToProto converte o evento de progresso de um step para o protocolo
+func StepProgressToProto(e core.StepEvent) *pb.StepProgress {
+	state := pb.StepState_STEP_STATE_UNSPECIFIED
+	switch e.State {
+	case core.StepDispatched:
+		state = pb.StepState_STEP_DISPATCHED
+	case core.StepCompleted:
+		state = pb.StepState_STEP_COMPLETED
+	case core.StepFailed:
+		state = pb.StepState_STEP_FAILED
+	}
+	return &pb.StepProgress{
+		StepIndex:     int32(e.Index),
+		TotalSteps:    int32(e.Total),
+		StepId:        e.StepID,
+		Operation:     e.Operation,
+		Operands:      e.Operands,
+		State:         state,
+		PartialResult: e.Result,
+		Error:         ErrorToProto(e.Error),
+	}
+}
+
+// StepProgressFromProto converte o evento de progresso do protocolo para o modelo comum
+func StepProgressFromProto(p *pb.StepProgress) core.StepEvent {
+	var state core.StepState
+	switch p.State {
+	case pb.StepState_STEP_DISPATCHED:
+		state = core.StepDispatched
+	case pb.StepState_STEP_COMPLETED:
+		state = core.StepCompleted
+	case pb.StepState_STEP_FAILED:
+		state = core.StepFailed
+	}
+	return core.StepEvent{
+		Index:     int(p.StepIndex),
+		Total:     int(p.TotalSteps),
+		StepID:    p.StepId,
+		Operation: p.Operation,
+		Operands:  p.Operands,
+		State:     state,
+		Result:    p.PartialResult,
+		Error:     ErrorFromProto(p.Error),
+	}
+}
+
+// ResponseFromProto converte a resposta do protocolo para o modelo comum
+func ResponseFromProto(r *pb.ExpressionResponse) core.ExpressionResponse {
+	return core.ExpressionResponse{
+		ExpressionID: r.ExpressionId,
+		Result:       r.Result,
+		Error:        ErrorFromProto(r.Error),
+		Trace:        TraceFromProto(r.Trace),
+	}
+}
+
+// TraceFromProto converte o trace do protocolo para o modelo comum
+func TraceFromProto(trace []*pb.StepTrace) []core.StepTrace {
+	if len(trace) == 0 {
+		return nil
+	}
+	out := make([]core.StepTrace, len(trace))
+	for i, t := range trace {
+		out[i] = core.StepTrace{
+			StepID:    t.StepId,
+			Operation: t.Operation,
+			Operands:  t.Operands,
+			Result:    t.Result,
+			Server:    t.Server,
+			LatencyUs: t.LatencyUs,
+			Error:     ErrorFromProto(t.Error),
+		}
+	}
+	return out
+}
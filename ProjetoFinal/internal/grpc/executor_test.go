@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+)
+
+func sampleTrace() []core.StepTrace {
+	return []core.StepTrace{
+		{StepID: "e1_step0", Operation: "add", Operands: []float64{2, 3}, Result: 5, Server: "add@host:1", LatencyUs: 120},
+		{StepID: "e1_step1", Operation: "divide", Operands: []float64{5, 0}, Server: "divide@host:2", LatencyUs: 80,
+			Error: &core.ErrorInfo{Code: "DIV_BY_ZERO", Message: "divisão por zero"}},
+	}
+}
+
+func TestTraceRoundTrip(t *testing.T) {
+	trace := sampleTrace()
+	proto := TraceToProto(trace)
+	if len(proto) != 2 || proto[1].Error.GetCode() != "DIV_BY_ZERO" || proto[0].Server != "add@host:1" {
+		t.Fatalf("TraceToProto = %v", proto)
+	}
+	if got := TraceFromProto(proto); !reflect.DeepEqual(got, trace) {
+		t.Errorf("TraceFromProto(TraceToProto(trace)) = %+v, esperado %+v", got, trace)
+	}
+}
+
+func TestTraceEmpty(t *testing.T) {
+	// Sem trace solicitado, a resposta não carrega a lista (nem vazia)
+	if got := TraceToProto(nil); got != nil {
+		t.Errorf("TraceToProto(nil) = %v, esperado nil", got)
+	}
+	if got := TraceFromProto([]*pb.StepTrace{}); got != nil {
+		t.Errorf("TraceFromProto(vazio) = %v, esperado nil", got)
+	}
+}
+
+func TestResponseFromProto(t *testing.T) {
+	resp := ResponseFromProto(&pb.ExpressionResponse{
+		ExpressionId: "e1",
+		Error:        &pb.ErrorInfo{Code: "DIV_BY_ZERO", Message: "divisão por zero"},
+		Trace:        TraceToProto(sampleTrace()),
+	})
+	if resp.ExpressionID != "e1" || resp.Error == nil || resp.Error.Code != "DIV_BY_ZERO" || !reflect.DeepEqual(resp.Trace, sampleTrace()) {
+		t.Errorf("ResponseFromProto = %+v", resp)
+	}
+}
@@ -0,0 +1,35 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"google.golang.org/grpc"
+)
+
+// OperationMetricsInterceptor registra contadores e latência das chamadas a OperationService.Execute
+func OperationMetricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		opReq, ok := req.(*pb.OperationRequest)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		code := metrics.CodeOK
+		if err != nil {
+			code = "RPC_ERROR"
+		} else if opResp, ok := resp.(*pb.OperationResponse); ok && opResp.Error != nil {
+			code = opResp.Error.Code
+		}
+
+		operation := metrics.Operation(opReq.Operation)
+		metrics.OperationsTotal.WithLabelValues("grpc", operation, code).Inc()
+		metrics.OperationDuration.WithLabelValues("grpc", operation).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
@@ -0,0 +1,42 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestOperationMetricsInterceptor(t *testing.T) {
+	interceptor := OperationMetricsInterceptor()
+	handler := func(ctx context.Context, req any) (any, error) {
+		r := req.(*pb.OperationRequest)
+		if _, err := ExecuteOperation(r.Operation, r.Numbers); err != nil {
+			return &pb.OperationResponse{Error: &pb.ErrorInfo{Code: "INVALID_OPERATION", Message: err.Error()}}, nil
+		}
+		return &pb.OperationResponse{}, nil
+	}
+
+	tests := []struct {
+		operation, wantLabel, wantCode string
+	}{
+		{"add", "add", metrics.CodeOK},
+		{"divide", "divide", "INVALID_OPERATION"}, // divisão por zero
+		{"rm -rf", metrics.OperationOther, "INVALID_OPERATION"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operation, func(t *testing.T) {
+			counter := metrics.OperationsTotal.WithLabelValues("grpc", tt.wantLabel, tt.wantCode)
+			before := testutil.ToFloat64(counter)
+			req := &pb.OperationRequest{Operation: tt.operation, Numbers: []float64{1, 0}}
+			if _, err := interceptor(context.Background(), req, executeInfo, handler); err != nil {
+				t.Fatal(err)
+			}
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("operations_total{operation=%q,error_code=%q} += %v, esperado 1", tt.wantLabel, tt.wantCode, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// Package instrument liga o Engine do core ao tracing (OpenTelemetry) e às métricas
+// (Prometheus), para que o core não dependa dessas bibliotecas
+package instrument
+
+import (
+	"context"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/telemetry"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StepHook cria um span por step e registra o step em calculator_steps_total e na
+// duração dos steps, com o rótulo transport informado
+func StepHook(transport string) core.StepHook {
+	return func(ctx context.Context, index int, req core.OperationRequest) (context.Context, func(*core.ErrorInfo, time.Duration)) {
+		ctx, span := telemetry.Tracer().Start(ctx, "step "+req.Operation, trace.WithAttributes(
+			telemetry.AttrStepID.String(req.StepID),
+			telemetry.AttrStepIndex.Int(index),
+			telemetry.AttrOperation.String(req.Operation),
+		))
+		return ctx, func(errInfo *core.ErrorInfo, latency time.Duration) {
+			code := metrics.CodeOK
+			if errInfo != nil {
+				code = errInfo.Code
+				span.SetAttributes(telemetry.AttrErrorCode.String(errInfo.Code))
+				span.SetStatus(codes.Error, errInfo.Message)
+			}
+			span.End()
+			metrics.StepsTotal.WithLabelValues(transport, req.Operation, code).Inc()
+			metrics.StepDuration.WithLabelValues(transport, req.Operation).Observe(latency.Seconds())
+		}
+	}
+}
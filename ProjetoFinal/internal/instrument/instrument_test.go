@@ -0,0 +1,34 @@
+package instrument
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStepHookRecordsMetrics(t *testing.T) {
+	hook := StepHook("teste")
+	req := core.OperationRequest{StepID: "e1_step0", Operation: "divide"}
+	ok := metrics.StepsTotal.WithLabelValues("teste", "divide", metrics.CodeOK)
+	failed := metrics.StepsTotal.WithLabelValues("teste", "divide", "DIV_BY_ZERO")
+	before, beforeFailed := testutil.ToFloat64(ok), testutil.ToFloat64(failed)
+
+	ctx, done := hook(context.Background(), 0, req)
+	if ctx == nil {
+		t.Fatal("hook retornou contexto nulo")
+	}
+	done(nil, time.Millisecond)
+	_, done = hook(context.Background(), 1, req)
+	done(&core.ErrorInfo{Code: "DIV_BY_ZERO", Message: "divisão por zero"}, time.Millisecond)
+
+	if got := testutil.ToFloat64(ok) - before; got != 1 {
+		t.Errorf("steps com sucesso = %v, esperado 1", got)
+	}
+	if got := testutil.ToFloat64(failed) - beforeFailed; got != 1 {
+		t.Errorf("steps com DIV_BY_ZERO = %v, esperado 1", got)
+	}
+}
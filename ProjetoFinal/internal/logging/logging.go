@@ -0,0 +1,207 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Variáveis de ambiente de configuração dos logs
+const (
+	FormatEnv   = "CALC_LOG_FORMAT"   // "text" (padrão) ou "json"
+	LevelEnv    = "CALC_LOG_LEVEL"    // ex: "info" ou "info,dispatcher=debug,add=warn"
+	SamplingEnv = "CALC_LOG_SAMPLING" // "<primeiros>,<depois 1 a cada N>" por segundo, ex: "100,100"; "off" desativa
+)
+
+// Chaves padronizadas dos campos estruturados
+const (
+	KeyComponent    = "component"
+	KeyExpressionID = "expression_id"
+	KeyStepID       = "step_id"
+	KeyClientID     = "client_id"
+	KeyOperation    = "operation"
+	KeyError        = "error"
+	KeyTraceID      = "trace_id"
+)
+
+// Config define formato, níveis e amostragem dos logs
+type Config struct {
+	Format          string
+	Level           slog.Level
+	ComponentLevels map[string]slog.Level
+	SampleInitial   int // registros por segundo mantidos integralmente (por mensagem)
+	SampleEvery     int // depois do limite, mantém 1 a cada N (0 desativa a amostragem)
+}
+
+var (
+	mu     sync.RWMutex
+	config = Config{Format: "text", Level: slog.LevelInfo, SampleInitial: 100, SampleEvery: 100}
+	base   slog.Handler
+)
+
+// ConfigFromEnv lê a configuração das variáveis de ambiente
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Format:          "text",
+		Level:           slog.LevelInfo,
+		ComponentLevels: make(map[string]slog.Level),
+		SampleInitial:   100,
+		SampleEvery:     100,
+	}
+
+	if format := strings.ToLower(os.Getenv(FormatEnv)); format == "json" {
+		cfg.Format = format
+	}
+
+	for _, part := range strings.Split(os.Getenv(LevelEnv), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if name, lvl, ok := strings.Cut(part, "="); ok {
+			if level, err := parseLevel(lvl); err == nil {
+				cfg.ComponentLevels[strings.ToLower(name)] = level
+			}
+			continue
+		}
+		if level, err := parseLevel(part); err == nil {
+			cfg.Level = level
+		}
+	}
+
+	if sampling := os.Getenv(SamplingEnv); sampling != "" {
+		if sampling == "off" {
+			cfg.SampleEvery = 0
+		} else if first, every, ok := strings.Cut(sampling, ","); ok {
+			if n, err := strconv.Atoi(first); err == nil {
+				cfg.SampleInitial = n
+			}
+			if n, err := strconv.Atoi(every); err == nil {
+				cfg.SampleEvery = n
+			}
+		}
+	}
+
+	return cfg
+}
+
+func parseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(s)))
+	return level, err
+}
+
+// Setup configura o logger padrão do processo a partir do ambiente e
+// retorna o logger do componente informado
+func Setup(component string) *slog.Logger {
+	return SetupWithConfig(component, ConfigFromEnv())
+}
+
+// SetupWithConfig configura o logger padrão do processo com a configuração informada
+func SetupWithConfig(component string, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+
+	var handler slog.Handler
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	handler = &contextHandler{next: handler}
+	if cfg.SampleEvery > 0 {
+		handler = newSamplingHandler(handler, cfg.SampleInitial, cfg.SampleEvery)
+	}
+
+	mu.Lock()
+	config = cfg
+	base = handler
+	mu.Unlock()
+
+	logger := Component(component)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// Component retorna um logger com o nível configurado para o componente
+func Component(name string) *slog.Logger {
+	mu.RLock()
+	handler, cfg := base, config
+	mu.RUnlock()
+
+	if handler == nil {
+		handler = slog.Default().Handler()
+	}
+
+	level, ok := cfg.ComponentLevels[strings.ToLower(name)]
+	if !ok {
+		level = cfg.Level
+	}
+
+	return slog.New(&levelHandler{level: level, next: handler}).With(KeyComponent, name)
+}
+
+// Truncate limita valores potencialmente grandes (ex: expressões) antes de irem para o log
+func Truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "…"
+}
+
+// Err formata um erro como campo estruturado
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+// levelHandler aplica um nível mínimo próprio sobre um handler compartilhado
+type levelHandler struct {
+	level slog.Leveler
+	next  slog.Handler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.next.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{level: h.level, next: h.next.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{level: h.level, next: h.next.WithGroup(name)}
+}
+
+// contextHandler adiciona o trace_id do span ativo quando o log recebe um contexto
+type contextHandler struct {
+	next slog.Handler
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	// Lê o span direto da API do OpenTelemetry: o logging (e o core, que o usa) não
+	// depende do SDK configurado em internal/telemetry
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		r.AddAttrs(slog.String(KeyTraceID, sc.TraceID().String()))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name)}
+}
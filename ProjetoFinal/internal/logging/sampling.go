@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// samplingHandler limita o volume de logs repetitivos nos caminhos quentes.
+// A cada segundo, os primeiros `initial` registros de uma mesma mensagem são
+// mantidos; depois disso, apenas 1 a cada `every`. Avisos e erros nunca são descartados.
+type samplingHandler struct {
+	next    slog.Handler
+	initial uint64
+	every   uint64
+	state   *samplingState
+	now     func() time.Time // relógio que define a janela de 1s (substituível nos testes)
+}
+
+type samplingState struct {
+	mu       sync.Mutex
+	counters map[string]*samplingCounter
+}
+
+type samplingCounter struct {
+	window atomic.Int64
+	count  atomic.Uint64
+}
+
+func newSamplingHandler(next slog.Handler, initial, every int) *samplingHandler {
+	return &samplingHandler{
+		next:    next,
+		initial: uint64(initial),
+		every:   uint64(every),
+		state:   &samplingState{counters: make(map[string]*samplingCounter)},
+		now:     time.Now,
+	}
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn || h.sample(r) {
+		return h.next.Handle(ctx, r)
+	}
+	return nil
+}
+
+func (h *samplingHandler) sample(r slog.Record) bool {
+	counter := h.state.counter(r.Message)
+
+	// A janela vem do relógio do handler, não de r.Time: registros montados
+	// manualmente podem chegar com o horário zerado
+
+	window := h.now().Truncate(time.Second).UnixNano()
+	if counter.window.Swap(window) != window {
+		counter.count.Store(0)
+	}
+
+	n := counter.count.Add(1)
+	if n <= h.initial {
+		return true
+	}
+	return (n-h.initial)%h.every == 0
+}
+
+func (s *samplingState) counter(key string) *samplingCounter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok {
+		c = &samplingCounter{}
+		s.counters[key] = c
+	}
+	return c
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), initial: h.initial, every: h.every, state: h.state, now: h.now}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), initial: h.initial, every: h.every, state: h.state, now: h.now}
+}
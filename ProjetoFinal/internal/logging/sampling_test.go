@@ -0,0 +1,151 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureHandler guarda as mensagens que chegam ao fim da cadeia de handlers
+type captureHandler struct {
+	mu       sync.Mutex
+	messages *[]string
+}
+
+func newCaptureHandler() *captureHandler {
+	return &captureHandler{messages: new([]string)}
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.messages = append(*h.messages, r.Message)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *captureHandler) WithGroup(string) slog.Handler      { return h }
+
+func (h *captureHandler) count(msg string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, m := range *h.messages {
+		if m == msg {
+			n++
+		}
+	}
+	return n
+}
+
+// fakeClock é um relógio manual para controlar a janela de amostragem
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newSampledLogger(initial, every int) (*slog.Logger, *captureHandler, *fakeClock) {
+	capture := newCaptureHandler()
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	h := newSamplingHandler(capture, initial, every)
+	h.now = clock.now
+	return slog.New(h), capture, clock
+}
+
+func TestSamplingFirstThenEvery(t *testing.T) {
+	logger, capture, _ := newSampledLogger(3, 5)
+
+	for range 23 {
+		logger.Info("step concluído")
+	}
+	// 3 iniciais + 1 a cada 5 dos 20 restantes
+	if got := capture.count("step concluído"); got != 7 {
+		t.Errorf("registros mantidos = %d, esperado 7", got)
+	}
+}
+
+func TestSamplingPerMessage(t *testing.T) {
+	logger, capture, _ := newSampledLogger(2, 100)
+
+	for range 10 {
+		logger.Info("a")
+		logger.Info("b")
+	}
+	// Cada mensagem tem seu próprio contador
+	if capture.count("a") != 2 || capture.count("b") != 2 {
+		t.Errorf("mantidos a=%d b=%d, esperado 2 de cada", capture.count("a"), capture.count("b"))
+	}
+}
+
+func TestSamplingWindowReset(t *testing.T) {
+	logger, capture, clock := newSampledLogger(2, 100)
+
+	for range 5 {
+		logger.Info("msg")
+	}
+	clock.advance(500 * time.Millisecond) // mesma janela de 1s
+	logger.Info("msg")
+	if got := capture.count("msg"); got != 2 {
+		t.Fatalf("registros mantidos na primeira janela = %d, esperado 2", got)
+	}
+
+	clock.advance(time.Second)
+	for range 5 {
+		logger.Info("msg")
+	}
+	if got := capture.count("msg"); got != 4 {
+		t.Errorf("registros mantidos após a virada = %d, esperado 4", got)
+	}
+}
+
+func TestSamplingKeepsWarnings(t *testing.T) {
+	logger, capture, _ := newSampledLogger(1, 1000)
+
+	for range 10 {
+		logger.Warn("fila cheia")
+		logger.Error("falha")
+	}
+	if capture.count("fila cheia") != 10 || capture.count("falha") != 10 {
+		t.Errorf("avisos = %d, erros = %d, esperado 10 de cada", capture.count("fila cheia"), capture.count("falha"))
+	}
+}
+
+func TestSamplingSharedAcrossDerivedLoggers(t *testing.T) {
+	logger, capture, _ := newSampledLogger(2, 100)
+
+	// Loggers derivados (With/WithGroup) compartilham os contadores
+	logger.With("component", "add").Info("msg")
+	logger.WithGroup("g").Info("msg")
+	logger.Info("msg")
+	if got := capture.count("msg"); got != 2 {
+		t.Errorf("registros mantidos = %d, esperado 2", got)
+	}
+}
+
+func TestConfigFromEnvSampling(t *testing.T) {
+	tests := []struct {
+		env                    string
+		wantInitial, wantEvery int
+	}{
+		{"", 100, 100},
+		{"off", 100, 0},
+		{"10,50", 10, 50},
+		{"0,1", 0, 1},
+		{"10", 100, 100},  // sem vírgula: mantém o padrão
+		{"x,20", 100, 20}, // parte inválida mantém o padrão
+		{"10,abc", 10, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv(SamplingEnv, tt.env)
+			cfg := ConfigFromEnv()
+			if cfg.SampleInitial != tt.wantInitial || cfg.SampleEvery != tt.wantEvery {
+				t.Errorf("amostragem = %d,%d, esperado %d,%d", cfg.SampleInitial, cfg.SampleEvery, tt.wantInitial, tt.wantEvery)
+			}
+		})
+	}
+}
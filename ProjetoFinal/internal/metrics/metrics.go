@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "calculator"
+
+	// CodeOK é o rótulo de error_code usado para execuções bem-sucedidas
+	CodeOK = "OK"
+
+	// OperationOther é o rótulo de operation para operações desconhecidas
+	OperationOther = "other"
+)
+
+// Buckets de latência (em segundos), de 100µs a ~10s
+var latencyBuckets = prometheus.ExponentialBuckets(0.0001, 2.5, 13)
+
+var (
+	// ExpressionsTotal conta expressões processadas pelos dispatchers
+	ExpressionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "expressions_total",
+		Help:      "Total de expressões processadas, por transporte e código de erro.",
+	}, []string{"transport", "error_code"})
+
+	// ExpressionDuration mede a latência fim a fim de uma expressão no dispatcher
+	ExpressionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "expression_duration_seconds",
+		Help:      "Latência de processamento de uma expressão no dispatcher.",
+		Buckets:   latencyBuckets,
+	}, []string{"transport"})
+
+	// StepsTotal conta steps despachados pelos dispatchers
+	StepsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "steps_total",
+		Help:      "Total de steps executados, por transporte, operação e código de erro.",
+	}, []string{"transport", "operation", "error_code"})
+
+	// StepDuration mede a latência de um step visto pelo dispatcher
+	StepDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "step_duration_seconds",
+		Help:      "Latência de um step (envio até resultado) vista pelo dispatcher.",
+		Buckets:   latencyBuckets,
+	}, []string{"transport", "operation"})
+
+	// ClientExpressionsTotal conta expressões por principal autenticado ("anonymous" sem autenticação)
+	ClientExpressionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "client_expressions_total",
+		Help:      "Total de expressões recebidas, por transporte e principal autenticado.",
+	}, []string{"transport", "client"})
+
+	// AuthFailuresTotal conta requisições rejeitadas na autenticação
+	AuthFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Requisições rejeitadas na autenticação, por transporte e motivo.",
+	}, []string{"transport", "reason"})
+
+	// QuotaRejectionsTotal conta expressões rejeitadas pelas cotas por cliente
+	QuotaRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quota_rejections_total",
+		Help:      "Expressões rejeitadas por cota, por transporte, principal e código (RATE_LIMITED/RESOURCE_EXHAUSTED).",
+	}, []string{"transport", "client", "code"})
+
+	// PendingExpressions indica as expressões em andamento no dispatcher (pendingSteps)
+	PendingExpressions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_expressions",
+		Help:      "Expressões em andamento no dispatcher.",
+	}, []string{"transport"})
+
+	// OperationsTotal conta operações executadas pelos servidores de operação
+	OperationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_total",
+		Help:      "Total de operações executadas pelo servidor, por operação e código de erro.",
+	}, []string{"transport", "operation", "error_code"})
+
+	// OperationDuration mede o tempo de execução de uma operação no servidor
+	OperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "operation_duration_seconds",
+		Help:      "Tempo de processamento de uma operação no servidor.",
+		Buckets:   latencyBuckets,
+	}, []string{"transport", "operation"})
+
+	// DuplicateExpressionsTotal conta expressões repetidas (mesmo ExpressionID) atendidas pela deduplicação
+	DuplicateExpressionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "duplicate_expressions_total",
+		Help:      "Expressões repetidas no dispatcher, por transporte e desfecho (attached: aguardou a execução em andamento; replayed: recebeu a resposta guardada).",
+	}, []string{"transport", "outcome"})
+
+	// DuplicateStepsTotal conta steps repetidos (mesmo StepID) que não foram executados de novo
+	DuplicateStepsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "duplicate_steps_total",
+		Help:      "Steps repetidos atendidos sem nova execução pelo servidor, por transporte e operação.",
+	}, []string{"transport", "operation"})
+
+	// CancelledStepsTotal conta steps descartados pelos servidores por pertencerem a expressões canceladas
+	CancelledStepsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cancelled_steps_total",
+		Help:      "Steps descartados sem execução por pertencerem a expressões canceladas, por transporte e operação.",
+	}, []string{"transport", "operation"})
+
+	// RabbitMQConsumed conta mensagens consumidas por fila
+	RabbitMQConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rabbitmq_consumed_total",
+		Help:      "Mensagens consumidas do RabbitMQ, por fila.",
+	}, []string{"queue"})
+
+	// RabbitMQPublished conta mensagens publicadas por fila (ou routing key) e resultado
+	RabbitMQPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rabbitmq_published_total",
+		Help:      "Mensagens publicadas no RabbitMQ, por fila ou routing key e status (ok/error).",
+	}, []string{"queue", "status"})
+)
+
+// ErrorCode normaliza o código de erro para uso como rótulo
+func ErrorCode(code string) string {
+	if code == "" {
+		return CodeOK
+	}
+	return code
+}
+
+// Operation limita o rótulo de operation às quatro operações da calculadora: o nome
+// vem da requisição e valores arbitrários criariam uma série nova a cada chamada
+func Operation(name string) string {
+	switch name {
+	case "add", "subtract", "multiply", "divide":
+		return name
+	}
+	return OperationOther
+}
+
+// Serve expõe /metrics no endereço informado (não faz nada se addr for vazio)
+func Serve(addr string) {
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		slog.Info("Métricas expostas", slog.String("addr", addr+"/metrics"))
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("Erro no servidor de métricas", slog.Any("error", err))
+		}
+	}()
+}
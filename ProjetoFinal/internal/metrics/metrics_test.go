@@ -0,0 +1,29 @@
+package metrics
+
+import "testing"
+
+func TestErrorCode(t *testing.T) {
+	if got := ErrorCode(""); got != CodeOK {
+		t.Errorf("ErrorCode(\"\") = %q, esperado %q", got, CodeOK)
+	}
+	if got := ErrorCode("DIV_BY_ZERO"); got != "DIV_BY_ZERO" {
+		t.Errorf("ErrorCode(DIV_BY_ZERO) = %q", got)
+	}
+}
+
+func TestOperation(t *testing.T) {
+	tests := map[string]string{
+		"add":      "add",
+		"subtract": "subtract",
+		"multiply": "multiply",
+		"divide":   "divide",
+		"pow":      OperationOther,
+		"ADD":      OperationOther,
+		"":         OperationOther,
+	}
+	for name, want := range tests {
+		if got := Operation(name); got != want {
+			t.Errorf("Operation(%q) = %q, esperado %q", name, got, want)
+		}
+	}
+}
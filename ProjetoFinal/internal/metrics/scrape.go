@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Sample representa o valor de uma série coletada de um endpoint /metrics
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Snapshot agrega as séries de um ou mais endpoints, indexadas por nome+rótulos
+type Snapshot map[string]Sample
+
+// Scrape coleta as métricas do projeto em todos os endpoints informados.
+// Séries idênticas vindas de processos diferentes são somadas.
+func Scrape(urls []string) (Snapshot, error) {
+	snapshot := make(Snapshot)
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	for _, url := range urls {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao coletar %s: %v", url, err)
+		}
+		families, err := parse(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("falha ao interpretar %s: %v", url, err)
+		}
+
+		for name, family := range families {
+			if !strings.HasPrefix(name, namespace+"_") {
+				continue
+			}
+			for _, m := range family.GetMetric() {
+				labels := make(map[string]string)
+				for _, lp := range m.GetLabel() {
+					labels[lp.GetName()] = lp.GetValue()
+				}
+				switch family.GetType() {
+				case dto.MetricType_COUNTER:
+					snapshot.add(name, labels, m.GetCounter().GetValue())
+				case dto.MetricType_GAUGE:
+					snapshot.add(name, labels, m.GetGauge().GetValue())
+				case dto.MetricType_HISTOGRAM:
+					snapshot.add(name+"_sum", labels, m.GetHistogram().GetSampleSum())
+					snapshot.add(name+"_count", labels, float64(m.GetHistogram().GetSampleCount()))
+				}
+			}
+		}
+	}
+
+	return snapshot, nil
+}
+
+func parse(r io.Reader) (map[string]*dto.MetricFamily, error) {
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(r)
+}
+
+func (s Snapshot) add(name string, labels map[string]string, value float64) {
+	key := seriesKey(name, labels)
+	sample, ok := s[key]
+	if !ok {
+		sample = Sample{Name: name, Labels: labels}
+	}
+	sample.Value += value
+	s[key] = sample
+}
+
+func seriesKey(name string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		fmt.Fprintf(&b, ",%s=%s", k, labels[k])
+	}
+	return b.String()
+}
+
+// Diff retorna a variação entre dois snapshots (gauges mantêm o valor final)
+func Diff(before, after Snapshot) Snapshot {
+	delta := make(Snapshot)
+	for key, sample := range after {
+		if !strings.HasSuffix(sample.Name, "pending_expressions") {
+			sample.Value -= before[key].Value
+		}
+		delta[key] = sample
+	}
+	return delta
+}
+
+// sumBy soma as séries de uma métrica agrupando pelo rótulo informado
+func (s Snapshot) sumBy(name string, label ...string) map[string]float64 {
+	out := make(map[string]float64)
+	for _, sample := range s {
+		if sample.Name != namespace+"_"+name {
+			continue
+		}
+		parts := make([]string, 0, len(label))
+		for _, l := range label {
+			parts = append(parts, sample.Labels[l])
+		}
+		out[strings.Join(parts, "/")] += sample.Value
+	}
+	return out
+}
+
+// meanLatency calcula a latência média de um histograma agrupada pelo rótulo informado
+func (s Snapshot) meanLatency(name string, label ...string) map[string]time.Duration {
+	sums := s.sumBy(name+"_sum", label...)
+	counts := s.sumBy(name+"_count", label...)
+	out := make(map[string]time.Duration)
+	for k, count := range counts {
+		if count > 0 {
+			out[k] = time.Duration(sums[k] / count * float64(time.Second))
+		}
+	}
+	return out
+}
+
+// PrintReport exibe um resumo das métricas coletadas do lado dos servidores
+func PrintReport(w io.Writer, s Snapshot) {
+	fmt.Fprintf(w, "\n🖥️  Métricas dos servidores:\n")
+
+	printCounts(w, "Expressões (transporte/código)", s.sumBy("expressions_total", "transport", "error_code"))
+	printLatencies(w, "Latência média por expressão", s.meanLatency("expression_duration_seconds", "transport"))
+	printCounts(w, "Steps (operação/código)", s.sumBy("steps_total", "operation", "error_code"))
+	printLatencies(w, "Latência média por step", s.meanLatency("step_duration_seconds", "operation"))
+	printLatencies(w, "Tempo médio de execução nos servidores", s.meanLatency("operation_duration_seconds", "operation"))
+	printCounts(w, "Mensagens consumidas (fila)", s.sumBy("rabbitmq_consumed_total", "queue"))
+	printCounts(w, "Mensagens publicadas (fila/status)", s.sumBy("rabbitmq_published_total", "queue", "status"))
+}
+
+func printCounts(w io.Writer, title string, values map[string]float64) {
+	keys := sortedKeys(values)
+	if len(keys) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "   %s:\n", title)
+	for _, k := range keys {
+		if values[k] != 0 {
+			fmt.Fprintf(w, "      %-32s %.0f\n", k, values[k])
+		}
+	}
+}
+
+func printLatencies(w io.Writer, title string, values map[string]time.Duration) {
+	keys := sortedKeys(values)
+	if len(keys) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "   %s:\n", title)
+	for _, k := range keys {
+		fmt.Fprintf(w, "      %-32s %v\n", k, values[k])
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exposition gera um /metrics com um contador, um histograma e uma série de outro namespace
+func exposition(steps, durationSum float64, durationCount int) string {
+	return fmt.Sprintf(`# TYPE calculator_steps_total counter
+calculator_steps_total{transport="grpc",operation="add",error_code="OK"} %v
+# TYPE calculator_step_duration_seconds histogram
+calculator_step_duration_seconds_bucket{transport="grpc",operation="add",le="+Inf"} %d
+calculator_step_duration_seconds_sum{transport="grpc",operation="add"} %v
+calculator_step_duration_seconds_count{transport="grpc",operation="add"} %d
+# TYPE calculator_pending_expressions gauge
+calculator_pending_expressions{transport="grpc"} 2
+# TYPE go_goroutines gauge
+go_goroutines 12
+`, steps, durationCount, durationSum, durationCount)
+}
+
+func serve(t *testing.T, body *string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, *body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestScrapeSumsProcesses(t *testing.T) {
+	a, b := exposition(3, 0.3, 3), exposition(5, 0.1, 1)
+	snapshot, err := Scrape([]string{serve(t, &a), serve(t, &b)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := snapshot.sumBy("steps_total", "operation", "error_code"); got["add/OK"] != 8 {
+		t.Errorf("steps_total = %v, esperado add/OK=8", got)
+	}
+	if got := snapshot.meanLatency("step_duration_seconds", "operation"); got["add"] != 100*time.Millisecond {
+		t.Errorf("latência média = %v, esperado 100ms", got)
+	}
+	for _, s := range snapshot {
+		if !strings.HasPrefix(s.Name, namespace+"_") {
+			t.Errorf("série de fora do projeto coletada: %s", s.Name)
+		}
+	}
+}
+
+func TestScrapeErrors(t *testing.T) {
+	invalid := "calculator_steps_total{"
+	if _, err := Scrape([]string{serve(t, &invalid)}); err == nil {
+		t.Error("exposição inválida aceita")
+	}
+	if _, err := Scrape([]string{"http://127.0.0.1:0/metrics"}); err == nil {
+		t.Error("endpoint inacessível aceito")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	body := exposition(3, 0.3, 3)
+	url := serve(t, &body)
+	before, err := Scrape([]string{url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body = strings.Replace(exposition(10, 1.0, 10), "} 2\n", "} 0\n", 1)
+	after, err := Scrape([]string{url})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delta := Diff(before, after)
+	if got := delta.sumBy("steps_total", "operation"); got["add"] != 7 {
+		t.Errorf("variação de steps_total = %v, esperado 7", got)
+	}
+	// Gauges mantêm o valor final em vez da variação
+	if got := delta.sumBy("pending_expressions", "transport"); got["grpc"] != 0 {
+		t.Errorf("pending_expressions = %v, esperado o valor final 0", got)
+	}
+
+	var out bytes.Buffer
+	PrintReport(&out, delta)
+	if !strings.Contains(out.String(), "add/OK") {
+		t.Errorf("relatório sem os steps:\n%s", out.String())
+	}
+}
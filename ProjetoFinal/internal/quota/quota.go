@@ -0,0 +1,260 @@
+package quota
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Variáveis de ambiente de configuração das cotas
+const (
+	DefaultEnv = "CALC_QUOTA_DEFAULT" // ex: "rate=50,burst=100,inflight=20"
+	ClientsEnv = "CALC_QUOTA_CLIENTS" // ex: "alice=rate=10,burst=20;bench=inflight=200"
+)
+
+// Códigos de erro retornados ao cliente
+const (
+	CodeRateLimited       = "RATE_LIMITED"
+	CodeResourceExhausted = "RESOURCE_EXHAUSTED"
+)
+
+// Clientes sem atividade por este tempo têm o estado descartado
+const idleTTL = 10 * time.Minute
+
+// Limits define as cotas de um cliente (zero significa ilimitado)
+type Limits struct {
+	Rate        float64 // expressões por segundo (reposição do token bucket)
+	Burst       int     // capacidade do token bucket (padrão: max(1, Rate))
+	MaxInFlight int     // expressões simultâneas em andamento
+}
+
+// Unlimited indica que não há nenhuma cota configurada
+func (l Limits) Unlimited() bool {
+	return l.Rate <= 0 && l.MaxInFlight <= 0
+}
+
+func (l Limits) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, l.Rate)
+}
+
+// Config reúne as cotas padrão e as específicas por cliente
+type Config struct {
+	Default   Limits
+	PerClient map[string]Limits
+}
+
+// Limits retorna as cotas aplicáveis ao cliente
+func (c Config) Limits(client string) Limits {
+	if l, ok := c.PerClient[client]; ok {
+		return l
+	}
+	return c.Default
+}
+
+// Enabled indica se alguma cota está configurada
+func (c Config) Enabled() bool {
+	if !c.Default.Unlimited() {
+		return true
+	}
+	for _, l := range c.PerClient {
+		if !l.Unlimited() {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigFromEnv lê as cotas de CALC_QUOTA_DEFAULT e CALC_QUOTA_CLIENTS
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{PerClient: make(map[string]Limits)}
+
+	if spec := os.Getenv(DefaultEnv); spec != "" {
+		l, err := ParseLimits(spec)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: %v", DefaultEnv, err)
+		}
+		cfg.Default = l
+	}
+
+	for _, entry := range strings.Split(os.Getenv(ClientsEnv), ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		client, spec, ok := strings.Cut(entry, "=")
+		if !ok || client == "" {
+			return Config{}, fmt.Errorf("%s: entrada inválida %q (esperado cliente=rate=N,...)", ClientsEnv, entry)
+		}
+		l, err := ParseLimits(spec)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: cliente %s: %v", ClientsEnv, client, err)
+		}
+		cfg.PerClient[strings.TrimSpace(client)] = l
+	}
+
+	return cfg, nil
+}
+
+// ParseLimits interpreta "rate=N,burst=N,inflight=N" (campos opcionais)
+func ParseLimits(spec string) (Limits, error) {
+	var l Limits
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return Limits{}, fmt.Errorf("campo inválido %q", field)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "rate":
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return Limits{}, fmt.Errorf("rate inválido %q", value)
+			}
+			l.Rate = v
+		case "burst":
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return Limits{}, fmt.Errorf("burst inválido %q", value)
+			}
+			l.Burst = v
+		case "inflight":
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return Limits{}, fmt.Errorf("inflight inválido %q", value)
+			}
+			l.MaxInFlight = v
+		default:
+			return Limits{}, fmt.Errorf("campo desconhecido %q", key)
+		}
+	}
+	return l, nil
+}
+
+// LimitError descreve uma requisição rejeitada por cota
+type LimitError struct {
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return e.Message
+}
+
+// RetryAfterMs retorna a sugestão de espera em milissegundos (mínimo 1ms)
+func (e *LimitError) RetryAfterMs() int64 {
+	return max(1, e.RetryAfter.Milliseconds())
+}
+
+// Limiter aplica token bucket e limite de expressões simultâneas por cliente
+type Limiter struct {
+	cfg       Config
+	mu        sync.Mutex
+	clients   map[string]*clientState
+	lastSweep time.Time
+}
+
+type clientState struct {
+	tokens   float64
+	updated  time.Time
+	inFlight int
+}
+
+// NewLimiter cria um limitador com a configuração informada
+func NewLimiter(cfg Config) *Limiter {
+	return &Limiter{cfg: cfg, clients: make(map[string]*clientState)}
+}
+
+// Enabled indica se o limitador aplica alguma cota
+func (l *Limiter) Enabled() bool {
+	return l != nil && l.cfg.Enabled()
+}
+
+// Acquire reserva uma vaga para uma nova expressão do cliente. Em caso de sucesso,
+// release deve ser chamada quando a expressão terminar.
+func (l *Limiter) Acquire(client string, now time.Time) (release func(), err *LimitError) {
+	if !l.Enabled() {
+		return func() {}, nil
+	}
+
+	limits := l.cfg.Limits(client)
+	if limits.Unlimited() {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	state, ok := l.clients[client]
+	if !ok {
+		state = &clientState{tokens: limits.burst(), updated: now}
+		l.clients[client] = state
+	}
+
+	if limits.MaxInFlight > 0 && state.inFlight >= limits.MaxInFlight {
+		return nil, &LimitError{
+			Code:       CodeResourceExhausted,
+			Message:    fmt.Sprintf("Limite de %d expressões simultâneas atingido para %s", limits.MaxInFlight, client),
+			RetryAfter: l.inFlightRetryAfter(limits),
+		}
+	}
+
+	if limits.Rate > 0 {
+		elapsed := now.Sub(state.updated).Seconds()
+		state.tokens = math.Min(limits.burst(), state.tokens+elapsed*limits.Rate)
+		state.updated = now
+		if state.tokens < 1 {
+			wait := time.Duration((1 - state.tokens) / limits.Rate * float64(time.Second))
+			return nil, &LimitError{
+				Code:       CodeRateLimited,
+				Message:    fmt.Sprintf("Limite de %.4g expressões/s excedido para %s", limits.Rate, client),
+				RetryAfter: wait,
+			}
+		}
+		state.tokens--
+	}
+
+	state.inFlight++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			state.inFlight--
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+// inFlightRetryAfter estima a espera por uma vaga: o intervalo do token bucket, ou 100ms sem taxa
+func (l *Limiter) inFlightRetryAfter(limits Limits) time.Duration {
+	if limits.Rate > 0 {
+		return time.Duration(float64(time.Second) / limits.Rate)
+	}
+	return 100 * time.Millisecond
+}
+
+// sweep descarta periodicamente o estado de clientes ociosos (chamado com l.mu travado)
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for client, state := range l.clients {
+		if state.inFlight == 0 && now.Sub(state.updated) > idleTTL {
+			delete(l.clients, client)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package quota
+
+import (
+	"testing"
+	"time"
+)
+
+var t0 = time.Unix(1_700_000_000, 0)
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Limits
+		wantErr bool
+	}{
+		{"", Limits{}, false},
+		{"rate=50,burst=100,inflight=20", Limits{Rate: 50, Burst: 100, MaxInFlight: 20}, false},
+		{" RATE = 0.5 , inflight=3 ", Limits{Rate: 0.5, MaxInFlight: 3}, false},
+		{"rate=2,", Limits{Rate: 2}, false},
+		{"rate", Limits{}, true},
+		{"rate=abc", Limits{}, true},
+		{"burst=1.5", Limits{}, true},
+		{"inflight=x", Limits{}, true},
+		{"cpu=1", Limits{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseLimits(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("erro = %v, esperado erro: %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimits(%q) = %+v, esperado %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		def, client string
+		wantDefault Limits
+		wantClients map[string]Limits
+		wantErr     bool
+	}{
+		{name: "vazio", wantClients: map[string]Limits{}},
+		{
+			name:        "padrão e clientes",
+			def:         "rate=50,burst=100",
+			client:      "alice=rate=10,burst=20; bench=inflight=200;",
+			wantDefault: Limits{Rate: 50, Burst: 100},
+			wantClients: map[string]Limits{"alice": {Rate: 10, Burst: 20}, "bench": {MaxInFlight: 200}},
+		},
+		{name: "padrão inválido", def: "rate=x", wantErr: true},
+		{name: "cliente sem nome", client: "=rate=1", wantErr: true},
+		{name: "cliente sem cotas", client: "alice", wantErr: true},
+		{name: "cota de cliente inválida", client: "alice=burst=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(DefaultEnv, tt.def)
+			t.Setenv(ClientsEnv, tt.client)
+			cfg, err := ConfigFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("erro = %v, esperado erro: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cfg.Default != tt.wantDefault {
+				t.Errorf("Default = %+v, esperado %+v", cfg.Default, tt.wantDefault)
+			}
+			if len(cfg.PerClient) != len(tt.wantClients) {
+				t.Fatalf("PerClient = %+v, esperado %+v", cfg.PerClient, tt.wantClients)
+			}
+			for client, want := range tt.wantClients {
+				if got := cfg.PerClient[client]; got != want {
+					t.Errorf("PerClient[%s] = %+v, esperado %+v", client, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigLimitsAndEnabled(t *testing.T) {
+	cfg := Config{Default: Limits{Rate: 1}, PerClient: map[string]Limits{"bench": {}}}
+	if got := cfg.Limits("alice"); got.Rate != 1 {
+		t.Errorf("Limits(alice) = %+v, esperado o padrão", got)
+	}
+	if got := cfg.Limits("bench"); !got.Unlimited() {
+		t.Errorf("Limits(bench) = %+v, esperado ilimitado", got)
+	}
+	if !cfg.Enabled() || (Config{PerClient: map[string]Limits{"bench": {}}}).Enabled() {
+		t.Error("Enabled incorreto")
+	}
+	if (*Limiter)(nil).Enabled() || NewLimiter(Config{}).Enabled() {
+		t.Error("limitador sem cotas deveria estar desativado")
+	}
+}
+
+// step é uma chamada de Acquire no instante t0+at
+type step struct {
+	at       time.Duration
+	wantCode string        // vazio: aceita
+	retry    time.Duration // RetryAfter esperado quando rejeitada
+}
+
+func TestAcquireTokenBucket(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits Limits
+		steps  []step
+	}{
+		{
+			name:   "burst e reposição",
+			limits: Limits{Rate: 2, Burst: 3},
+			steps: []step{
+				{at: 0}, {at: 0}, {at: 0},
+				{at: 0, wantCode: CodeRateLimited, retry: 500 * time.Millisecond},
+				{at: 250 * time.Millisecond, wantCode: CodeRateLimited, retry: 250 * time.Millisecond},
+				{at: 500 * time.Millisecond},
+				{at: 500 * time.Millisecond, wantCode: CodeRateLimited, retry: 500 * time.Millisecond},
+				// Ocioso por muito tempo: o bucket enche só até o burst
+				{at: time.Hour}, {at: time.Hour}, {at: time.Hour},
+				{at: time.Hour, wantCode: CodeRateLimited, retry: 500 * time.Millisecond},
+			},
+		},
+		{
+			name:   "burst padrão é a taxa",
+			limits: Limits{Rate: 2},
+			steps: []step{
+				{at: 0}, {at: 0},
+				{at: 0, wantCode: CodeRateLimited, retry: 500 * time.Millisecond},
+			},
+		},
+		{
+			name:   "taxa menor que 1 tem burst 1",
+			limits: Limits{Rate: 0.5},
+			steps: []step{
+				{at: 0},
+				{at: time.Second, wantCode: CodeRateLimited, retry: time.Second},
+				{at: 2 * time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(Config{Default: tt.limits})
+			for i, s := range tt.steps {
+				release, err := l.Acquire("alice", t0.Add(s.at))
+				if s.wantCode == "" {
+					if err != nil {
+						t.Fatalf("passo %d: rejeitada com %v", i, err)
+					}
+					release()
+					continue
+				}
+				if err == nil || err.Code != s.wantCode {
+					t.Fatalf("passo %d: erro = %v, esperado %s", i, err, s.wantCode)
+				}
+				if err.RetryAfter != s.retry {
+					t.Errorf("passo %d: RetryAfter = %v, esperado %v", i, err.RetryAfter, s.retry)
+				}
+			}
+		})
+	}
+}
+
+func TestAcquireInFlight(t *testing.T) {
+	l := NewLimiter(Config{Default: Limits{MaxInFlight: 2}})
+
+	r1, err := l.Acquire("alice", t0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Acquire("alice", t0); err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.Acquire("alice", t0)
+	if err == nil || err.Code != CodeResourceExhausted || err.RetryAfter != 100*time.Millisecond {
+		t.Fatalf("terceira expressão: erro = %+v, esperado %s após 100ms", err, CodeResourceExhausted)
+	}
+	// Outro cliente tem a própria cota
+	if _, err := l.Acquire("bob", t0); err != nil {
+		t.Fatalf("bob: %v", err)
+	}
+
+	// release é idempotente: chamá-la de novo não libera uma segunda vaga
+	r1()
+	r1()
+	if _, err := l.Acquire("alice", t0); err != nil {
+		t.Fatalf("após release: %v", err)
+	}
+	if _, err := l.Acquire("alice", t0); err == nil {
+		t.Fatal("release repetida liberou mais de uma vaga")
+	}
+}
+
+func TestAcquireInFlightRetryAfterUsesRate(t *testing.T) {
+	l := NewLimiter(Config{Default: Limits{Rate: 4, Burst: 10, MaxInFlight: 1}})
+	if _, err := l.Acquire("alice", t0); err != nil {
+		t.Fatal(err)
+	}
+	_, err := l.Acquire("alice", t0)
+	if err == nil || err.Code != CodeResourceExhausted || err.RetryAfter != 250*time.Millisecond {
+		t.Fatalf("erro = %+v, esperado %s após 250ms", err, CodeResourceExhausted)
+	}
+	if err.RetryAfterMs() != 250 {
+		t.Errorf("RetryAfterMs = %d", err.RetryAfterMs())
+	}
+}
+
+func TestAcquireUnlimitedClient(t *testing.T) {
+	l := NewLimiter(Config{Default: Limits{MaxInFlight: 1}, PerClient: map[string]Limits{"bench": {}}})
+	for range 10 {
+		if _, err := l.Acquire("bench", t0); err != nil {
+			t.Fatalf("cliente ilimitado rejeitado: %v", err)
+		}
+	}
+	if len(l.clients) != 0 {
+		t.Error("cliente ilimitado não deve manter estado")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l := NewLimiter(Config{Default: Limits{Rate: 1, MaxInFlight: 5}})
+	releaseIdle, _ := l.Acquire("ocioso", t0)
+	releaseIdle()
+	l.Acquire("ocupado", t0) // nunca liberada
+
+	// Antes do intervalo de varredura, nada é descartado
+	l.Acquire("outro", t0.Add(30*time.Second))
+	if len(l.clients) != 3 {
+		t.Fatalf("clientes = %d, esperado 3", len(l.clients))
+	}
+
+	l.Acquire("outro", t0.Add(idleTTL+time.Minute))
+	if _, ok := l.clients["ocioso"]; ok {
+		t.Error("cliente ocioso não foi descartado")
+	}
+	if _, ok := l.clients["ocupado"]; !ok {
+		t.Error("cliente com expressões em andamento foi descartado")
+	}
+	if _, ok := l.clients["outro"]; !ok {
+		t.Error("cliente ativo foi descartado")
+	}
+}
+
+func TestRetryAfterMsMinimum(t *testing.T) {
+	if got := (&LimitError{RetryAfter: time.Microsecond}).RetryAfterMs(); got != 1 {
+		t.Errorf("RetryAfterMs = %d, esperado 1", got)
+	}
+}
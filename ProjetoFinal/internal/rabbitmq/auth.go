@@ -0,0 +1,59 @@
+package rabbitmq
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// SignHeaders adiciona as credenciais do cliente aos headers da mensagem:
+// com API key, assina o corpo e um nonce (HMAC) sem enviar o segredo; com JWT, envia o token
+func SignHeaders(creds auth.Credentials, headers amqp.Table, body []byte) {
+	if creds.Empty() {
+		return
+	}
+	if creds.Token != "" {
+		headers[auth.HeaderAuthorization] = "Bearer " + creds.Token
+		return
+	}
+
+	timestamp, nonce := auth.Timestamp(time.Now()), auth.NewNonce()
+	headers[auth.HeaderPrincipal] = creds.Principal
+	headers[auth.HeaderTimestamp] = timestamp
+	headers[auth.HeaderNonce] = nonce
+	headers[auth.HeaderSignature] = auth.Sign(creds.Secret, timestamp, nonce, body)
+}
+
+// Authenticate valida os headers de autenticação de uma requisição
+func Authenticate(a *auth.Authenticator, headers amqp.Table, body []byte) (auth.Principal, error) {
+	if bearer := headerString(headers, auth.HeaderAuthorization); bearer != "" {
+		token, _ := strings.CutPrefix(bearer, "Bearer ")
+		return a.AuthenticateToken(strings.TrimSpace(token))
+	}
+
+	return a.VerifySignature(
+		headerString(headers, auth.HeaderPrincipal),
+		headerString(headers, auth.HeaderTimestamp),
+		headerString(headers, auth.HeaderNonce),
+		headerString(headers, auth.HeaderSignature),
+		body,
+		time.Now(),
+	)
+}
+
+// PrincipalFromHeaders retorna o principal repassado pelo dispatcher, ou fallback se ausente
+func PrincipalFromHeaders(headers amqp.Table, fallback string) string {
+	if principal := headerString(headers, auth.HeaderPrincipal); principal != "" {
+		return principal
+	}
+	return fallback
+}
+
+func headerString(headers amqp.Table, key string) string {
+	if v, ok := headers[key].(string); ok {
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package rabbitmq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestAuthenticateSignedHeaders(t *testing.T) {
+	a := auth.NewAuthenticator(map[string]string{"alice": "s3cr3t"}, nil)
+	body := []byte(`{"expression":"2+2"}`)
+
+	headers := amqp.Table{}
+	SignHeaders(auth.Credentials{Principal: "alice", Secret: "s3cr3t"}, headers, body)
+	if _, ok := headers[auth.HeaderAuthorization]; ok {
+		t.Fatal("o segredo da API key não deve trafegar")
+	}
+
+	p, err := Authenticate(a, headers, body)
+	if err != nil || p.ID != "alice" || p.Method != auth.MethodAPIKey {
+		t.Fatalf("Authenticate = %+v, %v", p, err)
+	}
+	if _, err := Authenticate(a, headers, body); !errors.Is(err, auth.ErrReplayed) {
+		t.Fatalf("reenvio: erro = %v, esperado %v", err, auth.ErrReplayed)
+	}
+
+	headers = amqp.Table{}
+	SignHeaders(auth.Credentials{Principal: "alice", Secret: "s3cr3t"}, headers, body)
+	if _, err := Authenticate(a, headers, []byte(`{"expression":"9+9"}`)); !errors.Is(err, auth.ErrInvalidSignature) {
+		t.Fatalf("corpo alterado: erro = %v, esperado %v", err, auth.ErrInvalidSignature)
+	}
+}
+
+func TestAuthenticateBearer(t *testing.T) {
+	secret := []byte("segredo-jwt")
+	a := auth.NewAuthenticator(nil, secret)
+	token, err := auth.SignJWT(secret, "bob", time.Hour, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := amqp.Table{}
+	SignHeaders(auth.Credentials{Token: token}, headers, nil)
+	p, err := Authenticate(a, headers, nil)
+	if err != nil || p.ID != "bob" || p.Method != auth.MethodJWT {
+		t.Fatalf("Authenticate = %+v, %v", p, err)
+	}
+
+	if _, err := Authenticate(a, amqp.Table{}, nil); !errors.Is(err, auth.ErrMissingCredentials) {
+		t.Fatalf("sem headers: erro = %v, esperado %v", err, auth.ErrMissingCredentials)
+	}
+}
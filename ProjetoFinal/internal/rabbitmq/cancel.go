@@ -0,0 +1,67 @@
+package rabbitmq
+
+import (
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// HeaderPublishedAt é o instante (unix ns, relógio do dispatcher) em que um step ou
+// um pedido de cancelamento foi publicado; ordena os steps em relação ao cancelamento
+const HeaderPublishedAt = "x-calc-published-at"
+
+// cancelRetention é por quanto tempo um servidor de operação lembra um cancelamento
+const cancelRetention = 10 * time.Minute
+
+// CancelledExpressions guarda os cancelamentos recebidos por um servidor de operação
+// (calculator.cancel.steps). Um step é descartado se foi publicado antes do
+// cancelamento da sua expressão; uma nova execução do mesmo ExpressionID, publicada
+// depois, segue normalmente.
+type CancelledExpressions struct {
+	mu        sync.Mutex
+	cancelled map[string]cancelEntry
+}
+
+type cancelEntry struct {
+	at       int64     // instante do cancelamento (HeaderPublishedAt)
+	received time.Time // para expirar a entrada
+}
+
+// NewCancelledExpressions cria o registro de cancelamentos vazio
+func NewCancelledExpressions() *CancelledExpressions {
+	return &CancelledExpressions{cancelled: make(map[string]cancelEntry)}
+}
+
+// Add registra o cancelamento recebido em headers
+func (c *CancelledExpressions) Add(expressionID string, headers amqp.Table) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, e := range c.cancelled {
+		if now.Sub(e.received) > cancelRetention {
+			delete(c.cancelled, id)
+		}
+	}
+	c.cancelled[expressionID] = cancelEntry{at: publishedAt(headers, now), received: now}
+}
+
+// Cancelled indica se o step (headers da entrega) pertence a uma expressão cancelada
+// depois de ele ser publicado
+func (c *CancelledExpressions) Cancelled(expressionID string, headers amqp.Table) bool {
+	c.mu.Lock()
+	e, ok := c.cancelled[expressionID]
+	c.mu.Unlock()
+	return ok && publishedAt(headers, time.Time{}) <= e.at
+}
+
+// publishedAt lê HeaderPublishedAt; mensagens sem o header usam fallback
+func publishedAt(headers amqp.Table, fallback time.Time) int64 {
+	if v, ok := headers[HeaderPublishedAt].(int64); ok {
+		return v
+	}
+	if fallback.IsZero() {
+		return 0
+	}
+	return fallback.UnixNano()
+}
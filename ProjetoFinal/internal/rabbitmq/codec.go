@@ -0,0 +1,260 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"strings"
+
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// Content types aceitos no transporte RabbitMQ
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// CodecEnv escolhe a codificação das mensagens publicadas: "json" (padrão) ou "protobuf"
+const CodecEnv = "CALC_RABBITMQ_CODEC"
+
+// CodecFromEnv retorna o content type configurado em CALC_RABBITMQ_CODEC. O padrão é
+// JSON, que servidores e clientes anteriores ao protobuf ainda entendem.
+func CodecFromEnv() (string, error) {
+	switch strings.ToLower(os.Getenv(CodecEnv)) {
+	case "", "json":
+		return ContentTypeJSON, nil
+	case "protobuf", "proto":
+		return ContentTypeProtobuf, nil
+	default:
+		return "", fmt.Errorf("%s inválido: %q (esperado json ou protobuf)", CodecEnv, os.Getenv(CodecEnv))
+	}
+}
+
+// Encode serializa uma mensagem (*ExpressionRequest, *ExpressionResponse,
+// *OperationRequest, *OperationResponse, *ResultRequest, *ExpressionStatus ou
+// *CancelRequest)
+// no content type informado
+func Encode(contentType string, v any) ([]byte, error) {
+	if !isProtobuf(contentType) {
+		return json.Marshal(v)
+	}
+
+	var m proto.Message
+	switch v := v.(type) {
+	case *ExpressionRequest:
+		m = &pb.ExpressionRequest{
+			ExpressionId: v.ExpressionID,
+			Expression:   v.Expression,
+			DeadlineMs:   v.DeadlineMs,
+			IncludeTrace: v.IncludeTrace,
+			Priority:     int32(v.Priority),
+			ClientId:     v.ClientID,
+			ReplyTo:      v.ReplyTo,
+		}
+	case *ExpressionResponse:
+		m = expressionResponseToProto(v)
+	case *OperationRequest:
+		m = &pb.OperationRequest{
+			ExpressionId: v.ExpressionID,
+			StepId:       v.StepID,
+			Operation:    v.Operation,
+			Numbers:      v.Numbers,
+			DeadlineMs:   v.DeadlineMs,
+			ReplyTo:      v.ReplyTo,
+		}
+	case *OperationResponse:
+		m = &pb.OperationResponse{
+			ExpressionId: v.ExpressionID,
+			StepId:       v.StepID,
+			Result:       v.Result,
+			Error:        errorToProto(v.Error),
+			Server:       v.Server,
+		}
+	case *ResultRequest:
+		m = &pb.ResultRequest{Ticket: v.Ticket, TimeoutMs: v.TimeoutMs, ReplyTo: v.ReplyTo, ClientId: v.ClientID}
+	case *CancelRequest:
+		m = &pb.CancelRequest{ExpressionId: v.ExpressionID, ClientId: v.ClientID}
+	case *ExpressionStatus:
+		m = &pb.ExpressionStatus{
+			Ticket:   v.Ticket,
+			State:    pb.ExpressionState(pb.ExpressionState_value[v.State]),
+			Response: expressionResponseToProto(v.Response),
+			Error:    errorToProto(v.Error),
+		}
+	default:
+		return nil, fmt.Errorf("tipo sem codificação protobuf: %T", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Decode interpreta o corpo conforme o content type da entrega; mensagens sem
+// content type (clientes antigos) são tratadas como JSON
+func Decode(contentType string, body []byte, v any) error {
+	if !isProtobuf(contentType) {
+		return json.Unmarshal(body, v)
+	}
+
+	switch v := v.(type) {
+	case *ExpressionRequest:
+		var m pb.ExpressionRequest
+		if err := proto.Unmarshal(body, &m); err != nil {
+			return err
+		}
+		*v = ExpressionRequest{
+			ExpressionID: m.ExpressionId,
+			Expression:   m.Expression,
+			DeadlineMs:   m.DeadlineMs,
+			IncludeTrace: m.IncludeTrace,
+			Priority:     int(m.Priority),
+			ClientID:     m.ClientId,
+			ReplyTo:      m.ReplyTo,
+		}
+	case *ExpressionResponse:
+		var m pb.ExpressionResponse
+		if err := proto.Unmarshal(body, &m); err != nil {
+			return err
+		}
+		*v = *expressionResponseFromProto(&m)
+	case *OperationRequest:
+		var m pb.OperationRequest
+		if err := proto.Unmarshal(body, &m); err != nil {
+			return err
+		}
+		*v = OperationRequest{
+			ExpressionID: m.ExpressionId,
+			StepID:       m.StepId,
+			Operation:    m.Operation,
+			Numbers:      m.Numbers,
+			DeadlineMs:   m.DeadlineMs,
+			ReplyTo:      m.ReplyTo,
+		}
+	case *OperationResponse:
+		var m pb.OperationResponse
+		if err := proto.Unmarshal(body, &m); err != nil {
+			return err
+		}
+		*v = OperationResponse{
+			ExpressionID: m.ExpressionId,
+			StepID:       m.StepId,
+			Result:       m.Result,
+			Error:        errorFromProto(m.Error),
+			Server:       m.Server,
+		}
+	case *ResultRequest:
+		var m pb.ResultRequest
+		if err := proto.Unmarshal(body, &m); err != nil {
+			return err
+		}
+		*v = ResultRequest{Ticket: m.Ticket, TimeoutMs: m.TimeoutMs, ReplyTo: m.ReplyTo, ClientID: m.ClientId}
+	case *CancelRequest:
+		var m pb.CancelRequest
+		if err := proto.Unmarshal(body, &m); err != nil {
+			return err
+		}
+		*v = CancelRequest{ExpressionID: m.ExpressionId, ClientID: m.ClientId}
+	case *ExpressionStatus:
+		var m pb.ExpressionStatus
+		if err := proto.Unmarshal(body, &m); err != nil {
+			return err
+		}
+		*v = ExpressionStatus{
+			Ticket:   m.Ticket,
+			Response: expressionResponseFromProto(m.Response),
+			Error:    errorFromProto(m.Error),
+		}
+		if m.State != pb.ExpressionState_EXPRESSION_STATE_UNSPECIFIED {
+			v.State = m.State.String()
+		}
+	default:
+		return fmt.Errorf("tipo sem codificação protobuf: %T", v)
+	}
+	return nil
+}
+
+// isProtobuf compara apenas o media type: parâmetros (ex: "; charset=binary") e
+// maiúsculas são ignorados. Content types inválidos ou vazios são tratados como JSON.
+func isProtobuf(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == ContentTypeProtobuf
+}
+
+func expressionResponseToProto(r *ExpressionResponse) *pb.ExpressionResponse {
+	if r == nil {
+		return nil
+	}
+	return &pb.ExpressionResponse{
+		ExpressionId: r.ExpressionID,
+		Result:       r.Result,
+		Error:        errorToProto(r.Error),
+		Trace:        traceToProto(r.Trace),
+		ClientId:     r.ClientID,
+	}
+}
+
+func expressionResponseFromProto(r *pb.ExpressionResponse) *ExpressionResponse {
+	if r == nil {
+		return nil
+	}
+	return &ExpressionResponse{
+		ExpressionID: r.ExpressionId,
+		Result:       r.Result,
+		Error:        errorFromProto(r.Error),
+		Trace:        traceFromProto(r.Trace),
+		ClientID:     r.ClientId,
+	}
+}
+
+func errorToProto(e *ErrorInfo) *pb.ErrorInfo {
+	if e == nil {
+		return nil
+	}
+	return &pb.ErrorInfo{Code: e.Code, Message: e.Message, RetryAfterMs: e.RetryAfterMs}
+}
+
+func errorFromProto(e *pb.ErrorInfo) *ErrorInfo {
+	if e == nil {
+		return nil
+	}
+	return &ErrorInfo{Code: e.Code, Message: e.Message, RetryAfterMs: e.RetryAfterMs}
+}
+
+func traceToProto(trace []StepTrace) []*pb.StepTrace {
+	if len(trace) == 0 {
+		return nil
+	}
+	out := make([]*pb.StepTrace, len(trace))
+	for i, t := range trace {
+		out[i] = &pb.StepTrace{
+			StepId:    t.StepID,
+			Operation: t.Operation,
+			Operands:  t.Operands,
+			Result:    t.Result,
+			Server:    t.Server,
+			LatencyUs: t.LatencyUs,
+			Error:     errorToProto(t.Error),
+		}
+	}
+	return out
+}
+
+func traceFromProto(trace []*pb.StepTrace) []StepTrace {
+	if len(trace) == 0 {
+		return nil
+	}
+	out := make([]StepTrace, len(trace))
+	for i, t := range trace {
+		out[i] = StepTrace{
+			StepID:    t.StepId,
+			Operation: t.Operation,
+			Operands:  t.Operands,
+			Result:    t.Result,
+			Server:    t.Server,
+			LatencyUs: t.LatencyUs,
+			Error:     errorFromProto(t.Error),
+		}
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// messages tem um exemplo preenchido de cada tipo aceito por Encode e Decode
+func messages() []any {
+	errInfo := &ErrorInfo{Code: "RATE_LIMITED", Message: "limite excedido", RetryAfterMs: 250}
+	response := &ExpressionResponse{
+		ExpressionID: "expr-1",
+		Result:       7.5,
+		Trace: []StepTrace{
+			{StepID: "s1", Operation: "add", Operands: []float64{2, 3}, Result: 5, Server: "add-1", LatencyUs: 120},
+			{StepID: "s2", Operation: "div", Operands: []float64{1, 0}, LatencyUs: 80, Error: &ErrorInfo{Code: "DIV_BY_ZERO", Message: "divisão por zero"}},
+		},
+		ClientID: "alice",
+	}
+	return []any{
+		&ExpressionRequest{ExpressionID: "expr-1", Expression: "2 + 3 * 4", DeadlineMs: 5000, IncludeTrace: true, Priority: 7, ClientID: "alice", ReplyTo: "calculator.responses.alice"},
+		response,
+		&ExpressionResponse{ExpressionID: "expr-2", Error: errInfo},
+		&OperationRequest{ExpressionID: "expr-1", StepID: "s1", Operation: "mul", Numbers: []float64{3, 4}, DeadlineMs: 1000, ReplyTo: "calculator.results.d1"},
+		&OperationResponse{ExpressionID: "expr-1", StepID: "s1", Result: 12, Server: "mul-1"},
+		&OperationResponse{ExpressionID: "expr-1", StepID: "s2", Error: errInfo},
+		&ResultRequest{Ticket: "expr-1", TimeoutMs: 3000, ReplyTo: "calculator.status.replies.c1", ClientID: "alice"},
+		&CancelRequest{ExpressionID: "expr-1", ClientID: "alice"},
+		&ExpressionStatus{Ticket: "expr-1", State: "DONE", Response: response},
+		&ExpressionStatus{Ticket: "expr-3", Error: &ErrorInfo{Code: "NOT_FOUND", Message: "ticket desconhecido"}},
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, contentType := range []string{ContentTypeJSON, ContentTypeProtobuf} {
+		for _, msg := range messages() {
+			t.Run(contentType+"/"+reflect.TypeOf(msg).Elem().Name(), func(t *testing.T) {
+				body, err := Encode(contentType, msg)
+				if err != nil {
+					t.Fatalf("Encode: %v", err)
+				}
+				got := reflect.New(reflect.TypeOf(msg).Elem()).Interface()
+				if err := Decode(contentType, body, got); err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				if !reflect.DeepEqual(got, msg) {
+					t.Errorf("Decode(Encode(m)) = %+v, esperado %+v", got, msg)
+				}
+			})
+		}
+	}
+}
+
+func TestCodecEncodingsDiffer(t *testing.T) {
+	req := &ExpressionRequest{ExpressionID: "expr-1", Expression: "1+1"}
+	jsonBody, _ := Encode(ContentTypeJSON, req)
+	pbBody, _ := Encode(ContentTypeProtobuf, req)
+	if !json.Valid(jsonBody) {
+		t.Errorf("corpo JSON inválido: %q", jsonBody)
+	}
+	if json.Valid(pbBody) {
+		t.Errorf("corpo protobuf codificado como JSON: %q", pbBody)
+	}
+}
+
+func TestDecodeWithoutContentTypeIsJSON(t *testing.T) {
+	// Clientes antigos publicam sem content type
+	var req ExpressionRequest
+	if err := Decode("", []byte(`{"expression_id":"expr-1","expression":"1+1","deadline_ms":10}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.ExpressionID != "expr-1" || req.Expression != "1+1" || req.DeadlineMs != 10 {
+		t.Errorf("req = %+v", req)
+	}
+}
+
+func TestCodecUnsupportedType(t *testing.T) {
+	if _, err := Encode(ContentTypeProtobuf, &struct{}{}); err == nil {
+		t.Error("Encode aceitou tipo sem codificação protobuf")
+	}
+	if err := Decode(ContentTypeProtobuf, nil, &struct{}{}); err == nil {
+		t.Error("Decode aceitou tipo sem codificação protobuf")
+	}
+}
+
+func TestCodecFromEnv(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{"", ContentTypeJSON, false},
+		{"json", ContentTypeJSON, false},
+		{"protobuf", ContentTypeProtobuf, false},
+		{"PROTO", ContentTypeProtobuf, false},
+		{"xml", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv(CodecEnv, tt.env)
+			got, err := CodecFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("erro = %v, esperado erro: %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CodecFromEnv() = %q, esperado %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsProtobuf(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{ContentTypeProtobuf, true},
+		{"Application/X-Protobuf", true},
+		{"application/x-protobuf; charset=binary", true},
+		{"application/x-protobuf;proto=calculator.ExpressionRequest", true},
+		{ContentTypeJSON, false},
+		{"application/json; charset=utf-8", false},
+		{"", false},
+		{"application/x-protobuf;;", false}, // inválido: JSON
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if got := isProtobuf(tt.contentType); got != tt.want {
+				t.Errorf("isProtobuf(%q) = %v, esperado %v", tt.contentType, got, tt.want)
+			}
+		})
+	}
+
+	// Decode com parâmetros no content type usa o protobuf
+	body, err := Encode(ContentTypeProtobuf, &CancelRequest{ExpressionID: "expr-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req CancelRequest
+	if err := Decode("application/x-protobuf; charset=binary", body, &req); err != nil || req.ExpressionID != "expr-1" {
+		t.Errorf("Decode = %+v, erro = %v", req, err)
+	}
+}
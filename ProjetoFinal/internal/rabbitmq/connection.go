@@ -1,30 +1,63 @@
 package rabbitmq
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/metrics"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 const (
-	// Filas de requisição e resposta
-	RequestQueue  = "calculator.requests"
-	ResponseQueue = "calculator.responses"
+	// Filas de requisição e resposta. Cada cliente recebe as respostas na própria fila
+	// (reply_to, com o prefixo ResponseReplyPrefix); calculator.responses atende os
+	// clientes que não informam reply_to
+	RequestQueue        = "calculator.requests"
+	ResponseQueue       = "calculator.responses"
+	ResponseReplyPrefix = ResponseQueue + "."
 
-	// Filas de operações
+	// Filas de operações (ligadas ao exchange pela Topology)
 	AddQueue      = "operations.add"
 	SubtractQueue = "operations.subtract"
 	MultiplyQueue = "operations.multiply"
 	DivideQueue   = "operations.divide"
 	ResultsQueue  = "operations.results"
+
+	// Consultas de resultado (GetResult/WaitResult): o exchange fanout entrega a
+	// consulta a todas as instâncias do dispatcher; responde quem tem o ticket
+	StatusExchange = "calculator.status"
+	StatusQueue    = "calculator.status"
+
+	// StatusReplyPrefix é o prefixo das filas de resposta das consultas, uma por
+	// cliente; o dispatcher não publica consultas em outras filas
+	StatusReplyPrefix = StatusQueue + ".replies."
+
+	// Cancelamento: os clientes publicam em CancelExchange (fanout para as instâncias
+	// do dispatcher); o dono da expressão repassa o pedido aos servidores de operação
+	// por StepCancelExchange, para que descartem os steps ainda na fila
+	CancelExchange     = "calculator.cancel"
+	CancelQueue        = "calculator.cancel"
+	StepCancelExchange = "calculator.cancel.steps"
 )
 
+// ErrChannelInUse indica que o canal precisaria ser reaberto depois que a conexão
+// passou a consumir: os consumidores existentes parariam de receber mensagens
+var ErrChannelInUse = errors.New("canal já em uso por consumidores; declare as filas antes de Consume")
+
 // Connection encapsula uma conexão RabbitMQ
 type Connection struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	conn *amqp.Connection
+
+	mu        sync.RWMutex
+	channel   *amqp.Channel // substituído apenas por reopenChannel
+	prefetch  int           // reaplicado se o canal for reaberto
+	consuming bool          // Consume já foi chamado: o canal não pode mais ser substituído
 }
 
 // NewConnection cria uma nova conexão com o RabbitMQ
@@ -46,10 +79,17 @@ func NewConnection(url string) (*Connection, error) {
 	}, nil
 }
 
+// ch retorna o canal atual
+func (c *Connection) ch() *amqp.Channel {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.channel
+}
+
 // Close fecha a conexão
 func (c *Connection) Close() {
-	if c.channel != nil {
-		c.channel.Close()
+	if channel := c.ch(); channel != nil {
+		channel.Close()
 	}
 	if c.conn != nil {
 		c.conn.Close()
@@ -58,36 +98,222 @@ func (c *Connection) Close() {
 
 // DeclareQueue declara uma fila
 func (c *Connection) DeclareQueue(name string) error {
-	_, err := c.channel.QueueDeclare(
+	_, err := c.ch().QueueDeclare(
+		name,  // nome
+		true,  // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	return err
+}
+
+// DeclarePriorityQueue declara uma fila com x-max-priority, que entrega primeiro as
+// mensagens de maior prioridade. Os argumentos de uma fila existente não podem mudar:
+// se a fila já existe sem prioridade (criada por uma versão anterior), ela é usada como
+// está, com um aviso, e as mensagens seguem em ordem de chegada até que seja removida.
+// Como o fallback reabre o canal, deve ser chamado antes de Consume (senão retorna
+// ErrChannelInUse).
+func (c *Connection) DeclarePriorityQueue(name string) error {
+	_, err := c.ch().QueueDeclare(
 		name,  // nome
 		true,  // durable
 		false, // delete when unused
 		false, // exclusive
 		false, // no-wait
+		amqp.Table{"x-max-priority": int32(core.MaxPriority)},
+	)
+	var amqpErr *amqp.Error
+	if !errors.As(err, &amqpErr) || amqpErr.Code != amqp.PreconditionFailed {
+		return err
+	}
+
+	// O broker fecha o canal ao recusar a redeclaração: reabre e usa a fila existente
+	if err := c.reopenChannel(); err != nil {
+		return err
+	}
+	if _, err := c.ch().QueueDeclarePassive(name, true, false, false, false, nil); err != nil {
+		return err
+	}
+	slog.Warn("Fila existente sem x-max-priority; as prioridades não serão aplicadas até que ela seja removida",
+		slog.String("queue", name))
+	return nil
+}
+
+// reopenChannel substitui um canal fechado pelo broker, reaplicando o prefetch. Só é
+// permitido antes de Consume: as entregas dos consumidores vêm do canal antigo.
+func (c *Connection) reopenChannel() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.consuming {
+		return ErrChannelInUse
+	}
+	channel, err := c.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("falha ao reabrir canal: %v", err)
+	}
+	c.channel = channel
+	if c.prefetch > 0 {
+		return c.channel.Qos(c.prefetch, 0, false)
+	}
+	return nil
+}
+
+// DeclareReplyQueue declara uma fila exclusiva da conexão, removida ao desconectar
+// (ex: respostas das consultas de resultado de um cliente)
+func (c *Connection) DeclareReplyQueue(name string) error {
+	_, err := c.ch().QueueDeclare(
+		name,  // nome
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
 		nil,   // arguments
 	)
 	return err
 }
 
+// DeclareTemporaryQueue declara uma fila exclusiva com nome gerado pelo broker,
+// removida ao desconectar (ex: receber as mensagens de um exchange fanout)
+func (c *Connection) DeclareTemporaryQueue() (string, error) {
+	q, err := c.ch().QueueDeclare(
+		"",    // nome gerado pelo broker
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	return q.Name, err
+}
+
+// DeclareExchange declara um exchange durável
+func (c *Connection) DeclareExchange(name, kind string) error {
+	return c.ch().ExchangeDeclare(
+		name,  // nome
+		kind,  // tipo
+		true,  // durable
+		false, // auto-deleted
+		false, // internal
+		false, // no-wait
+		nil,   // arguments
+	)
+}
+
+// BindQueue liga uma fila a um exchange pela routing key
+func (c *Connection) BindQueue(queue, key, exchange string) error {
+	return c.ch().QueueBind(queue, key, exchange, false, nil)
+}
+
+// SetPrefetch limita as mensagens entregues e ainda não confirmadas por consumidor
+// do canal (0 desativa o limite); deve ser chamado antes de Consume
+func (c *Connection) SetPrefetch(count int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prefetch = count
+	return c.channel.Qos(count, 0, false)
+}
+
+// Concurrency normaliza os flags -workers e -prefetch dos servidores de operação: ao
+// menos 1 worker e, sem prefetch informado, 2 entregas não confirmadas por worker
+func Concurrency(workers, prefetch int) (int, int) {
+	if workers < 1 {
+		workers = 1
+	}
+	if prefetch <= 0 {
+		prefetch = 2 * workers
+	}
+	return workers, prefetch
+}
+
+// ServeWorkers processa as entregas com `workers` goroutines; cada worker confirma
+// (ack/nack) a própria entrega em handle
+func ServeWorkers(msgs <-chan amqp.Delivery, workers int, handle func(amqp.Delivery)) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for msg := range msgs {
+				handle(msg)
+			}
+		}()
+	}
+}
+
 // Publish publica uma mensagem em uma fila
 func (c *Connection) Publish(queue string, body []byte) error {
-	return c.channel.Publish(
-		"",    // exchange
-		queue, // routing key
+	return c.PublishWithContext(context.Background(), queue, body)
+}
+
+// PublishWithContext publica uma mensagem propagando o trace context nos headers
+func (c *Connection) PublishWithContext(ctx context.Context, queue string, body []byte) error {
+	return c.PublishWithHeaders(ctx, queue, body, nil)
+}
+
+// PublishWithHeaders publica uma mensagem com headers adicionais (ex: autenticação)
+func (c *Connection) PublishWithHeaders(ctx context.Context, queue string, body []byte, extra amqp.Table) error {
+	return c.PublishToExchange(ctx, "", queue, body, extra)
+}
+
+// PublishToExchange publica uma mensagem em um exchange com a routing key informada
+// (exchange vazio é o default exchange, que roteia pelo nome da fila)
+func (c *Connection) PublishToExchange(ctx context.Context, exchange, key string, body []byte, extra amqp.Table) error {
+	return c.Send(ctx, Message{Exchange: exchange, RoutingKey: key, Body: body, Headers: extra})
+}
+
+// Message descreve uma publicação
+type Message struct {
+	Exchange    string // vazio usa o default exchange (RoutingKey é o nome da fila)
+	RoutingKey  string
+	Body        []byte
+	Headers     amqp.Table // headers adicionais (ex: autenticação)
+	Priority    int        // 0 a core.MaxPriority; só tem efeito em filas com x-max-priority
+	ContentType string     // codificação de Body (vazio: JSON)
+}
+
+// Send publica a mensagem propagando o trace context nos headers
+func (c *Connection) Send(ctx context.Context, msg Message) error {
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	InjectContext(ctx, headers)
+
+	key := msg.RoutingKey
+	contentType := msg.ContentType
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+	err := c.ch().PublishWithContext(
+		ctx,
+		msg.Exchange,
+		key,   // routing key
 		false, // mandatory
 		false, // immediate
 		amqp.Publishing{
+			Headers:      headers,
 			DeliveryMode: amqp.Persistent,
-			ContentType:  "application/json",
-			Body:         body,
+			ContentType:  contentType,
+			Priority:     uint8(core.ClampPriority(msg.Priority)),
+			Body:         msg.Body,
 			Timestamp:    time.Now(),
 		},
 	)
+
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	metrics.RabbitMQPublished.WithLabelValues(queueLabel(key), status).Inc()
+	return err
 }
 
 // Consume consome mensagens de uma fila
 func (c *Connection) Consume(queue string) (<-chan amqp.Delivery, error) {
-	return c.channel.Consume(
+	c.mu.Lock()
+	c.consuming = true
+	channel := c.channel
+	c.mu.Unlock()
+	deliveries, err := channel.Consume(
 		queue, // queue
 		"",    // consumer
 		false, // auto-ack
@@ -96,17 +322,32 @@ func (c *Connection) Consume(queue string) (<-chan amqp.Delivery, error) {
 		false, // no-wait
 		nil,   // args
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Repassa as mensagens contabilizando o consumo por fila
+	consumed := metrics.RabbitMQConsumed.WithLabelValues(queue)
+	out := make(chan amqp.Delivery)
+	go func() {
+		defer close(out)
+		for d := range deliveries {
+			consumed.Inc()
+			out <- d
+		}
+	}()
+	return out, nil
 }
 
-// SetupQueues declara todas as filas necessárias
-func SetupQueues(conn *Connection) error {
+// SetupQueues declara as filas endereçadas diretamente e a topologia das operações
+func SetupQueues(conn *Connection, topology *Topology) error {
+	// Requisições são ordenadas por prioridade (interativo antes de lote)
+	if err := conn.DeclarePriorityQueue(RequestQueue); err != nil {
+		return fmt.Errorf("falha ao declarar fila %s: %v", RequestQueue, err)
+	}
+
 	queues := []string{
-		RequestQueue,
 		ResponseQueue,
-		AddQueue,
-		SubtractQueue,
-		MultiplyQueue,
-		DivideQueue,
 		ResultsQueue,
 	}
 
@@ -114,24 +355,80 @@ func SetupQueues(conn *Connection) error {
 		if err := conn.DeclareQueue(queue); err != nil {
 			return fmt.Errorf("falha ao declarar fila %s: %v", queue, err)
 		}
-		log.Printf("Fila declarada: %s", queue)
+		slog.Debug("Fila declarada", slog.String("queue", queue))
 	}
 
-	return nil
+	for _, exchange := range []string{StatusExchange, CancelExchange, StepCancelExchange} {
+		if err := conn.DeclareExchange(exchange, "fanout"); err != nil {
+			return fmt.Errorf("falha ao declarar exchange %s: %v", exchange, err)
+		}
+	}
+
+	return DeclareTopology(conn, topology)
+}
+
+// StatusQueueFor retorna a fila de consultas de resultado de uma instância do
+// dispatcher (vazio usa calculator.status)
+func StatusQueueFor(instance string) string {
+	if instance == "" {
+		return StatusQueue
+	}
+	return StatusQueue + "." + instance
+}
+
+// CancelQueueFor retorna a fila de pedidos de cancelamento de uma instância do
+// dispatcher (vazio usa calculator.cancel)
+func CancelQueueFor(instance string) string {
+	if instance == "" {
+		return CancelQueue
+	}
+	return CancelQueue + "." + instance
+}
+
+// StatusReplyQueue retorna a fila de resposta das consultas de um cliente
+func StatusReplyQueue(clientID string) string {
+	return StatusReplyPrefix + clientID
+}
+
+// IsStatusReplyQueue indica se name é uma fila de resposta de consultas
+func IsStatusReplyQueue(name string) bool {
+	return len(name) > len(StatusReplyPrefix) && strings.HasPrefix(name, StatusReplyPrefix)
+}
+
+// ResponseReplyQueue retorna a fila de respostas das expressões de um cliente
+func ResponseReplyQueue(clientID string) string {
+	return ResponseReplyPrefix + clientID
+}
+
+// IsResponseReplyQueue indica se name é uma fila de respostas de um cliente
+func IsResponseReplyQueue(name string) bool {
+	return len(name) > len(ResponseReplyPrefix) && strings.HasPrefix(name, ResponseReplyPrefix)
+}
+
+// queueLabel agrupa as filas de resposta por cliente em um único rótulo de métrica
+func queueLabel(queue string) string {
+	switch {
+	case IsStatusReplyQueue(queue):
+		return StatusReplyPrefix + "*"
+	case IsResponseReplyQueue(queue):
+		return ResponseReplyPrefix + "*"
+	}
+	return queue
+}
+
+// ResultsQueueFor retorna a fila de resultados de uma instância do dispatcher
+// (vazio usa a fila compartilhada operations.results)
+func ResultsQueueFor(instance string) string {
+	if instance == "" {
+		return ResultsQueue
+	}
+	return ResultsQueue + "." + instance
 }
 
-// GetQueueForOperation retorna o nome da fila para uma operação
-func GetQueueForOperation(operation string) string {
-	switch operation {
-	case "add":
-		return AddQueue
-	case "subtract":
-		return SubtractQueue
-	case "multiply":
-		return MultiplyQueue
-	case "divide":
-		return DivideQueue
-	default:
-		return ""
+// ReplyQueue retorna a fila onde publicar o resultado de uma operação
+func ReplyQueue(req OperationRequest) string {
+	if req.ReplyTo != "" {
+		return req.ReplyTo
 	}
+	return ResultsQueue
 }
@@ -0,0 +1,116 @@
+package rabbitmq
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestReopenChannelAfterConsume(t *testing.T) {
+	// Depois de Consume o canal não é substituído (nem chega a usar a conexão)
+	c := &Connection{consuming: true}
+	if err := c.reopenChannel(); !errors.Is(err, ErrChannelInUse) {
+		t.Errorf("erro = %v, esperado %v", err, ErrChannelInUse)
+	}
+}
+
+func TestInstanceQueues(t *testing.T) {
+	tests := []struct {
+		name     string
+		queueFor func(string) string
+		shared   string
+	}{
+		{"results", ResultsQueueFor, ResultsQueue},
+		{"status", StatusQueueFor, StatusQueue},
+		{"cancel", CancelQueueFor, CancelQueue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Sem -instance, a instância única usa a fila compartilhada
+			if got := tt.queueFor(""); got != tt.shared {
+				t.Errorf("fila sem instância = %q, esperado %q", got, tt.shared)
+			}
+			a, b := tt.queueFor("d1"), tt.queueFor("d2")
+			if a != tt.shared+".d1" || a == b {
+				t.Errorf("filas das instâncias = %q, %q", a, b)
+			}
+		})
+	}
+}
+
+func TestReplyQueue(t *testing.T) {
+	// Steps de dispatchers anteriores ao ReplyTo respondem na fila compartilhada
+	if got := ReplyQueue(OperationRequest{StepID: "s1"}); got != ResultsQueue {
+		t.Errorf("ReplyQueue sem ReplyTo = %q, esperado %q", got, ResultsQueue)
+	}
+	req := OperationRequest{StepID: "s1", ReplyTo: ResultsQueueFor("d1")}
+	if got := ReplyQueue(req); got != "operations.results.d1" {
+		t.Errorf("ReplyQueue = %q, esperado operations.results.d1", got)
+	}
+
+	// O ReplyTo sobrevive ao JSON e é omitido quando vazio
+	var decoded OperationRequest
+	body, _ := Encode(ContentTypeJSON, &req)
+	if err := Decode(ContentTypeJSON, body, &decoded); err != nil || ReplyQueue(decoded) != req.ReplyTo {
+		t.Errorf("ReplyTo após o JSON = %q, %v", decoded.ReplyTo, err)
+	}
+	if body, _ := Encode(ContentTypeJSON, &OperationRequest{StepID: "s1"}); strings.Contains(string(body), "reply_to") {
+		t.Errorf("reply_to vazio serializado: %s", body)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	tests := []struct {
+		workers, prefetch         int
+		wantWorkers, wantPrefetch int
+	}{
+		{4, 0, 4, 8},   // padrão: 2 entregas por worker
+		{4, 3, 4, 3},   // prefetch explícito é mantido
+		{0, 0, 1, 2},   // ao menos 1 worker
+		{-2, -1, 1, 2}, // valores negativos
+		{1, 50, 1, 50},
+	}
+	for _, tt := range tests {
+		workers, prefetch := Concurrency(tt.workers, tt.prefetch)
+		if workers != tt.wantWorkers || prefetch != tt.wantPrefetch {
+			t.Errorf("Concurrency(%d, %d) = %d, %d, esperado %d, %d",
+				tt.workers, tt.prefetch, workers, prefetch, tt.wantWorkers, tt.wantPrefetch)
+		}
+	}
+}
+
+func TestServeWorkers(t *testing.T) {
+	const workers, total = 3, 10
+	msgs := make(chan amqp.Delivery)
+	release := make(chan struct{})
+	started := make(chan uint64, total)
+	var done sync.WaitGroup
+	done.Add(total)
+
+	ServeWorkers(msgs, workers, func(msg amqp.Delivery) {
+		started <- msg.DeliveryTag
+		<-release
+		done.Done()
+	})
+
+	// As primeiras entregas ocupam todos os workers ao mesmo tempo
+	for tag := uint64(1); tag <= workers; tag++ {
+		select {
+		case msgs <- amqp.Delivery{DeliveryTag: tag}:
+		case <-time.After(time.Second):
+			t.Fatalf("entrega %d não recebida: menos de %d workers livres", tag, workers)
+		}
+		<-started
+	}
+
+	close(release)
+	for tag := uint64(workers + 1); tag <= total; tag++ {
+		msgs <- amqp.Delivery{DeliveryTag: tag}
+	}
+	close(msgs)
+	done.Wait()
+}
@@ -0,0 +1,173 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Executor implementa core.OperationExecutor sobre RabbitMQ: publica o step no
+// exchange das operações e aguarda o resultado correspondente (pelo StepID)
+// na fila de resultados da instância, entregue por Deliver.
+type Executor struct {
+	conn         *Connection
+	topology     *Topology
+	codec        string // content type das operações publicadas
+	resultsQueue string // informada em ReplyTo
+	mu           sync.Mutex
+	waiting      map[string]chan OperationResponse // StepID -> step aguardando resultado
+}
+
+// NewExecutor cria um executor que publica com a topologia e codificação informadas
+func NewExecutor(conn *Connection, topology *Topology, codec, resultsQueue string) *Executor {
+	return &Executor{
+		conn:         conn,
+		topology:     topology,
+		codec:        codec,
+		resultsQueue: resultsQueue,
+		waiting:      make(map[string]chan OperationResponse),
+	}
+}
+
+// Execute publica o step e bloqueia até o resultado chegar ou o contexto terminar
+func (e *Executor) Execute(ctx context.Context, req core.OperationRequest) (core.OperationResponse, error) {
+	routingKey, ok := e.topology.RoutingKey(req.Operation)
+	if !ok {
+		return stepError(req, "UNKNOWN_OPERATION", fmt.Sprintf("Operação desconhecida: %s", req.Operation)), nil
+	}
+
+	body, err := Encode(e.codec, &OperationRequest{
+		ExpressionID: req.ExpressionID,
+		StepID:       req.StepID,
+		Operation:    req.Operation,
+		Numbers:      req.Numbers,
+		DeadlineMs:   req.DeadlineMs,
+		ReplyTo:      e.resultsQueue,
+	})
+	if err != nil {
+		return stepError(req, "SERIALIZATION_ERROR", fmt.Sprintf("Erro ao serializar: %v", err)), nil
+	}
+
+	// Registra a espera antes de publicar para não perder um resultado rápido
+	result := make(chan OperationResponse, 1)
+	e.mu.Lock()
+	e.waiting[req.StepID] = result
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		delete(e.waiting, req.StepID)
+		e.mu.Unlock()
+	}()
+
+	// Repassa o principal aos servidores de operação; o instante de publicação permite
+	// que descartem o step se a expressão for cancelada enquanto ele está na fila
+	headers := amqp.Table{HeaderPublishedAt: time.Now().UnixNano()}
+	if principal, ok := auth.FromContext(ctx); ok {
+		headers[auth.HeaderPrincipal] = principal.ID
+	}
+	if err := e.conn.Send(ctx, Message{
+		Exchange:    e.topology.Exchange,
+		RoutingKey:  routingKey,
+		Body:        body,
+		Headers:     headers,
+		Priority:    req.Priority,
+		ContentType: e.codec,
+	}); err != nil {
+		return stepError(req, "PUBLISH_ERROR", fmt.Sprintf("Erro ao publicar: %v", err)), nil
+	}
+
+	select {
+	case resp := <-result:
+		return core.OperationResponse{
+			ExpressionID: resp.ExpressionID,
+			StepID:       resp.StepID,
+			Result:       resp.Result,
+			Error:        ErrorToCore(resp.Error),
+			Server:       resp.Server,
+		}, nil
+	case <-ctx.Done():
+		return core.OperationResponse{}, ctx.Err()
+	}
+}
+
+// Deliver entrega um resultado ao step que o aguarda. Retorna false se nenhum step
+// aguarda esse StepID (resultado repetido, atrasado ou de outra instância).
+func (e *Executor) Deliver(resp OperationResponse) bool {
+	e.mu.Lock()
+	result, ok := e.waiting[resp.StepID]
+	delete(e.waiting, resp.StepID)
+	e.mu.Unlock()
+	if ok {
+		result <- resp
+	}
+	return ok
+}
+
+func stepError(req core.OperationRequest, code, message string) core.OperationResponse {
+	return core.OperationResponse{
+		ExpressionID: req.ExpressionID,
+		StepID:       req.StepID,
+		Error:        &core.ErrorInfo{Code: code, Message: message},
+	}
+}
+
+// ErrorToCore converte o erro da mensagem para o modelo comum
+func ErrorToCore(e *ErrorInfo) *core.ErrorInfo {
+	if e == nil {
+		return nil
+	}
+	return &core.ErrorInfo{Code: e.Code, Message: e.Message, RetryAfterMs: e.RetryAfterMs}
+}
+
+// ErrorFromCore converte o erro do modelo comum para a mensagem
+func ErrorFromCore(e *core.ErrorInfo) *ErrorInfo {
+	if e == nil {
+		return nil
+	}
+	return &ErrorInfo{Code: e.Code, Message: e.Message, RetryAfterMs: e.RetryAfterMs}
+}
+
+// TraceFromCore converte o trace de execução para a mensagem
+func TraceFromCore(trace []core.StepTrace) []StepTrace {
+	if len(trace) == 0 {
+		return nil
+	}
+	out := make([]StepTrace, len(trace))
+	for i, t := range trace {
+		out[i] = StepTrace{
+			StepID:    t.StepID,
+			Operation: t.Operation,
+			Operands:  t.Operands,
+			Result:    t.Result,
+			Server:    t.Server,
+			LatencyUs: t.LatencyUs,
+			Error:     ErrorFromCore(t.Error),
+		}
+	}
+	return out
+}
+
+// TraceToCore converte o trace da mensagem para o modelo comum
+func TraceToCore(trace []StepTrace) []core.StepTrace {
+	if len(trace) == 0 {
+		return nil
+	}
+	out := make([]core.StepTrace, len(trace))
+	for i, t := range trace {
+		out[i] = core.StepTrace{
+			StepID:    t.StepID,
+			Operation: t.Operation,
+			Operands:  t.Operands,
+			Result:    t.Result,
+			Server:    t.Server,
+			LatencyUs: t.LatencyUs,
+			Error:     ErrorToCore(t.Error),
+		}
+	}
+	return out
+}
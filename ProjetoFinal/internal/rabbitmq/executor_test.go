@@ -0,0 +1,26 @@
+package rabbitmq
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+)
+
+func TestTraceCoreRoundTrip(t *testing.T) {
+	trace := []core.StepTrace{
+		{StepID: "e1_step0", Operation: "add", Operands: []float64{2, 3}, Result: 5, Server: "add@host:1", LatencyUs: 120},
+		{StepID: "e1_step1", Operation: "divide", Operands: []float64{5, 0}, LatencyUs: 80,
+			Error: &core.ErrorInfo{Code: "DIV_BY_ZERO", Message: "divisão por zero", RetryAfterMs: 10}},
+	}
+	msg := TraceFromCore(trace)
+	if len(msg) != 2 || msg[1].Error == nil || msg[1].Error.RetryAfterMs != 10 {
+		t.Fatalf("TraceFromCore = %+v", msg)
+	}
+	if got := TraceToCore(msg); !reflect.DeepEqual(got, trace) {
+		t.Errorf("TraceToCore(TraceFromCore(trace)) = %+v, esperado %+v", got, trace)
+	}
+	if TraceFromCore(nil) != nil || TraceToCore([]StepTrace{}) != nil {
+		t.Error("trace vazio deveria virar nil (omitido do JSON)")
+	}
+}
@@ -1,17 +1,49 @@
 package rabbitmq
 
+// Os modelos abaixo espelham as mensagens de proto/calculator.proto; a conversão
+// para o formato binário fica em codec.go
+
 // ExpressionRequest representa uma requisição de expressão via RabbitMQ
 type ExpressionRequest struct {
 	ExpressionID string `json:"expression_id"`
 	Expression   string `json:"expression"`
 	DeadlineMs   int64  `json:"deadline_ms"`
+	IncludeTrace bool   `json:"include_trace,omitempty"`
+	Priority     int    `json:"priority,omitempty"` // 0 a 9; maior é atendida primeiro
+	ClientID     string `json:"client_id,omitempty"`
+	ReplyTo      string `json:"reply_to,omitempty"` // Fila de resposta do cliente (vazio: calculator.responses)
 }
 
 // ExpressionResponse representa uma resposta de expressão via RabbitMQ
 type ExpressionResponse struct {
-	ExpressionID string     `json:"expression_id"`
-	Result       float64    `json:"result"`
-	Error        *ErrorInfo `json:"error,omitempty"`
+	ExpressionID string      `json:"expression_id"`
+	Result       float64     `json:"result"`
+	Error        *ErrorInfo  `json:"error,omitempty"`
+	Trace        []StepTrace `json:"trace,omitempty"`
+	ClientID     string      `json:"client_id,omitempty"` // client_id da requisição: o cliente consome só as suas respostas
+}
+
+// ResultRequest consulta o estado de uma expressão pelo ticket (o expressionID)
+type ResultRequest struct {
+	Ticket    string `json:"ticket"`
+	TimeoutMs int64  `json:"timeout_ms,omitempty"` // > 0: aguarda a expressão terminar
+	ReplyTo   string `json:"reply_to"`             // Fila da resposta
+	ClientID  string `json:"client_id,omitempty"`
+}
+
+// ExpressionStatus é a resposta de uma consulta de resultado
+type ExpressionStatus struct {
+	Ticket   string              `json:"ticket"`
+	State    string              `json:"state,omitempty"`    // QUEUED, RUNNING, DONE ou FAILED
+	Response *ExpressionResponse `json:"response,omitempty"` // Preenchido em DONE/FAILED
+	Error    *ErrorInfo          `json:"error,omitempty"`    // Falha da consulta (ex: NOT_FOUND)
+}
+
+// CancelRequest pede o cancelamento de uma expressão em andamento (calculator.cancel);
+// o dispatcher repassa o pedido aos servidores de operação (calculator.cancel.steps)
+type CancelRequest struct {
+	ExpressionID string `json:"expression_id"`
+	ClientID     string `json:"client_id,omitempty"`
 }
 
 // OperationRequest representa uma requisição de operação via RabbitMQ
@@ -21,6 +53,7 @@ type OperationRequest struct {
 	Operation    string    `json:"operation"`
 	Numbers      []float64 `json:"numbers"`
 	DeadlineMs   int64     `json:"deadline_ms"`
+	ReplyTo      string    `json:"reply_to,omitempty"` // Fila de resultados do dispatcher dono da expressão
 }
 
 // OperationResponse representa uma resposta de operação via RabbitMQ
@@ -29,10 +62,23 @@ type OperationResponse struct {
 	StepID       string     `json:"step_id"`
 	Result       float64    `json:"result"`
 	Error        *ErrorInfo `json:"error,omitempty"`
+	Server       string     `json:"server,omitempty"`
+}
+
+// StepTrace representa o trace de execução de um step via RabbitMQ
+type StepTrace struct {
+	StepID    string     `json:"step_id"`
+	Operation string     `json:"operation"`
+	Operands  []float64  `json:"operands"`
+	Result    float64    `json:"result"`
+	Server    string     `json:"server,omitempty"`
+	LatencyUs int64      `json:"latency_us"`
+	Error     *ErrorInfo `json:"error,omitempty"`
 }
 
 // ErrorInfo representa informações de erro
 type ErrorInfo struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+	RetryAfterMs int64  `json:"retry_after_ms,omitempty"`
 }
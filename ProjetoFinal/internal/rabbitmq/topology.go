@@ -0,0 +1,173 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// TopologyEnv aponta para um arquivo JSON com a topologia (vazio usa DefaultTopology)
+const TopologyEnv = "CALC_RABBITMQ_TOPOLOGY"
+
+// Exchange padrão das operações
+const (
+	DefaultExchange     = "calculator"
+	DefaultExchangeType = "topic"
+)
+
+// Topology descreve o exchange das operações, as rotas e as filas ligadas a ele
+type Topology struct {
+	Exchange     string            `json:"exchange"`
+	ExchangeType string            `json:"exchange_type"` // "direct" ou "topic"
+	Routes       map[string]string `json:"routes"`        // operação -> routing key publicada pelo dispatcher
+	Queues       []QueueBinding    `json:"queues"`
+}
+
+// QueueBinding liga uma fila ao exchange pelas routing keys (ou padrões, no exchange topic)
+type QueueBinding struct {
+	Name        string   `json:"name"`
+	RoutingKeys []string `json:"routing_keys"`
+}
+
+// OperationRoutingKey retorna a routing key padrão de uma operação (ex: "op.add")
+func OperationRoutingKey(operation string) string {
+	return "op." + operation
+}
+
+// DefaultTopology liga cada fila operations.<op> à routing key op.<op>
+func DefaultTopology() *Topology {
+	t := &Topology{
+		Exchange:     DefaultExchange,
+		ExchangeType: DefaultExchangeType,
+		Routes:       make(map[string]string),
+	}
+	for operation, queue := range map[string]string{
+		"add":      AddQueue,
+		"subtract": SubtractQueue,
+		"multiply": MultiplyQueue,
+		"divide":   DivideQueue,
+	} {
+		key := OperationRoutingKey(operation)
+		t.Routes[operation] = key
+		t.Queues = append(t.Queues, QueueBinding{Name: queue, RoutingKeys: []string{key}})
+	}
+	return t
+}
+
+// LoadTopology lê a topologia do arquivo em CALC_RABBITMQ_TOPOLOGY, ou retorna a padrão
+func LoadTopology() (*Topology, error) {
+	path := os.Getenv(TopologyEnv)
+	if path == "" {
+		return DefaultTopology(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler topologia: %v", err)
+	}
+	var t Topology
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("topologia inválida em %s: %v", path, err)
+	}
+	if t.Exchange == "" {
+		t.Exchange = DefaultExchange
+	}
+	if t.ExchangeType == "" {
+		t.ExchangeType = DefaultExchangeType
+	}
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("topologia inválida em %s: %v", path, err)
+	}
+	return &t, nil
+}
+
+func (t *Topology) validate() error {
+	if t.ExchangeType != "direct" && t.ExchangeType != "topic" {
+		return fmt.Errorf("exchange_type deve ser direct ou topic, recebido %q", t.ExchangeType)
+	}
+	if len(t.Routes) == 0 {
+		return fmt.Errorf("nenhuma rota de operação definida")
+	}
+	for _, q := range t.Queues {
+		if q.Name == "" {
+			return fmt.Errorf("fila sem nome")
+		}
+		if len(q.RoutingKeys) == 0 {
+			return fmt.Errorf("fila %s sem routing_keys", q.Name)
+		}
+	}
+	return nil
+}
+
+// RoutingKey retorna a routing key da operação (false se a operação não tiver rota)
+func (t *Topology) RoutingKey(operation string) (string, bool) {
+	key, ok := t.Routes[operation]
+	return key, ok && key != ""
+}
+
+// QueueFor retorna a fila ligada à routing key da operação (vazio se nenhuma fila a recebe)
+func (t *Topology) QueueFor(operation string) string {
+	key, ok := t.RoutingKey(operation)
+	if !ok {
+		return ""
+	}
+	for _, q := range t.Queues {
+		for _, pattern := range q.RoutingKeys {
+			if t.matches(pattern, key) {
+				return q.Name
+			}
+		}
+	}
+	return ""
+}
+
+// matches aplica a semântica de roteamento do exchange (igualdade no direct, * e # no topic)
+func (t *Topology) matches(pattern, key string) bool {
+	if t.ExchangeType == "direct" {
+		return pattern == key
+	}
+	return matchTopic(strings.Split(pattern, "."), strings.Split(key, "."))
+}
+
+func matchTopic(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if matchTopic(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && matchTopic(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && matchTopic(pattern[1:], words[1:])
+	}
+}
+
+// DeclareTopology declara o exchange, as filas (com prioridade) e os bindings (operações idempotentes)
+func DeclareTopology(conn *Connection, t *Topology) error {
+	if err := conn.DeclareExchange(t.Exchange, t.ExchangeType); err != nil {
+		return fmt.Errorf("falha ao declarar exchange %s: %v", t.Exchange, err)
+	}
+	for _, q := range t.Queues {
+		if err := conn.DeclarePriorityQueue(q.Name); err != nil {
+			return fmt.Errorf("falha ao declarar fila %s: %v", q.Name, err)
+		}
+		for _, key := range q.RoutingKeys {
+			if err := conn.BindQueue(q.Name, key, t.Exchange); err != nil {
+				return fmt.Errorf("falha ao ligar fila %s a %s: %v", q.Name, key, err)
+			}
+			slog.Debug("Fila ligada ao exchange",
+				slog.String("queue", q.Name),
+				slog.String("exchange", t.Exchange),
+				slog.String("routing_key", key))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package rabbitmq
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"op.add", "op.add", true},
+		{"op.add", "op.sub", false},
+		{"op.add", "op.add.fast", false},
+		{"op.*", "op.add", true},
+		{"op.*", "op", false},
+		{"op.*", "op.add.fast", false},
+		{"*.add", "op.add", true},
+		{"*.*", "op.add", true},
+		{"#", "op.add", true},
+		{"#", "op", true},
+		{"op.#", "op", true},
+		{"op.#", "op.add", true},
+		{"op.#", "op.add.fast", true},
+		{"op.#", "ops.add", false},
+		{"#.add", "op.add", true},
+		{"#.add", "add", true},
+		{"#.add", "op.add.fast", false},
+		{"op.#.fast", "op.fast", true},
+		{"op.#.fast", "op.add.div.fast", true},
+		{"op.*.fast", "op.fast", false},
+		{"*.#", "op", true},
+		{"op", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.key, func(t *testing.T) {
+			if got := matchTopic(strings.Split(tt.pattern, "."), strings.Split(tt.key, ".")); got != tt.want {
+				t.Errorf("matchTopic(%q, %q) = %v, esperado %v", tt.pattern, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueFor(t *testing.T) {
+	topic := &Topology{
+		ExchangeType: "topic",
+		Routes: map[string]string{
+			"add":      "op.add",
+			"subtract": "op.sub",
+			"multiply": "math.mul",
+			"divide":   "calc.div",
+			"pow":      "",
+		},
+		Queues: []QueueBinding{
+			{Name: "operations.add", RoutingKeys: []string{"op.add"}},
+			{Name: "operations.misc", RoutingKeys: []string{"op.*", "math.#"}},
+		},
+	}
+	direct := &Topology{ExchangeType: "direct", Routes: topic.Routes, Queues: topic.Queues}
+
+	tests := []struct {
+		topology  *Topology
+		operation string
+		want      string
+	}{
+		{topic, "add", "operations.add"}, // primeira fila que recebe a chave
+		{topic, "subtract", "operations.misc"},
+		{topic, "multiply", "operations.misc"},
+		{topic, "divide", ""},  // nenhuma fila recebe a chave
+		{topic, "pow", ""},     // rota vazia
+		{topic, "unknown", ""}, // sem rota
+		{direct, "add", "operations.add"},
+		{direct, "subtract", ""}, // no direct, op.* é literal
+	}
+	for _, tt := range tests {
+		t.Run(tt.topology.ExchangeType+"/"+tt.operation, func(t *testing.T) {
+			if got := tt.topology.QueueFor(tt.operation); got != tt.want {
+				t.Errorf("QueueFor(%q) = %q, esperado %q", tt.operation, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultTopology(t *testing.T) {
+	topo := DefaultTopology()
+	for operation, queue := range map[string]string{"add": AddQueue, "subtract": SubtractQueue, "multiply": MultiplyQueue, "divide": DivideQueue} {
+		if got := topo.QueueFor(operation); got != queue {
+			t.Errorf("QueueFor(%q) = %q, esperado %q", operation, got, queue)
+		}
+	}
+	if err := topo.validate(); err != nil {
+		t.Errorf("topologia padrão inválida: %v", err)
+	}
+}
+
+func TestLoadTopology(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "topology.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Setenv(TopologyEnv, "")
+	if topo, err := LoadTopology(); err != nil || topo.Exchange != DefaultExchange {
+		t.Fatalf("sem arquivo: %+v, %v", topo, err)
+	}
+
+	t.Setenv(TopologyEnv, write(`{"routes": {"add": "op.add"}, "queues": [{"name": "q", "routing_keys": ["op.#"]}]}`))
+	topo, err := LoadTopology()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topo.Exchange != DefaultExchange || topo.ExchangeType != DefaultExchangeType || topo.QueueFor("add") != "q" {
+		t.Errorf("topologia = %+v", topo)
+	}
+
+	for name, content := range map[string]string{
+		"tipo inválido":  `{"exchange_type": "fanout", "routes": {"add": "op.add"}}`,
+		"sem rotas":      `{"queues": []}`,
+		"fila sem nome":  `{"routes": {"add": "op.add"}, "queues": [{"routing_keys": ["op.add"]}]}`,
+		"fila sem chave": `{"routes": {"add": "op.add"}, "queues": [{"name": "q"}]}`,
+		"JSON inválido":  `{`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(TopologyEnv, write(content))
+			if _, err := LoadTopology(); err == nil {
+				t.Error("topologia inválida aceita")
+			}
+		})
+	}
+}
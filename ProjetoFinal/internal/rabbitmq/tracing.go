@@ -0,0 +1,49 @@
+package rabbitmq
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// headerCarrier adapta os headers AMQP para o propagador de contexto do OpenTelemetry
+type headerCarrier amqp.Table
+
+// Get retorna o valor de um header
+func (c headerCarrier) Get(key string) string {
+	if v, ok := c[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// Set define o valor de um header
+func (c headerCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// Keys retorna as chaves dos headers
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+var _ propagation.TextMapCarrier = headerCarrier(nil)
+
+// InjectContext grava o trace context (W3C traceparent/tracestate) nos headers
+func InjectContext(ctx context.Context, headers amqp.Table) {
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(headers))
+}
+
+// ExtractContext lê o trace context dos headers de uma mensagem recebida
+func ExtractContext(ctx context.Context, headers amqp.Table) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, headerCarrier(headers))
+}
@@ -0,0 +1,46 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextPropagationThroughHeaders(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	headers := amqp.Table{"x-retry": int32(1)}
+	InjectContext(trace.ContextWithSpanContext(context.Background(), sc), headers)
+	if headers["traceparent"] != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("traceparent = %v", headers["traceparent"])
+	}
+
+	got := trace.SpanContextFromContext(ExtractContext(context.Background(), headers))
+	if !got.IsRemote() || got.TraceID() != sc.TraceID() || got.SpanID() != sc.SpanID() {
+		t.Errorf("contexto extraído = %+v, esperado %+v", got, sc)
+	}
+}
+
+func TestExtractContextWithoutHeaders(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	for name, headers := range map[string]amqp.Table{
+		"nil":             nil,
+		"sem traceparent": {"x-retry": int32(1)},
+		"valor não texto": {"traceparent": []byte("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if sc := trace.SpanContextFromContext(ExtractContext(context.Background(), headers)); sc.IsValid() {
+				t.Errorf("contexto extraído = %+v, esperado vazio", sc)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package repl
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/pkg/calcclient"
+)
+
+// Formatos de entrada (-input) e saída (-output) do modo não interativo
+const (
+	FormatText   = "text"   // entrada: uma expressão por linha; saída: "expr = resultado"
+	FormatNDJSON = "ndjson" // entrada: um BatchItem JSON por linha
+	FormatJSON   = "json"   // saída: um BatchResult JSON por linha
+	FormatCSV    = "csv"    // saída: cabeçalho e uma linha por expressão
+)
+
+var (
+	inputFormats  = []string{FormatText, FormatNDJSON}
+	outputFormats = []string{FormatText, FormatJSON, FormatCSV}
+)
+
+// Códigos das falhas do lado do cliente no modo não interativo; os demais códigos
+// vêm do dispatcher
+const (
+	CodeClientError = "CLIENT_ERROR" // entrada inválida, variável não definida ou falha do transporte
+	CodeTimeout     = "TIMEOUT"      // a resposta não chegou dentro do timeout
+)
+
+// Códigos de saída do processo no modo não interativo
+const (
+	ExitOK     = 0 // todas as expressões foram calculadas
+	ExitFailed = 1 // ao menos uma expressão terminou com erro
+	ExitUsage  = 2 // flags, arquivo de entrada ou conexão inválidos
+)
+
+// Batch descreve a entrada e a saída do modo não interativo
+type Batch struct {
+	Exprs  []string // -e, calculadas antes do arquivo
+	File   string   // -f; "-" lê da entrada padrão
+	Input  string   // formato de File
+	Output string
+}
+
+// BatchItem é uma linha da entrada NDJSON
+type BatchItem struct {
+	ID         string `json:"id,omitempty"`  // repetido na saída para correlacionar
+	Expression string `json:"expression"`    // pode usar variáveis e ans
+	Let        string `json:"let,omitempty"` // guarda o resultado nesta variável
+}
+
+// BatchResult é o resultado de uma expressão no modo não interativo
+type BatchResult struct {
+	ID           string      `json:"id,omitempty"`
+	Expression   string      `json:"expression"`
+	Let          string      `json:"let,omitempty"`
+	ExpressionID string      `json:"expression_id,omitempty"`
+	Result       *float64    `json:"result,omitempty"`
+	Error        *BatchError `json:"error,omitempty"`
+	DurationMs   float64     `json:"duration_ms"`
+	Trace        []BatchStep `json:"trace,omitempty"`
+}
+
+// BatchError é o erro de uma expressão no modo não interativo
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BatchStep é um step do trace de execução no modo não interativo
+type BatchStep struct {
+	StepID    string      `json:"step_id"`
+	Operation string      `json:"operation"`
+	Operands  []float64   `json:"operands"`
+	Result    float64     `json:"result"`
+	Server    string      `json:"server,omitempty"`
+	LatencyUs int64       `json:"latency_us"`
+	Error     *BatchError `json:"error,omitempty"`
+}
+
+// Enabled indica que há entrada para o modo não interativo
+func (b Batch) Enabled() bool {
+	return len(b.Exprs) > 0 || b.File != ""
+}
+
+// RunBatch calcula as expressões de -e e -f em ordem e escreve um resultado por
+// expressão. Retorna o código de saída; err indica uma falha antes do cálculo
+// (formato ou arquivo inválidos).
+func (r *REPL) RunBatch(b Batch) (int, error) {
+	if !slices.Contains(inputFormats, b.Input) {
+		return ExitUsage, fmt.Errorf("formato de entrada desconhecido: %s (use %s)", b.Input, strings.Join(inputFormats, ", "))
+	}
+	w, err := newResultWriter(b.Output, r.out)
+	if err != nil {
+		return ExitUsage, err
+	}
+
+	var file io.Reader
+	switch b.File {
+	case "":
+	case "-":
+		file = r.cfg.In
+	default:
+		f, err := os.Open(b.File)
+		if err != nil {
+			return ExitUsage, err
+		}
+		defer f.Close()
+		file = f
+	}
+
+	code := ExitOK
+	run := func(item BatchItem, err error) {
+		res := r.batchItem(item, err)
+		if err := w.write(res); err != nil || res.Error != nil {
+			code = ExitFailed
+		}
+	}
+	for _, expr := range b.Exprs {
+		run(parseTextItem(expr))
+	}
+	if file != nil {
+		if err := readItems(file, b.Input, run); err != nil {
+			w.flush()
+			return ExitUsage, err
+		}
+	}
+	return code, w.flush()
+}
+
+// batchItem calcula uma entrada; parseErr é o erro de leitura da própria entrada
+func (r *REPL) batchItem(item BatchItem, parseErr error) BatchResult {
+	res := BatchResult{ID: item.ID, Expression: item.Expression, Let: item.Let}
+	if parseErr != nil {
+		res.Error = &BatchError{Code: CodeClientError, Message: parseErr.Error()}
+		return res
+	}
+	if item.Let != "" && (!validName(item.Let) || item.Let == Ans) {
+		res.Error = &BatchError{Code: CodeClientError, Message: fmt.Sprintf("nome de variável inválido: %q", item.Let)}
+		return res
+	}
+
+	out, err := r.calculate(item.Expression, false)
+	if out == nil {
+		res.Error = &BatchError{Code: CodeClientError, Message: err.Error()}
+		return res
+	}
+	res.ExpressionID = out.ExpressionID
+	res.DurationMs = float64(out.Duration.Microseconds()) / 1000
+	res.Trace = batchTrace(out.Trace)
+	var calcErr *calcclient.Error
+	switch {
+	case errors.Is(err, calcclient.ErrTimeout):
+		res.Error = &BatchError{Code: CodeTimeout, Message: err.Error()}
+	case errors.As(err, &calcErr):
+		res.Error = &BatchError{Code: calcErr.Code, Message: calcErr.Message}
+	case err != nil:
+		res.Error = &BatchError{Code: CodeClientError, Message: err.Error()}
+	default:
+		result := out.Value
+		res.Result = &result
+		if item.Let != "" {
+			r.vars.Set(item.Let, result)
+		}
+	}
+	return res
+}
+
+// parseTextItem interpreta uma entrada de texto: uma expressão ou "let x = expr"
+func parseTextItem(line string) (BatchItem, error) {
+	if strings.HasPrefix(line, ":") {
+		return BatchItem{Expression: line}, fmt.Errorf("comandos ':' só estão disponíveis no modo interativo")
+	}
+	name, expr, isLet, err := parseLet(line)
+	switch {
+	case err != nil:
+		return BatchItem{Expression: line}, err
+	case isLet:
+		return BatchItem{Expression: expr, Let: name}, nil
+	}
+	return BatchItem{Expression: line}, nil
+}
+
+// readItems lê as entradas do arquivo; no texto, linhas vazias e iniciadas por '#'
+// são ignoradas e uma linha terminada em '\' (ou com parênteses abertos) continua na
+// seguinte, como no modo interativo
+func readItems(in io.Reader, format string, fn func(BatchItem, error)) error {
+	if format == FormatNDJSON {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var item BatchItem
+			if err := json.Unmarshal([]byte(line), &item); err != nil {
+				fn(BatchItem{Expression: line}, fmt.Errorf("JSON inválido: %w", err))
+				continue
+			}
+			fn(item, nil)
+		}
+		return scanner.Err()
+	}
+
+	reader := &scannerReader{scanner: bufio.NewScanner(in), out: io.Discard}
+	for {
+		input, err := readInput(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if input == "" || strings.HasPrefix(input, "#") {
+			continue
+		}
+		fn(parseTextItem(input))
+	}
+}
+
+func batchTrace(trace []calcclient.StepTrace) []BatchStep {
+	if len(trace) == 0 {
+		return nil
+	}
+	out := make([]BatchStep, len(trace))
+	for i, t := range trace {
+		out[i] = BatchStep{
+			StepID:    t.StepID,
+			Operation: t.Operation,
+			Operands:  t.Operands,
+			Result:    t.Result,
+			Server:    t.Server,
+			LatencyUs: t.Latency.Microseconds(),
+		}
+		if t.Err != nil {
+			out[i].Error = &BatchError{Code: t.Err.Code, Message: t.Err.Message}
+		}
+	}
+	return out
+}
+
+// resultWriter escreve os resultados no formato de saída escolhido. write retorna
+// erro se o resultado não pôde ser escrito como calculado (a linha traz o erro).
+type resultWriter interface {
+	write(BatchResult) error
+	flush() error
+}
+
+func newResultWriter(format string, out io.Writer) (resultWriter, error) {
+	switch format {
+	case FormatText:
+		return &textWriter{w: bufio.NewWriter(out)}, nil
+	case FormatJSON:
+		return &jsonWriter{w: bufio.NewWriter(out)}, nil
+	case FormatCSV:
+		w := csv.NewWriter(out)
+		w.Write([]string{"id", "expression", "expression_id", "result", "error_code", "error_message", "duration_ms"})
+		return &csvWriter{w: w}, nil
+	}
+	return nil, fmt.Errorf("formato de saída desconhecido: %s (use %s)", format, strings.Join(outputFormats, ", "))
+}
+
+// textWriter escreve "expr = resultado" (ou "nome = resultado" para let) e
+// "expr: ERRO [código] mensagem", com o trace indentado logo abaixo
+type textWriter struct {
+	w *bufio.Writer
+}
+
+func (t *textWriter) write(res BatchResult) error {
+	label := res.Expression
+	if res.Let != "" {
+		label = res.Let
+	}
+	if res.Error != nil {
+		fmt.Fprintf(t.w, "%s: ERRO [%s] %s\n", label, res.Error.Code, res.Error.Message)
+	} else {
+		fmt.Fprintf(t.w, "%s = %s\n", label, formatResult(*res.Result))
+	}
+	for _, step := range res.Trace {
+		if step.Error != nil {
+			fmt.Fprintf(t.w, "  %s: %s(%v) ERRO [%s] %s | %s | %v\n", step.StepID, step.Operation, step.Operands,
+				step.Error.Code, step.Error.Message, step.Server, time.Duration(step.LatencyUs)*time.Microsecond)
+			continue
+		}
+		fmt.Fprintf(t.w, "  %s: %s(%v) = %s | %s | %v\n", step.StepID, step.Operation, step.Operands,
+			formatResult(step.Result), step.Server, time.Duration(step.LatencyUs)*time.Microsecond)
+	}
+	// Uma linha por expressão assim que calculada, para uso em pipelines
+	t.w.Flush()
+	return nil
+}
+
+func (t *textWriter) flush() error {
+	return t.w.Flush()
+}
+
+type jsonWriter struct {
+	w *bufio.Writer
+}
+
+// write escreve o resultado em uma linha. JSON não representa ±Inf nem NaN (ex: um
+// resultado que estoura float64): a linha traz um CLIENT_ERROR no lugar do resultado.
+func (j *jsonWriter) write(res BatchResult) error {
+	body, err := json.Marshal(res)
+	if err != nil {
+		body, _ = json.Marshal(BatchResult{
+			ID:           res.ID,
+			Expression:   res.Expression,
+			Let:          res.Let,
+			ExpressionID: res.ExpressionID,
+			Error:        &BatchError{Code: CodeClientError, Message: fmt.Sprintf("resultado não representável em JSON: %v", err)},
+			DurationMs:   res.DurationMs,
+		})
+	}
+	j.w.Write(append(body, '\n'))
+	j.w.Flush()
+	return err
+}
+
+func (j *jsonWriter) flush() error {
+	return j.w.Flush()
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) write(res BatchResult) error {
+	var result, code, message string
+	if res.Result != nil {
+		result = formatResult(*res.Result)
+	}
+	if res.Error != nil {
+		code, message = res.Error.Code, res.Error.Message
+	}
+	expression := res.Expression
+	if res.Let != "" {
+		expression = "let " + res.Let + " = " + expression
+	}
+	c.w.Write([]string{res.ID, expression, res.ExpressionID, result, code, message,
+		strconv.FormatFloat(res.DurationMs, 'f', 3, 64)})
+	c.w.Flush()
+	return nil
+}
+
+func (c *csvWriter) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// formatResult escreve o resultado sem perder precisão (ex: 0.1, 1e+21)
+func formatResult(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
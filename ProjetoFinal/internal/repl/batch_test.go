@@ -0,0 +1,148 @@
+package repl
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// item é uma entrada lida por readItems, com o erro como texto para comparar
+type item struct {
+	BatchItem
+	err string
+}
+
+func collect(t *testing.T, input, format string) []item {
+	t.Helper()
+	var items []item
+	err := readItems(strings.NewReader(input), format, func(it BatchItem, err error) {
+		got := item{BatchItem: it}
+		if err != nil {
+			got.err = err.Error()
+		}
+		items = append(items, got)
+	})
+	if err != nil {
+		t.Fatalf("readItems: %v", err)
+	}
+	return items
+}
+
+func TestParseTextItem(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    BatchItem
+		wantErr bool
+	}{
+		{"1 + 2", BatchItem{Expression: "1 + 2"}, false},
+		{"let x = 2 * 3", BatchItem{Expression: "2 * 3", Let: "x"}, false},
+		{"let ans = 1", BatchItem{Expression: "let ans = 1"}, true},
+		{"let 1x = 1", BatchItem{Expression: "let 1x = 1"}, true},
+		{"let x =", BatchItem{Expression: "let x ="}, true},
+		{":vars", BatchItem{Expression: ":vars"}, true},
+		{"letx = 1", BatchItem{Expression: "letx = 1"}, false}, // não é definição: vai ao dispatcher
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := parseTextItem(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("erro = %v, esperado erro: %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseTextItem(%q) = %+v, esperado %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadItemsText(t *testing.T) {
+	input := strings.Join([]string{
+		"# comentário",
+		"",
+		"1 + 2",
+		"let x = (4 +",
+		"  3) * 2",
+		"10 / \\",
+		"5",
+		":help",
+		"   ",
+	}, "\n")
+	want := []item{
+		{BatchItem: BatchItem{Expression: "1 + 2"}},
+		{BatchItem: BatchItem{Expression: "(4 + 3) * 2", Let: "x"}},
+		{BatchItem: BatchItem{Expression: "10 / 5"}},
+		{BatchItem: BatchItem{Expression: ":help"}, err: "comandos ':' só estão disponíveis no modo interativo"},
+	}
+	if got := collect(t, input, FormatText); !reflect.DeepEqual(got, want) {
+		t.Errorf("itens = %+v\nesperado %+v", got, want)
+	}
+}
+
+func TestReadItemsTextIncompleteLastLine(t *testing.T) {
+	// Parênteses abertos no fim do arquivo descartam a entrada incompleta
+	got := collect(t, "1 + 1\n(2 + 3", FormatText)
+	if len(got) != 1 || got[0].Expression != "1 + 1" {
+		t.Errorf("itens = %+v", got)
+	}
+}
+
+func TestReadItemsNDJSON(t *testing.T) {
+	input := `{"id": "a", "expression": "x*2", "let": "y"}
+
+{"expression": "1+1"}
+{inválido}
+`
+	got := collect(t, input, FormatNDJSON)
+	if len(got) != 3 {
+		t.Fatalf("itens = %+v, esperado 3", got)
+	}
+	if want := (BatchItem{ID: "a", Expression: "x*2", Let: "y"}); got[0].BatchItem != want || got[0].err != "" {
+		t.Errorf("item 0 = %+v, esperado %+v", got[0], want)
+	}
+	if got[1].Expression != "1+1" || got[1].err != "" {
+		t.Errorf("item 1 = %+v", got[1])
+	}
+	if got[2].Expression != "{inválido}" || !strings.HasPrefix(got[2].err, "JSON inválido") {
+		t.Errorf("item 2 = %+v, esperado erro de JSON", got[2])
+	}
+}
+
+func TestJSONWriterNonFiniteResult(t *testing.T) {
+	for _, v := range []float64{math.Inf(1), math.Inf(-1), math.NaN()} {
+		var out bytes.Buffer
+		w, _ := newResultWriter(FormatJSON, &out)
+		res := BatchResult{ID: "a", Expression: "1e308 * 10", ExpressionID: "e1", Result: &v}
+		if err := w.write(res); err == nil {
+			t.Fatalf("write(%v) não retornou erro", v)
+		}
+
+		var got BatchResult
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatalf("linha %q não é JSON: %v", out.String(), err)
+		}
+		if got.ID != "a" || got.ExpressionID != "e1" || got.Result != nil || got.Error == nil || got.Error.Code != CodeClientError {
+			t.Errorf("linha = %s, esperado CLIENT_ERROR sem result", out.String())
+		}
+	}
+
+	// Valores finitos não são afetados
+	var out bytes.Buffer
+	w, _ := newResultWriter(FormatJSON, &out)
+	a, b := 0.1, 0.2
+	v := a + b
+	if err := w.write(BatchResult{Expression: "0.1 + 0.2", Result: &v}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"result":0.30000000000000004`) {
+		t.Errorf("linha = %s", out.String())
+	}
+}
+
+func TestNewResultWriterUnknownFormat(t *testing.T) {
+	if _, err := newResultWriter("xml", &bytes.Buffer{}); err == nil {
+		t.Error("formato de saída desconhecido aceito")
+	}
+}
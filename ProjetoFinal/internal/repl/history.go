@@ -0,0 +1,111 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// MaxHistory é o número de linhas mantidas no histórico
+const MaxHistory = 1000
+
+// DefaultHistoryFile retorna o arquivo padrão do histórico (~/.calc_history);
+// vazio se o diretório do usuário não for conhecido
+func DefaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".calc_history")
+}
+
+// History guarda as linhas digitadas e as grava em um arquivo, para que fiquem
+// disponíveis (setas para cima/baixo) nas próximas sessões. Implementa term.History.
+type History struct {
+	mu    sync.Mutex
+	lines []string // da mais antiga para a mais recente
+	path  string   // vazio mantém o histórico apenas em memória
+}
+
+// LoadHistory carrega o histórico do arquivo (inexistente é tratado como vazio)
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+	if path == "" {
+		return h, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			h.lines = append(h.lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if n := len(h.lines); n > MaxHistory {
+		h.lines = h.lines[n-MaxHistory:]
+		// Reescreve o arquivo apenas com as linhas mantidas
+		_ = h.rewrite()
+	}
+	return h, nil
+}
+
+// Add registra uma linha, ignorando repetições da linha anterior
+func (h *History) Add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n := len(h.lines); n > 0 && h.lines[n-1] == line {
+		return
+	}
+	h.lines = append(h.lines, line)
+	if n := len(h.lines); n > MaxHistory {
+		h.lines = h.lines[n-MaxHistory:]
+	}
+	h.append(line)
+}
+
+// Len retorna o número de linhas do histórico
+func (h *History) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.lines)
+}
+
+// At retorna a linha idx, contando a partir da mais recente (0)
+func (h *History) At(idx int) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lines[len(h.lines)-1-idx]
+}
+
+// append grava a linha no fim do arquivo; falhas de escrita não interrompem o REPL
+func (h *History) append(line string) {
+	if h.path == "" {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(line + "\n")
+}
+
+func (h *History) rewrite() error {
+	return os.WriteFile(h.path, []byte(strings.Join(h.lines, "\n")+"\n"), 0o600)
+}
@@ -0,0 +1,132 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Prompts da linha principal e das linhas de continuação
+const (
+	mainPrompt         = "> "
+	continuationPrompt = "... "
+)
+
+// lineReader lê uma linha de entrada exibindo o prompt
+type lineReader interface {
+	readLine(prompt string) (string, error)
+	// addHistory registra uma entrada completa no histórico
+	addHistory(input string)
+}
+
+// newLineReader usa edição de linha e histórico quando a entrada é um terminal e
+// leitura simples caso contrário (ex: entrada redirecionada de um arquivo)
+func newLineReader(in io.Reader, out io.Writer, history *History) lineReader {
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		t := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{f, out}, mainPrompt)
+		// O terminal apenas consulta o histórico: cada linha de uma entrada de várias
+		// linhas não deve virar uma entrada própria, então readInput registra a entrada completa
+		t.History = recallHistory{history}
+		return &terminalReader{fd: int(f.Fd()), term: t, history: history}
+	}
+	return &scannerReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+// terminalReader coloca o terminal em modo raw apenas durante a leitura, para que a
+// saída do cálculo (e os logs) sejam exibidos normalmente
+type terminalReader struct {
+	fd      int
+	term    *term.Terminal
+	history *History
+}
+
+func (r *terminalReader) readLine(prompt string) (string, error) {
+	state, err := term.MakeRaw(r.fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(r.fd, state)
+	if width, height, err := term.GetSize(r.fd); err == nil {
+		r.term.SetSize(width, height)
+	}
+	r.term.SetPrompt(prompt)
+	return r.term.ReadLine()
+}
+
+func (r *terminalReader) addHistory(input string) {
+	r.history.Add(input)
+}
+
+// recallHistory expõe o histórico ao terminal sem que ele registre cada linha lida
+type recallHistory struct {
+	*History
+}
+
+func (recallHistory) Add(string) {}
+
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *scannerReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// addHistory não registra nada: a entrada redirecionada não entra no histórico
+func (r *scannerReader) addHistory(string) {}
+
+// readInput lê uma entrada completa, que pode ocupar várias linhas: a linha continua
+// quando termina com '\' ou enquanto houver parênteses abertos. A entrada completa
+// entra no histórico como uma única linha.
+func readInput(r lineReader) (string, error) {
+	var parts []string
+	p := mainPrompt
+	for {
+		line, err := r.readLine(p)
+		if err != nil {
+			// EOF no meio de uma entrada de várias linhas descarta a entrada incompleta
+			return "", err
+		}
+		line = strings.TrimSpace(line)
+		explicit := strings.HasSuffix(line, `\`)
+		line = strings.TrimSpace(strings.TrimSuffix(line, `\`))
+		parts = append(parts, line)
+
+		input := strings.Join(parts, " ")
+		if !explicit && (input == "" || openParens(input) <= 0) {
+			input = strings.TrimSpace(input)
+			r.addHistory(input)
+			return input, nil
+		}
+		p = continuationPrompt
+	}
+}
+
+// openParens retorna quantos parênteses continuam abertos na entrada
+func openParens(s string) int {
+	depth := 0
+	for _, ch := range s {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+	}
+	return depth
+}
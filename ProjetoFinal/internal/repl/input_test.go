@@ -0,0 +1,134 @@
+package repl
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeReader devolve as linhas em ordem e registra os prompts e o histórico
+type fakeReader struct {
+	lines   []string
+	prompts []string
+	history *History
+}
+
+func (f *fakeReader) readLine(prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	if len(f.lines) == 0 {
+		return "", io.EOF
+	}
+	line := f.lines[0]
+	f.lines = f.lines[1:]
+	return line, nil
+}
+
+func (f *fakeReader) addHistory(input string) { f.history.Add(input) }
+
+func historyLines(h *History) []string {
+	var lines []string
+	for i := h.Len() - 1; i >= 0; i-- {
+		lines = append(lines, h.At(i))
+	}
+	return lines
+}
+
+func TestReadInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		lines       []string
+		want        string
+		wantPrompts int
+		wantErr     error
+	}{
+		{name: "uma linha", lines: []string{"  1 + 2  "}, want: "1 + 2", wantPrompts: 1},
+		{name: "barra invertida", lines: []string{`1 + \`, "2"}, want: "1 + 2", wantPrompts: 2},
+		{name: "parênteses abertos", lines: []string{"((4+3)", "*2)", "/5"}, want: "((4+3) *2)", wantPrompts: 2},
+		{name: "linha vazia", lines: []string{""}, want: "", wantPrompts: 1},
+		{name: "EOF na continuação", lines: []string{"(1 +"}, wantPrompts: 2, wantErr: io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &fakeReader{lines: tt.lines, history: &History{}}
+			got, err := readInput(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("erro = %v, esperado %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readInput() = %q, esperado %q", got, tt.want)
+			}
+			if len(r.prompts) != tt.wantPrompts || r.prompts[0] != mainPrompt {
+				t.Errorf("prompts = %q", r.prompts)
+			}
+			for _, p := range r.prompts[1:] {
+				if p != continuationPrompt {
+					t.Errorf("prompt de continuação = %q, esperado %q", p, continuationPrompt)
+				}
+			}
+		})
+	}
+}
+
+func TestReadInputHistory(t *testing.T) {
+	// Uma entrada de várias linhas entra no histórico como uma só linha
+	r := &fakeReader{lines: []string{"(1 +", "2)", "", "3*3", "3*3"}, history: &History{}}
+	for range 4 {
+		if _, err := readInput(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := historyLines(r.history), []string{"(1 + 2)", "3*3"}; !slices.Equal(got, want) {
+		t.Errorf("histórico = %q, esperado %q", got, want)
+	}
+	// Incompleta no EOF: não entra no histórico
+	r.lines = []string{"(4 *"}
+	if _, err := readInput(r); !errors.Is(err, io.EOF) {
+		t.Fatalf("erro = %v, esperado EOF", err)
+	}
+	if r.history.Len() != 2 {
+		t.Errorf("entrada incompleta registrada: %q", historyLines(r.history))
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Add("1+1")
+	h.Add("  ")
+	h.Add("2*2")
+	// O terminal apenas consulta o histórico
+	recallHistory{h}.Add("fragmento")
+
+	reloaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := historyLines(reloaded), []string{"1+1", "2*2"}; !slices.Equal(got, want) {
+		t.Errorf("histórico recarregado = %q, esperado %q", got, want)
+	}
+	if reloaded.At(0) != "2*2" {
+		t.Errorf("At(0) = %q, esperado a mais recente", reloaded.At(0))
+	}
+
+	// Acima de MaxHistory, mantém e regrava só as mais recentes
+	lines := make([]string, MaxHistory+5)
+	for i := range lines {
+		lines[i] = strings.Repeat("1+", i) + "1"
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if h, err = LoadHistory(path); err != nil || h.Len() != MaxHistory || h.At(MaxHistory-1) != lines[5] {
+		t.Fatalf("LoadHistory = %d linhas, erro %v", h.Len(), err)
+	}
+	if data, _ := os.ReadFile(path); strings.Count(string(data), "\n") != MaxHistory {
+		t.Errorf("arquivo regravado com %d linhas, esperado %d", strings.Count(string(data), "\n"), MaxHistory)
+	}
+}
@@ -0,0 +1,414 @@
+package repl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/pkg/calcclient"
+)
+
+// Transportes suportados pelo REPL
+const (
+	TransportGRPC     = "grpc"
+	TransportRabbitMQ = "rabbitmq"
+)
+
+// Transports lista os transportes aceitos por -transport e :transport
+var Transports = []string{TransportGRPC, TransportRabbitMQ}
+
+// Dialer conecta ao dispatcher pelo transporte informado
+type Dialer func(transport string) (calcclient.Client, error)
+
+// Config configura uma sessão do REPL
+type Config struct {
+	Transport   string // transporte inicial
+	Dial        Dialer
+	Trace       bool
+	Progress    bool // gRPC: exibe o progresso dos steps
+	Async       bool // gRPC: envia com Submit e aguarda pelo ticket
+	Timeout     time.Duration
+	Priority    int
+	HistoryFile string // vazio mantém o histórico apenas em memória
+	Logger      *slog.Logger
+	In          io.Reader // padrão: os.Stdin
+	Out         io.Writer // padrão: os.Stdout
+}
+
+// REPL lê expressões, comandos e definições de variáveis e envia as expressões ao
+// dispatcher pelo transporte atual
+type REPL struct {
+	cfg       Config
+	transport string
+	backend   calcclient.Client
+	vars      *Variables
+	in        lineReader
+	out       io.Writer
+	logger    *slog.Logger
+}
+
+// New conecta ao transporte inicial e prepara a sessão
+func New(cfg Config) (*REPL, error) {
+	if cfg.In == nil {
+		cfg.In = os.Stdin
+	}
+	if cfg.Out == nil {
+		cfg.Out = os.Stdout
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	history, err := LoadHistory(cfg.HistoryFile)
+	if err != nil {
+		cfg.Logger.Warn("Erro ao carregar histórico", slog.String("file", cfg.HistoryFile), logging.Err(err))
+		history, _ = LoadHistory("")
+	}
+	backend, err := cfg.Dial(cfg.Transport)
+	if err != nil {
+		return nil, err
+	}
+	return &REPL{
+		cfg:       cfg,
+		transport: cfg.Transport,
+		backend:   backend,
+		vars:      NewVariables(),
+		in:        newLineReader(cfg.In, cfg.Out, history),
+		out:       cfg.Out,
+		logger:    cfg.Logger,
+	}, nil
+}
+
+// Close encerra a conexão do transporte atual
+func (r *REPL) Close() error {
+	return r.backend.Close()
+}
+
+// Run executa o loop de interação até "sair" ou o fim da entrada
+func (r *REPL) Run() error {
+	r.printf("\nDigite uma expressão matemática (ou 'sair' para encerrar, ':help' para os comandos):\n")
+	r.printf("Exemplos: ((4+3)*2)/5, 10+20*3, let x = (15-5)/2, x*ans\n")
+
+	for {
+		r.printf("\n")
+		input, err := readInput(r.in)
+		if errors.Is(err, io.EOF) {
+			r.printf("\n")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if input == "" {
+			continue
+		}
+		if !r.execute(input) {
+			r.printf("Encerrando cliente...\n")
+			return nil
+		}
+	}
+}
+
+// execute trata uma entrada; retorna false para encerrar a sessão
+func (r *REPL) execute(input string) bool {
+	lower := strings.ToLower(input)
+	if lower == "sair" || lower == "exit" || lower == ":quit" {
+		return false
+	}
+
+	if strings.HasPrefix(input, ":") {
+		r.command(input)
+		return true
+	}
+
+	// Consulta o resultado de uma expressão enviada antes (inclusive por outra sessão)
+	if ticket, ok := strings.CutPrefix(input, "resultado "); ok {
+		r.printResult(strings.TrimSpace(ticket))
+		return true
+	}
+
+	// Cancela uma expressão em andamento (ex: enviada com -async por outra sessão)
+	if ticket, ok := strings.CutPrefix(input, "cancelar "); ok {
+		r.cancel(strings.TrimSpace(ticket))
+		return true
+	}
+
+	name, expr, isLet, err := parseLet(input)
+	if err != nil {
+		r.printf("❌ %v\n", err)
+		return true
+	}
+	if !isLet {
+		expr = input
+	}
+	if value, ok := r.evaluate(expr); ok && isLet {
+		r.vars.Set(name, value)
+		r.printf("📌 %s = %v\n", name, value)
+	}
+	return true
+}
+
+// command trata os comandos iniciados por ':'
+func (r *REPL) command(input string) {
+	name, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case ":help":
+		r.printf("Comandos:\n")
+		for _, c := range [][2]string{
+			{"let <nome> = <expr>", "define uma variável, usada pelo nome nas expressões seguintes"},
+			{Ans, "resultado da expressão anterior"},
+			{"resultado <ticket>", "consulta uma expressão enviada antes"},
+			{"cancelar <ticket>", "cancela uma expressão em andamento"},
+			{":vars", "lista as variáveis definidas"},
+			{":trace [on|off]", "exibe o trace de execução de cada step"},
+			{":timeout [duração]", "prazo de cada expressão (ex: 5s, 1m)"},
+			{":transport [" + strings.Join(Transports, "|") + "]", "troca o transporte usado"},
+			{"sair, exit, :quit", "encerra o cliente"},
+		} {
+			r.printf("   %-28s %s\n", c[0], c[1])
+		}
+		r.printf("Termine a linha com '\\' (ou deixe parênteses abertos) para continuar na linha seguinte.\n")
+
+	case ":vars":
+		names := r.vars.Names()
+		if len(names) == 0 {
+			r.printf("Nenhuma variável definida\n")
+		}
+		for _, n := range names {
+			value, _ := r.vars.Get(n)
+			r.printf("   %s = %v\n", n, value)
+		}
+
+	case ":trace":
+		switch strings.ToLower(arg) {
+		case "":
+			r.cfg.Trace = !r.cfg.Trace
+		case "on":
+			r.cfg.Trace = true
+		case "off":
+			r.cfg.Trace = false
+		default:
+			r.printf("❌ uso: :trace [on|off]\n")
+			return
+		}
+		r.printf("🔎 Trace %s\n", onOff(r.cfg.Trace))
+
+	case ":timeout":
+		if arg != "" {
+			timeout, err := time.ParseDuration(arg)
+			if err != nil || timeout <= 0 {
+				r.printf("❌ Duração inválida: %s (ex: 5s, 1m)\n", arg)
+				return
+			}
+			r.cfg.Timeout = timeout
+		}
+		r.printf("⏱️  Timeout: %v\n", r.cfg.Timeout)
+
+	case ":transport":
+		if arg != "" && arg != r.transport {
+			r.switchTransport(arg)
+			return
+		}
+		r.printf("🔌 Transporte: %s\n", r.transport)
+
+	default:
+		r.printf("❌ Comando desconhecido: %s (':help' lista os comandos)\n", name)
+	}
+}
+
+// switchTransport conecta pelo novo transporte e só então fecha o anterior
+func (r *REPL) switchTransport(transport string) {
+	backend, err := r.cfg.Dial(transport)
+	if err != nil {
+		r.printf("❌ Erro ao conectar via %s: %v\n", transport, err)
+		return
+	}
+	if err := r.backend.Close(); err != nil {
+		r.logger.Warn("Erro ao fechar transporte", slog.String("transport", r.transport), logging.Err(err))
+	}
+	r.backend, r.transport = backend, transport
+	r.logger.Info("Transporte alterado", slog.String("transport", transport))
+	r.printf("🔌 Transporte: %s\n", transport)
+}
+
+// calculate substitui as variáveis e envia a expressão pelo transporte atual; em caso
+// de sucesso o resultado passa a ser o valor de ans. Um erro da expressão vem como
+// *calcclient.Error junto com o Result; res é nil se as variáveis não puderem ser
+// substituídas. interactive exibe o ticket e o progresso dos steps.
+func (r *REPL) calculate(input string, interactive bool) (*calcclient.Result, error) {
+	expr, err := r.vars.Substitute(input)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []calcclient.Option{
+		calcclient.WithTimeout(r.cfg.Timeout),
+		calcclient.WithPriority(r.cfg.Priority),
+	}
+	if r.cfg.Trace {
+		opts = append(opts, calcclient.WithTrace())
+	}
+	if interactive && r.cfg.Progress {
+		opts = append(opts, calcclient.WithProgress(r.printProgress))
+	}
+	if interactive && r.cfg.Async {
+		opts = append(opts, calcclient.WithSubmit(func(ticket, state string) {
+			r.printf("🎫 Ticket: %s (%s)\n", ticket, state)
+		}))
+	}
+
+	ctx := context.Background()
+	r.logger.InfoContext(ctx, "Enviando expressão",
+		slog.String("expression", expr),
+		slog.String("transport", r.transport))
+
+	res, err := r.backend.Calculate(ctx, expr, opts...)
+	var calcErr *calcclient.Error
+	switch {
+	case errors.As(err, &calcErr):
+		r.logger.InfoContext(ctx, "Erro retornado",
+			slog.String(logging.KeyExpressionID, res.ExpressionID),
+			slog.String("code", calcErr.Code),
+			slog.String("message", calcErr.Message),
+			slog.Int("attempts", res.Attempts))
+	case err != nil:
+		r.logger.WarnContext(ctx, "Erro ao calcular",
+			slog.String(logging.KeyExpressionID, res.ExpressionID),
+			slog.Int("attempts", res.Attempts),
+			logging.Err(err))
+	default:
+		r.logger.InfoContext(ctx, "Resultado",
+			slog.String(logging.KeyExpressionID, res.ExpressionID),
+			slog.Float64("result", res.Value),
+			slog.Duration("duration", res.Duration),
+			slog.Int("attempts", res.Attempts))
+		r.vars.Set(Ans, res.Value)
+	}
+	return res, err
+}
+
+// evaluate envia a expressão e exibe o resultado
+func (r *REPL) evaluate(input string) (float64, bool) {
+	res, err := r.calculate(input, true)
+	var calcErr *calcclient.Error
+	switch {
+	case res == nil:
+		r.printf("❌ %v\n", err)
+		return 0, false
+	case errors.Is(err, calcclient.ErrTimeout):
+		r.printf("❌ Timeout ao aguardar resposta (consulte depois com 'resultado %s')\n", res.ExpressionID)
+		return 0, false
+	case errors.As(err, &calcErr):
+		r.printf("❌ Erro: [%s] %s\n", calcErr.Code, calcErr.Message)
+		if calcErr.RetryAfter > 0 {
+			r.printf("⏳ Tente novamente em %v\n", calcErr.RetryAfter)
+		}
+		r.printTrace(res.Trace)
+		return 0, false
+	case err != nil:
+		r.printf("❌ Erro ao calcular: %v\n", err)
+		return 0, false
+	}
+
+	r.printf("✅ Resultado: %s = %f\n", input, res.Value)
+	r.printf("⏱️  Tempo de execução: %v\n", res.Duration)
+	if res.Attempts > 1 {
+		r.printf("🔁 Tentativas: %d\n", res.Attempts)
+	}
+	r.printTrace(res.Trace)
+	return res.Value, true
+}
+
+// printResult consulta e exibe o estado de uma expressão pelo ticket
+func (r *REPL) printResult(ticket string) {
+	// Margem para a resposta chegar depois do timeout da consulta
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.Timeout+2*time.Second)
+	defer cancel()
+
+	st, err := r.backend.Result(ctx, ticket, r.cfg.Timeout)
+	switch {
+	case err != nil:
+		r.printf("❌ Erro ao consultar: %v\n", err)
+		return
+	case st.Result == nil:
+		r.printf("⏳ %s: %s\n", st.Ticket, st.State)
+		return
+	case st.Err != nil:
+		r.printf("❌ %s: [%s] %s\n", st.Ticket, st.Err.Code, st.Err.Message)
+	default:
+		r.printf("✅ %s: %f\n", st.Ticket, st.Result.Value)
+	}
+	r.printTrace(st.Result.Trace)
+}
+
+// cancel cancela uma expressão em andamento pelo ticket
+func (r *REPL) cancel(ticket string) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.Timeout)
+	defer cancel()
+
+	st, err := r.backend.Cancel(ctx, ticket)
+	switch {
+	case err != nil:
+		r.printf("❌ Erro ao cancelar: %v\n", err)
+	case st.Cancelled:
+		r.printf("🛑 %s cancelada\n", ticket)
+	case st.State == "":
+		// O transporte só encaminha o pedido; a resposta CANCELLED chega a quem enviou a expressão
+		r.printf("🛑 Pedido de cancelamento enviado\n")
+	default:
+		r.printf("ℹ️  %s já havia terminado (%s)\n", ticket, st.State)
+	}
+}
+
+// printProgress exibe cada step despachado e concluído
+func (r *REPL) printProgress(step calcclient.Step) {
+	prefix := fmt.Sprintf("   [%d/%d] %s(%v)", step.Index+1, step.Total, step.Operation, step.Operands)
+	switch step.State {
+	case calcclient.StepDispatched:
+		r.printf("%s ...\n", prefix)
+	case calcclient.StepCompleted:
+		r.printf("%s = %f\n", prefix, step.Result)
+	case calcclient.StepFailed:
+		code := ""
+		if step.Err != nil {
+			code = step.Err.Code
+		}
+		r.printf("%s → ❌ [%s]\n", prefix, code)
+	}
+}
+
+// printTrace exibe o trace de execução retornado pelo dispatcher
+func (r *REPL) printTrace(trace []calcclient.StepTrace) {
+	if len(trace) == 0 {
+		return
+	}
+
+	r.printf("🔎 Trace de execução:\n")
+	for _, step := range trace {
+		if step.Err != nil {
+			r.printf("   %s: %s(%v) → ❌ [%s] %s | %s | %v\n", step.StepID, step.Operation, step.Operands,
+				step.Err.Code, step.Err.Message, step.Server, step.Latency)
+			continue
+		}
+		r.printf("   %s: %s(%v) = %f | %s | %v\n", step.StepID, step.Operation, step.Operands,
+			step.Result, step.Server, step.Latency)
+	}
+}
+
+func (r *REPL) printf(format string, args ...any) {
+	fmt.Fprintf(r.out, format, args...)
+}
+
+func onOff(b bool) string {
+	if b {
+		return "ativado"
+	}
+	return "desativado"
+}
@@ -0,0 +1,128 @@
+package repl
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ans é a variável com o resultado da expressão anterior
+const Ans = "ans"
+
+// Variables guarda os valores definidos com "let x = expr" e o último resultado (ans).
+// Os nomes são substituídos pelos valores antes do envio, já que os dispatchers só
+// conhecem números, operadores e parênteses.
+type Variables struct {
+	values map[string]float64
+}
+
+// NewVariables cria o conjunto vazio de variáveis da sessão
+func NewVariables() *Variables {
+	return &Variables{values: make(map[string]float64)}
+}
+
+// Set define o valor de uma variável
+func (v *Variables) Set(name string, value float64) {
+	v.values[name] = value
+}
+
+// Get retorna o valor de uma variável
+func (v *Variables) Get(name string) (float64, bool) {
+	value, ok := v.values[name]
+	return value, ok
+}
+
+// Names retorna os nomes definidos em ordem alfabética
+func (v *Variables) Names() []string {
+	names := make([]string, 0, len(v.values))
+	for name := range v.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Substitute troca cada nome da expressão pelo seu valor entre parênteses, para que
+// valores negativos e nomes ao lado de números não mudem o sentido da expressão
+func (v *Variables) Substitute(expr string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(expr); {
+		if !isIdentStart(expr[i]) {
+			b.WriteByte(expr[i])
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(expr) && isIdentPart(expr[j]) {
+			j++
+		}
+		name := expr[i:j]
+		value, ok := v.values[name]
+		if !ok {
+			if name == Ans {
+				return "", fmt.Errorf("nenhum resultado anterior para '%s'", Ans)
+			}
+			return "", fmt.Errorf("variável não definida: %s", name)
+		}
+		literal, err := formatValue(value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		b.WriteString("(" + literal + ")")
+		i = j
+	}
+	return b.String(), nil
+}
+
+// parseLet separa "let nome = expressão"; ok indica que a entrada é uma definição
+func parseLet(input string) (name, expr string, ok bool, err error) {
+	rest, ok := strings.CutPrefix(input, "let ")
+	if !ok {
+		return "", "", false, nil
+	}
+	name, expr, found := strings.Cut(rest, "=")
+	name, expr = strings.TrimSpace(name), strings.TrimSpace(expr)
+	switch {
+	case !found || expr == "":
+		return "", "", true, fmt.Errorf("uso: let <nome> = <expressão>")
+	case !validName(name):
+		return "", "", true, fmt.Errorf("nome de variável inválido: %q", name)
+	case name == Ans:
+		return "", "", true, fmt.Errorf("'%s' é reservada para o resultado anterior", Ans)
+	}
+	return name, expr, true, nil
+}
+
+// formatValue escreve o valor como literal aceito pelo parser (sem expoente nem
+// sinal: o parser não tem menos unário, então negativos viram 0-x)
+func formatValue(value float64) (string, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return "", fmt.Errorf("valor %v não pode ser usado em uma expressão", value)
+	}
+	if value < 0 {
+		return "0-" + strconv.FormatFloat(-value, 'f', -1, 64), nil
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64), nil
+}
+
+func validName(name string) bool {
+	if name == "" || !isIdentStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isIdentPart(name[i]) {
+			return false
+		}
+	}
+	return name != "let"
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+func isIdentPart(ch byte) bool {
+	return isIdentStart(ch) || ch >= '0' && ch <= '9'
+}
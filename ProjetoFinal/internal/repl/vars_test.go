@@ -0,0 +1,86 @@
+package repl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value   float64
+		want    string
+		wantErr bool
+	}{
+		{0, "0", false},
+		{42, "42", false},
+		{0.1, "0.1", false},
+		{-2.5, "0-2.5", false},
+		{1e21, "1000000000000000000000", false}, // sem expoente
+		{1e-7, "0.0000001", false},
+		{math.Inf(1), "", true},
+		{math.Inf(-1), "", true},
+		{math.NaN(), "", true},
+	}
+	for _, tt := range tests {
+		got, err := formatValue(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("formatValue(%v): erro = %v, esperado erro: %v", tt.value, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("formatValue(%v) = %q, esperado %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	v := NewVariables()
+	v.Set("x", 3)
+	v.Set("neg", -2)
+	v.Set("x2", 0.5)
+	v.Set(Ans, 10)
+	v.Set("inf", math.Inf(1))
+
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{"1 + 2", "1 + 2", ""},
+		{"x * 2", "(3) * 2", ""},
+		{"2x", "2(3)", ""},
+		{"x2 + x", "(0.5) + (3)", ""},
+		{"neg * neg", "(0-2) * (0-2)", ""},
+		{"ans / 4", "(10) / 4", ""},
+		{"_y + 1", "", "variável não definida: _y"},
+		{"inf + 1", "", "inf: valor +Inf não pode ser usado em uma expressão"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := v.Substitute(tt.expr)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("erro = %v, esperado %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Substitute(%q) = %q, %v; esperado %q", tt.expr, got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewVariables().Substitute("ans + 1"); err == nil || err.Error() != "nenhum resultado anterior para 'ans'" {
+		t.Errorf("ans sem resultado anterior: erro = %v", err)
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"x": true, "_tmp": true, "valor2": true,
+		"": false, "2x": false, "a-b": false, "let": false, "ç": false,
+	} {
+		if got := validName(name); got != want {
+			t.Errorf("validName(%q) = %v, esperado %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package results
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/logging"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/store"
+)
+
+// Status é o estado de uma expressão no result store
+type Status string
+
+const (
+	StatusQueued  Status = "QUEUED"  // recebida, aguardando execução
+	StatusRunning Status = "RUNNING" // steps em execução
+	StatusDone    Status = "DONE"    // concluída com sucesso
+	StatusFailed  Status = "FAILED"  // concluída com erro
+)
+
+// Terminal indica que a expressão terminou (DONE ou FAILED)
+func (s Status) Terminal() bool {
+	return s == StatusDone || s == StatusFailed
+}
+
+// CodeInterrupted indica uma expressão perdida em um reinício do dispatcher
+const CodeInterrupted = "INTERRUPTED"
+
+// CodeDuplicateExpressionID indica um ExpressionID já usado, dentro da janela de retenção,
+// por outro cliente ou por outra expressão ainda em andamento
+const CodeDuplicateExpressionID = "DUPLICATE_EXPRESSION_ID"
+
+// ErrDuplicateExpressionID é retornado por Begin para um ExpressionID em conflito
+var ErrDuplicateExpressionID = errors.New("ExpressionID já usado por outra expressão")
+
+// Bucket do estado persistido dos resultados
+const Bucket = "expression_results"
+
+// Record é o estado de uma expressão, identificada pelo ExpressionID (o ticket)
+type Record struct {
+	ExpressionID string           `json:"expression_id"`
+	Owner        string           `json:"owner"`                // principal que enviou a expressão
+	Expression   string           `json:"expression,omitempty"` // identifica repetições do mesmo pedido
+	Status       Status           `json:"status"`
+	Result       float64          `json:"result,omitempty"`
+	Error        *core.ErrorInfo  `json:"error,omitempty"`
+	Trace        []core.StepTrace `json:"trace,omitempty"`
+	SubmittedAt  time.Time        `json:"submitted_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+// Response retorna a resposta da expressão (válida apenas em estado terminal)
+func (r Record) Response() core.ExpressionResponse {
+	return core.ExpressionResponse{ExpressionID: r.ExpressionID, Result: r.Result, Error: r.Error, Trace: r.Trace}
+}
+
+// Retryable indica uma falha transitória, que não deve ser devolvida a uma repetição:
+// rejeições com Retry-After, expressões interrompidas ou canceladas e erros de execução ou internos
+func (r Record) Retryable() bool {
+	if r.Status != StatusFailed || r.Error == nil {
+		return false
+	}
+	switch r.Error.Code {
+	case CodeInterrupted, core.CodeCancelled, core.CodeExecutionError, "INTERNAL_ERROR":
+		return true
+	}
+	return r.Error.RetryAfterMs > 0
+}
+
+// Store guarda o estado das expressões de um dispatcher. Resultados terminais são
+// mantidos pelo período de retenção; com db, sobrevivem a reinícios.
+type Store struct {
+	mu        sync.Mutex
+	records   map[string]*Record
+	done      map[string]chan struct{} // fechado quando a expressão termina
+	retention time.Duration
+	db        *store.Store
+	dbMu      sync.Mutex // serializa as gravações em db, feitas fora de mu
+	logger    *slog.Logger
+}
+
+// NewStore cria o result store, recarregando os registros persistidos em db (opcional)
+func NewStore(retention time.Duration, db *store.Store) (*Store, error) {
+	s := &Store{
+		records:   make(map[string]*Record),
+		done:      make(map[string]chan struct{}),
+		retention: retention,
+		db:        db,
+		logger:    logging.Component("results"),
+	}
+	if db == nil {
+		return s, nil
+	}
+	err := db.ForEach(Bucket, func(key string, value []byte) error {
+		var r Record
+		if err := json.Unmarshal(value, &r); err != nil {
+			return err
+		}
+		s.records[key] = &r
+		return nil
+	})
+	return s, err
+}
+
+// Submit registra uma expressão recebida (QUEUED), substituindo um registro anterior
+func (s *Store) Submit(expressionID, owner, expression string) Record {
+	s.mu.Lock()
+	r := s.submit(expressionID, owner, expression)
+	s.mu.Unlock()
+	s.persist(expressionID)
+	return r
+}
+
+// submit implementa Submit (chamado com mu travado; quem chama grava o registro com persist)
+func (s *Store) submit(expressionID, owner, expression string) Record {
+	if expressionID == "" {
+		return Record{Owner: owner, Expression: expression, Status: StatusQueued}
+	}
+	now := time.Now()
+	r := &Record{ExpressionID: expressionID, Owner: owner, Expression: expression, Status: StatusQueued, SubmittedAt: now, UpdatedAt: now}
+	if ch, ok := s.done[expressionID]; ok {
+		close(ch)
+		delete(s.done, expressionID)
+	}
+	s.records[expressionID] = r
+	return *r
+}
+
+// Begin registra a expressão como Submit, exceto quando ela repete (mesmo ExpressionID,
+// dono e expressão) uma ainda em andamento ou concluída dentro da retenção: nesse caso
+// retorna o registro existente com duplicate=true, para que quem repetiu aguarde a
+// execução original ou receba a resposta guardada. Falhas transitórias (ver Retryable)
+// são executadas de novo.
+func (s *Store) Begin(expressionID, owner, expression string) (r Record, duplicate bool, err error) {
+	s.mu.Lock()
+	if prev, ok := s.records[expressionID]; ok && expressionID != "" {
+		same := prev.Expression == "" || prev.Expression == expression
+		switch {
+		case prev.Owner != owner, !same && !prev.Status.Terminal():
+			s.mu.Unlock()
+			return Record{}, false, ErrDuplicateExpressionID
+		case same && (!prev.Status.Terminal() || !prev.Retryable()):
+			r = *prev
+			s.mu.Unlock()
+			return r, true, nil
+		}
+	}
+	r = s.submit(expressionID, owner, expression)
+	s.mu.Unlock()
+	s.persist(expressionID)
+	return r, false, nil
+}
+
+// Running marca a expressão como em execução
+func (s *Store) Running(expressionID string) {
+	s.mu.Lock()
+	r, ok := s.records[expressionID]
+	if !ok || r.Status != StatusQueued {
+		s.mu.Unlock()
+		return
+	}
+	r.Status = StatusRunning
+	r.UpdatedAt = time.Now()
+	s.mu.Unlock()
+	s.persist(expressionID)
+}
+
+// Finish grava a resposta da expressão (DONE ou FAILED) e libera quem a aguarda
+func (s *Store) Finish(resp core.ExpressionResponse) {
+	s.mu.Lock()
+	r, ok := s.records[resp.ExpressionID]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	r.Status = StatusDone
+	if resp.Error != nil {
+		r.Status = StatusFailed
+	}
+	r.Result, r.Error, r.Trace = resp.Result, resp.Error, resp.Trace
+	r.UpdatedAt = time.Now()
+
+	if ch, ok := s.done[r.ExpressionID]; ok {
+		close(ch)
+		delete(s.done, r.ExpressionID)
+	}
+	s.mu.Unlock()
+	s.persist(resp.ExpressionID)
+}
+
+// Get retorna o estado atual da expressão
+func (s *Store) Get(expressionID string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[expressionID]
+	if !ok {
+		return Record{}, false
+	}
+	return *r, true
+}
+
+// Wait aguarda a expressão terminar ou o contexto expirar e retorna o estado atual
+func (s *Store) Wait(ctx context.Context, expressionID string) (Record, bool) {
+	s.mu.Lock()
+	r, ok := s.records[expressionID]
+	if !ok || r.Status.Terminal() {
+		defer s.mu.Unlock()
+		if !ok {
+			return Record{}, false
+		}
+		return *r, true
+	}
+	ch, waiting := s.done[expressionID]
+	if !waiting {
+		ch = make(chan struct{})
+		s.done[expressionID] = ch
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ch:
+	case <-ctx.Done():
+	}
+	return s.Get(expressionID)
+}
+
+// FailUnfinished marca como FAILED (INTERRUPTED) as expressões não terminadas que
+// não serão retomadas, ex: após um reinício do dispatcher
+func (s *Store) FailUnfinished(resumed func(expressionID string) bool) int {
+	s.mu.Lock()
+	var lost []string
+	for id, r := range s.records {
+		if !r.Status.Terminal() && (resumed == nil || !resumed(id)) {
+			lost = append(lost, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range lost {
+		s.Finish(core.ExpressionResponse{
+			ExpressionID: id,
+			Error:        &core.ErrorInfo{Code: CodeInterrupted, Message: "Expressão interrompida por reinício do dispatcher"},
+		})
+	}
+	return len(lost)
+}
+
+// Prune remove os resultados terminais mais antigos que a retenção
+func (s *Store) Prune(now time.Time) int {
+	s.mu.Lock()
+	var removed []string
+	for id, r := range s.records {
+		if r.Status.Terminal() && now.Sub(r.UpdatedAt) > s.retention {
+			delete(s.records, id)
+			removed = append(removed, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range removed {
+		s.persist(id)
+	}
+	return len(removed)
+}
+
+// PruneEvery executa Prune periodicamente até o contexto terminar
+func (s *Store) PruneEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.Prune(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// persist grava em db o estado atual da expressão, ou a remove se não estiver mais no
+// store. Chamado sem mu travado: as gravações são serializadas por dbMu e cada uma lê o
+// registro mais recente, então uma gravação atrasada nunca sobrescreve um estado posterior.
+func (s *Store) persist(expressionID string) {
+	if s.db == nil || expressionID == "" {
+		return
+	}
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+
+	s.mu.Lock()
+	r, ok := s.records[expressionID]
+	var rec Record
+	if ok {
+		rec = *r
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		if err := s.db.Delete(Bucket, expressionID); err != nil {
+			s.logger.Error("Erro ao remover resultado", slog.String(logging.KeyExpressionID, expressionID), logging.Err(err))
+		}
+		return
+	}
+	if err := s.db.Put(Bucket, expressionID, rec); err != nil {
+		s.logger.Error("Erro ao gravar resultado", slog.String(logging.KeyExpressionID, expressionID), logging.Err(err))
+	}
+}
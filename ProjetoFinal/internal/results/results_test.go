@@ -0,0 +1,269 @@
+package results
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/store"
+)
+
+func newTestStore(t *testing.T, db *store.Store) *Store {
+	t.Helper()
+	s, err := NewStore(time.Minute, db)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return s
+}
+
+func failure(code string, retryAfterMs int64) *core.ErrorInfo {
+	return &core.ErrorInfo{Code: code, Message: code, RetryAfterMs: retryAfterMs}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		rec  Record
+		want bool
+	}{
+		{"concluída", Record{Status: StatusDone}, false},
+		{"em andamento", Record{Status: StatusRunning}, false},
+		{"falha sem erro", Record{Status: StatusFailed}, false},
+		{"divisão por zero", Record{Status: StatusFailed, Error: failure("DIV_BY_ZERO", 0)}, false},
+		{"interrompida", Record{Status: StatusFailed, Error: failure(CodeInterrupted, 0)}, true},
+		{"cancelada", Record{Status: StatusFailed, Error: failure(core.CodeCancelled, 0)}, true},
+		{"erro de execução", Record{Status: StatusFailed, Error: failure(core.CodeExecutionError, 0)}, true},
+		{"erro interno", Record{Status: StatusFailed, Error: failure("INTERNAL_ERROR", 0)}, true},
+		{"com retry_after", Record{Status: StatusFailed, Error: failure("RATE_LIMITED", 100)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rec.Retryable(); got != tt.want {
+				t.Errorf("Retryable() = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBegin(t *testing.T) {
+	tests := []struct {
+		name          string
+		prev          *core.ErrorInfo // resposta da execução anterior; nil com finish=false a deixa em andamento
+		finish        bool
+		owner, expr   string
+		wantDuplicate bool
+		wantErr       error
+	}{
+		{name: "repetição em andamento", owner: "alice", expr: "1+1", wantDuplicate: true},
+		{name: "repetição concluída", finish: true, owner: "alice", expr: "1+1", wantDuplicate: true},
+		{name: "repetição de erro definitivo", finish: true, prev: failure("DIV_BY_ZERO", 0), owner: "alice", expr: "1+1", wantDuplicate: true},
+		{name: "repetição de falha transitória", finish: true, prev: failure(CodeInterrupted, 0), owner: "alice", expr: "1+1"},
+		{name: "outro dono", owner: "bob", expr: "1+1", wantErr: ErrDuplicateExpressionID},
+		{name: "outro dono após concluir", finish: true, owner: "bob", expr: "1+1", wantErr: ErrDuplicateExpressionID},
+		{name: "outra expressão em andamento", owner: "alice", expr: "2+2", wantErr: ErrDuplicateExpressionID},
+		{name: "outra expressão após concluir", finish: true, owner: "alice", expr: "2+2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStore(t, nil)
+			if _, duplicate, err := s.Begin("e1", "alice", "1+1"); duplicate || err != nil {
+				t.Fatalf("primeira execução: duplicate = %v, erro = %v", duplicate, err)
+			}
+			if tt.finish {
+				s.Finish(core.ExpressionResponse{ExpressionID: "e1", Result: 2, Error: tt.prev})
+			}
+
+			r, duplicate, err := s.Begin("e1", tt.owner, tt.expr)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("erro = %v, esperado %v", err, tt.wantErr)
+			}
+			if duplicate != tt.wantDuplicate {
+				t.Fatalf("duplicate = %v, esperado %v", duplicate, tt.wantDuplicate)
+			}
+			switch {
+			case err != nil:
+			case duplicate && r.Expression != "1+1":
+				t.Errorf("registro da repetição = %+v, esperado o original", r)
+			case !duplicate && (r.Status != StatusQueued || r.Expression != tt.expr):
+				t.Errorf("nova execução = %+v, esperado QUEUED com %q", r, tt.expr)
+			}
+		})
+	}
+}
+
+func TestBeginWithoutExpressionIDIsNeverDuplicate(t *testing.T) {
+	s := newTestStore(t, nil)
+	for range 2 {
+		if _, duplicate, err := s.Begin("", "alice", "1+1"); duplicate || err != nil {
+			t.Fatalf("duplicate = %v, erro = %v", duplicate, err)
+		}
+	}
+	if len(s.records) != 0 {
+		t.Errorf("expressão sem ExpressionID registrada: %v", s.records)
+	}
+}
+
+func TestRunningAndFinish(t *testing.T) {
+	s := newTestStore(t, nil)
+	s.Submit("e1", "alice", "1+1")
+	s.Running("e1")
+	if r, _ := s.Get("e1"); r.Status != StatusRunning {
+		t.Fatalf("status = %s, esperado %s", r.Status, StatusRunning)
+	}
+	s.Finish(core.ExpressionResponse{ExpressionID: "e1", Result: 2})
+	if r, _ := s.Get("e1"); r.Status != StatusDone || r.Result != 2 {
+		t.Fatalf("registro = %+v, esperado DONE com 2", r)
+	}
+	// Running não volta uma expressão terminada para RUNNING
+	s.Running("e1")
+	if r, _ := s.Get("e1"); r.Status != StatusDone {
+		t.Errorf("status = %s após Running, esperado %s", r.Status, StatusDone)
+	}
+	s.Finish(core.ExpressionResponse{ExpressionID: "e2", Error: failure("DIV_BY_ZERO", 0)})
+	if _, ok := s.Get("e2"); ok {
+		t.Error("Finish de expressão desconhecida criou um registro")
+	}
+}
+
+func TestWait(t *testing.T) {
+	s := newTestStore(t, nil)
+
+	if _, ok := s.Wait(context.Background(), "desconhecida"); ok {
+		t.Fatal("Wait encontrou expressão desconhecida")
+	}
+
+	s.Submit("e1", "alice", "1+1")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.Finish(core.ExpressionResponse{ExpressionID: "e1", Result: 2})
+	}()
+	// Vários aguardam a mesma expressão
+	results := make(chan Record, 2)
+	for range 2 {
+		go func() {
+			r, _ := s.Wait(context.Background(), "e1")
+			results <- r
+		}()
+	}
+	for range 2 {
+		select {
+		case r := <-results:
+			if r.Status != StatusDone || r.Result != 2 {
+				t.Errorf("Wait = %+v, esperado DONE com 2", r)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Wait não retornou após Finish")
+		}
+	}
+
+	// Já terminada: retorna sem aguardar
+	if r, ok := s.Wait(context.Background(), "e1"); !ok || r.Status != StatusDone {
+		t.Errorf("Wait = %+v, %v", r, ok)
+	}
+
+	// Contexto expirado: retorna o estado atual
+	s.Submit("e2", "alice", "2+2")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if r, ok := s.Wait(ctx, "e2"); !ok || r.Status != StatusQueued {
+		t.Errorf("Wait com timeout = %+v, %v, esperado QUEUED", r, ok)
+	}
+}
+
+func TestWaitReleasedByResubmit(t *testing.T) {
+	// Uma nova execução do mesmo ExpressionID libera quem aguardava a anterior
+	s := newTestStore(t, nil)
+	s.Submit("e1", "alice", "1+1")
+	done := make(chan struct{})
+	go func() {
+		s.Wait(context.Background(), "e1")
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.Submit("e1", "alice", "1+1")
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait não retornou após nova submissão")
+	}
+}
+
+func TestFailUnfinished(t *testing.T) {
+	s := newTestStore(t, nil)
+	s.Submit("fila", "alice", "1+1")
+	s.Submit("rodando", "alice", "1+1")
+	s.Running("rodando")
+	s.Submit("retomada", "alice", "1+1")
+	s.Submit("concluída", "alice", "1+1")
+	s.Finish(core.ExpressionResponse{ExpressionID: "concluída", Result: 2})
+
+	n := s.FailUnfinished(func(id string) bool { return id == "retomada" })
+	if n != 2 {
+		t.Fatalf("FailUnfinished = %d, esperado 2", n)
+	}
+	for _, id := range []string{"fila", "rodando"} {
+		r, _ := s.Get(id)
+		if r.Status != StatusFailed || r.Error == nil || r.Error.Code != CodeInterrupted {
+			t.Errorf("%s = %+v, esperado FAILED (%s)", id, r, CodeInterrupted)
+		}
+	}
+	if r, _ := s.Get("retomada"); r.Status != StatusQueued {
+		t.Errorf("retomada = %s, esperado %s", r.Status, StatusQueued)
+	}
+	if r, _ := s.Get("concluída"); r.Status != StatusDone {
+		t.Errorf("concluída = %s, esperado %s", r.Status, StatusDone)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s := newTestStore(t, nil)
+	s.Submit("antiga", "alice", "1+1")
+	s.Finish(core.ExpressionResponse{ExpressionID: "antiga", Result: 2})
+	s.Submit("em andamento", "alice", "1+1")
+
+	now := time.Now()
+	if n := s.Prune(now); n != 0 {
+		t.Fatalf("Prune dentro da retenção removeu %d", n)
+	}
+	if n := s.Prune(now.Add(time.Hour)); n != 1 {
+		t.Fatalf("Prune = %d, esperado 1", n)
+	}
+	if _, ok := s.Get("antiga"); ok {
+		t.Error("resultado expirado não foi removido")
+	}
+	if _, ok := s.Get("em andamento"); !ok {
+		t.Error("expressão em andamento foi removida")
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	s := newTestStore(t, db)
+	s.Submit("e1", "alice", "1+1")
+	s.Running("e1")
+	s.Finish(core.ExpressionResponse{ExpressionID: "e1", Result: 2})
+	s.Submit("e2", "alice", "2+2")
+
+	// Um novo store (reinício) recarrega o estado mais recente de cada registro
+	reloaded := newTestStore(t, db)
+	if r, ok := reloaded.Get("e1"); !ok || r.Status != StatusDone || r.Result != 2 || r.Owner != "alice" {
+		t.Fatalf("e1 recarregada = %+v, %v", r, ok)
+	}
+	if r, ok := reloaded.Get("e2"); !ok || r.Status != StatusQueued {
+		t.Fatalf("e2 recarregada = %+v, %v", r, ok)
+	}
+
+	s.Prune(time.Now().Add(time.Hour))
+	if _, ok := newTestStore(t, db).Get("e1"); ok {
+		t.Error("resultado removido por Prune continua persistido")
+	}
+}
@@ -0,0 +1,128 @@
+package sched
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// Scheduler limita as execuções simultâneas e, quando não há vaga,
+// atende primeiro quem tem maior prioridade (FIFO entre prioridades iguais)
+type Scheduler struct {
+	mu      sync.Mutex
+	slots   int
+	running int
+	waiting waitQueue
+	seq     uint64
+}
+
+type waiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+	index    int // posição no heap (-1 após sair da fila)
+}
+
+// NewScheduler cria um escalonador com o número de vagas informado (0 ou menos desativa o limite)
+func NewScheduler(slots int) *Scheduler {
+	return &Scheduler{slots: slots}
+}
+
+// Acquire aguarda uma vaga; release deve ser chamada ao fim da execução.
+// Retorna o erro do contexto se ele terminar antes de a vaga ser concedida.
+func (s *Scheduler) Acquire(ctx context.Context, priority int) (release func(), err error) {
+	if s == nil || s.slots <= 0 {
+		return func() {}, nil
+	}
+
+	s.mu.Lock()
+	if s.running < s.slots && s.waiting.Len() == 0 {
+		s.running++
+		s.mu.Unlock()
+		return s.releaseOnce(), nil
+	}
+	w := &waiter{priority: priority, seq: s.seq, ready: make(chan struct{})}
+	s.seq++
+	heap.Push(&s.waiting, w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return s.releaseOnce(), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if w.index < 0 {
+			// A vaga foi concedida junto com o cancelamento: devolve
+			s.release()
+		} else {
+			heap.Remove(&s.waiting, w.index)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Waiting retorna quantas execuções aguardam vaga
+func (s *Scheduler) Waiting() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiting.Len()
+}
+
+func (s *Scheduler) releaseOnce() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.release()
+			s.mu.Unlock()
+		})
+	}
+}
+
+// release libera a vaga, repassando-a ao próximo da fila (chamado com s.mu travado)
+func (s *Scheduler) release() {
+	if s.waiting.Len() > 0 {
+		w := heap.Pop(&s.waiting).(*waiter)
+		close(w.ready)
+		return
+	}
+	s.running--
+}
+
+// waitQueue é um heap de waiters ordenado por prioridade e ordem de chegada
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() any {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
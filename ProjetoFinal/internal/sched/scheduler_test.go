@@ -0,0 +1,167 @@
+package sched
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitFor aguarda até que o escalonador tenha n execuções na fila
+func waitFor(t *testing.T, s *Scheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.Waiting() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Waiting() = %d, esperado %d", s.Waiting(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func mustAcquire(t *testing.T, s *Scheduler, priority int) func() {
+	t.Helper()
+	release, err := s.Acquire(context.Background(), priority)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	return release
+}
+
+func TestSchedulerUnlimited(t *testing.T) {
+	for _, s := range []*Scheduler{nil, NewScheduler(0), NewScheduler(-1)} {
+		for range 100 {
+			mustAcquire(t, s, 0)
+		}
+		if s.Waiting() != 0 {
+			t.Errorf("Waiting() = %d em escalonador sem limite", s.Waiting())
+		}
+	}
+}
+
+func TestSchedulerPriorityOrder(t *testing.T) {
+	s := NewScheduler(1)
+	release := mustAcquire(t, s, 0)
+
+	// Entram na fila nesta ordem; devem ser atendidos por prioridade e, entre iguais, por chegada
+	priorities := []int{1, 8, 1, 5, 8}
+	want := []int{1, 4, 3, 0, 2}
+	order := make(chan int, len(priorities))
+	for i, p := range priorities {
+		go func() {
+			r := mustAcquire(t, s, p)
+			order <- i
+			r()
+		}()
+		waitFor(t, s, i+1)
+	}
+
+	release()
+	for _, w := range want {
+		select {
+		case got := <-order:
+			if got != w {
+				t.Fatalf("atendido %d (prioridade %d), esperado %d (prioridade %d)", got, priorities[got], w, priorities[w])
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout aguardando vaga")
+		}
+	}
+	if s.Waiting() != 0 {
+		t.Errorf("Waiting() = %d após atender todos", s.Waiting())
+	}
+}
+
+func TestSchedulerSlots(t *testing.T) {
+	s := NewScheduler(2)
+	r1 := mustAcquire(t, s, 0)
+	mustAcquire(t, s, 0)
+
+	got := make(chan struct{})
+	go func() {
+		mustAcquire(t, s, 9)
+		close(got)
+	}()
+	waitFor(t, s, 1)
+
+	select {
+	case <-got:
+		t.Fatal("vaga concedida acima do limite")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	r1()
+	select {
+	case <-got:
+	case <-time.After(2 * time.Second):
+		t.Fatal("vaga liberada não foi repassada")
+	}
+}
+
+func TestSchedulerReleaseIsIdempotent(t *testing.T) {
+	s := NewScheduler(1)
+	r := mustAcquire(t, s, 0)
+	r()
+	r()
+
+	mustAcquire(t, s, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("release repetida liberou mais de uma vaga: erro = %v", err)
+	}
+}
+
+func TestSchedulerCancelledWaiterLeavesQueue(t *testing.T) {
+	s := NewScheduler(1)
+	release := mustAcquire(t, s, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.Acquire(ctx, 9)
+		errc <- err
+	}()
+	waitFor(t, s, 1)
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("erro = %v, esperado %v", err, context.Canceled)
+	}
+	if s.Waiting() != 0 {
+		t.Fatalf("Waiting() = %d após cancelamento", s.Waiting())
+	}
+
+	// A vaga volta a quem está rodando e, liberada, fica disponível
+	release()
+	mustAcquire(t, s, 0)
+}
+
+func TestSchedulerCancelAfterGrantReturnsSlot(t *testing.T) {
+	// Cancelamentos concorrentes com a concessão não podem vazar vagas
+	s := NewScheduler(1)
+	for range 200 {
+		release := mustAcquire(t, s, 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan func(), 1)
+		go func() {
+			r, err := s.Acquire(ctx, 0)
+			if err != nil {
+				r = func() {}
+			}
+			done <- r
+		}()
+		waitFor(t, s, 1)
+		go cancel()
+		release()
+		(<-done)()
+		cancel()
+	}
+
+	// Se alguma vaga vazou, esta aquisição não termina
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := s.Acquire(ctx, 0); err != nil {
+		t.Fatalf("vaga perdida: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// Variáveis de ambiente de configuração do tracing
+	ExporterEnv = "OTEL_TRACES_EXPORTER" // "none" (padrão), "file" ou "otlp"
+	FileEnv     = "OTEL_TRACES_FILE"     // arquivo de saída do exporter "file"
+
+	// Endpoint OTLP/HTTP padrão (coletor local, ex: Jaeger ou otel-collector)
+	defaultOTLPEndpoint = "localhost:4318"
+
+	instrumentationName = "github.com/Monterazo/Atividades-IF711/ProjetoFinal"
+)
+
+// Chaves de atributos usadas nos spans
+const (
+	AttrExpressionID = attribute.Key("calculator.expression_id")
+	AttrClientID     = attribute.Key("calculator.client_id")
+	AttrStepID       = attribute.Key("calculator.step_id")
+	AttrStepIndex    = attribute.Key("calculator.step_index")
+	AttrOperation    = attribute.Key("calculator.operation")
+	AttrErrorCode    = attribute.Key("calculator.error_code")
+	AttrResumed      = attribute.Key("calculator.resumed")
+)
+
+// InitTracer configura o TracerProvider global e o propagador W3C Trace Context.
+// O exporter é escolhido pela variável OTEL_TRACES_EXPORTER; a função retornada
+// deve ser chamada no encerramento do processo para descarregar os spans.
+func InitTracer(serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var closeFile func() error
+
+	switch strings.ToLower(os.Getenv(ExporterEnv)) {
+	case "", "none":
+		// Mantém o provider no-op: o contexto continua sendo propagado
+		return func(context.Context) error { return nil }, nil
+	case "file":
+		path := os.Getenv(FileEnv)
+		if path == "" {
+			path = fmt.Sprintf("traces-%s.json", serviceName)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao abrir arquivo de traces: %v", err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("falha ao criar exporter de arquivo: %v", err)
+		}
+		closeFile = f.Close
+	case "otlp":
+		opts := []otlptracehttp.Option{}
+		if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(defaultOTLPEndpoint), otlptracehttp.WithInsecure())
+		}
+		var err error
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao criar exporter OTLP: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("exporter de traces desconhecido: %s", os.Getenv(ExporterEnv))
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeFile != nil {
+			closeFile()
+		}
+		return err
+	}, nil
+}
+
+// Tracer retorna o tracer do projeto
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// TraceID retorna o trace ID do contexto (vazio se não houver span válido)
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// InjectMap serializa o trace context em um mapa (ex: para persistir junto ao estado)
+func InjectMap(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// ExtractMap restaura o trace context serializado por InjectMap
+func ExtractMap(ctx context.Context, m map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(m))
+}
@@ -0,0 +1,51 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var remote = trace.NewSpanContext(trace.SpanContextConfig{
+	TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+	SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	TraceFlags: trace.FlagsSampled,
+})
+
+func TestTraceID(t *testing.T) {
+	if got := TraceID(context.Background()); got != "" {
+		t.Errorf("TraceID sem span = %q, esperado vazio", got)
+	}
+	ctx := trace.ContextWithSpanContext(context.Background(), remote)
+	if got := TraceID(ctx); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("TraceID = %q", got)
+	}
+}
+
+func TestInjectExtractMap(t *testing.T) {
+	t.Setenv(ExporterEnv, "none")
+	if _, err := InitTracer("test"); err != nil { // registra o propagador W3C
+		t.Fatal(err)
+	}
+
+	m := InjectMap(trace.ContextWithSpanContext(context.Background(), remote))
+	if m["traceparent"] == "" {
+		t.Fatalf("InjectMap = %v, esperado traceparent", m)
+	}
+	got := trace.SpanContextFromContext(ExtractMap(context.Background(), m))
+	if got.TraceID() != remote.TraceID() || got.SpanID() != remote.SpanID() {
+		t.Errorf("ExtractMap = %+v, esperado %+v", got, remote)
+	}
+
+	if m := InjectMap(context.Background()); len(m) != 0 {
+		t.Errorf("InjectMap sem span = %v, esperado vazio", m)
+	}
+}
+
+func TestInitTracerUnknownExporter(t *testing.T) {
+	t.Setenv(ExporterEnv, "zipkin")
+	if _, err := InitTracer("test"); err == nil {
+		t.Error("exporter desconhecido aceito")
+	}
+}
@@ -0,0 +1,196 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Options descreve a configuração TLS de um lado da conexão gRPC
+type Options struct {
+	Enabled    bool   // cliente: ativa TLS mesmo sem CA/certificado (usa as raízes do sistema)
+	CertFile   string // certificado do processo (servidor, ou cliente no mTLS)
+	KeyFile    string // chave privada do certificado
+	CAFile     string // CA usada para verificar o outro lado
+	ServerName string // cliente: nome esperado no certificado do servidor
+	ClientAuth bool   // servidor: exige certificado de cliente assinado pela CA (mTLS)
+	AllowNames string // servidor: nomes (CN/SAN) aceitos nos certificados de cliente, separados por vírgula
+}
+
+// BindServerFlags registra as flags de TLS do lado servidor
+func BindServerFlags(fs *flag.FlagSet) *Options {
+	o := &Options{}
+	fs.StringVar(&o.CertFile, "tls-cert", "", "Arquivo PEM do certificado do servidor (ativa TLS)")
+	fs.StringVar(&o.KeyFile, "tls-key", "", "Arquivo PEM da chave privada do servidor")
+	fs.StringVar(&o.CAFile, "tls-ca", "", "Arquivo PEM da CA usada para verificar os clientes (mTLS; exige -tls-client-auth)")
+	fs.BoolVar(&o.ClientAuth, "tls-client-auth", false, "Exige certificado de cliente assinado pela CA (mTLS)")
+	fs.StringVar(&o.AllowNames, "tls-allow", "", "Nomes (CN/SAN) aceitos nos certificados de cliente, separados por vírgula (exige -tls-client-auth)")
+	return o
+}
+
+// BindClientFlags registra as flags de TLS do lado cliente com o prefixo informado (ex: "" ou "upstream-")
+func BindClientFlags(fs *flag.FlagSet, prefix string) *Options {
+	o := &Options{}
+	fs.BoolVar(&o.Enabled, prefix+"tls", false, "Conecta com TLS (implícito ao informar CA ou certificado)")
+	fs.StringVar(&o.CertFile, prefix+"tls-cert", "", "Arquivo PEM do certificado de cliente (mTLS)")
+	fs.StringVar(&o.KeyFile, prefix+"tls-key", "", "Arquivo PEM da chave privada do certificado de cliente")
+	fs.StringVar(&o.CAFile, prefix+"tls-ca", "", "Arquivo PEM da CA usada para verificar o servidor")
+	fs.StringVar(&o.ServerName, prefix+"tls-server-name", "", "Nome esperado no certificado do servidor")
+	return o
+}
+
+// ServerEnabled indica se o servidor deve escutar com TLS
+func (o *Options) ServerEnabled() bool {
+	return o.CertFile != ""
+}
+
+// ClientEnabled indica se o cliente deve conectar com TLS
+func (o *Options) ClientEnabled() bool {
+	return o.Enabled || o.CAFile != "" || o.CertFile != ""
+}
+
+// ServerCredentials retorna as credenciais de transporte do servidor (inseguras sem certificado).
+// -tls-ca e -tls-allow sem -tls-client-auth são recusados, pois não restringiriam os clientes.
+func (o *Options) ServerCredentials() (credentials.TransportCredentials, error) {
+	if !o.ClientAuth && (o.CAFile != "" || o.AllowNames != "") {
+		return nil, errors.New("-tls-ca e -tls-allow verificam os clientes apenas com -tls-client-auth (mTLS)")
+	}
+	if !o.ServerEnabled() {
+		if o.ClientAuth {
+			return nil, errors.New("mTLS exige -tls-cert e -tls-key")
+		}
+		return insecure.NewCredentials(), nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao carregar certificado: %v", err)
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if o.ClientAuth {
+		if o.CAFile == "" {
+			return nil, errors.New("mTLS exige -tls-ca para verificar os clientes")
+		}
+		pool, err := loadCA(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		if names := splitNames(o.AllowNames); len(names) > 0 {
+			cfg.VerifyPeerCertificate = allowNames(names)
+		}
+	}
+
+	return credentials.NewTLS(cfg), nil
+}
+
+// ClientCredentials retorna as credenciais de transporte do cliente (inseguras sem TLS)
+func (o *Options) ClientCredentials() (credentials.TransportCredentials, error) {
+	if !o.ClientEnabled() {
+		return insecure.NewCredentials(), nil
+	}
+
+	cfg := &tls.Config{
+		ServerName: o.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if o.CAFile != "" {
+		pool, err := loadCA(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao carregar certificado de cliente: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(cfg), nil
+}
+
+// ServerMode descreve o modo de transporte do servidor para os logs
+func (o *Options) ServerMode() string {
+	switch {
+	case o.ServerEnabled() && o.ClientAuth:
+		return "mtls"
+	case o.ServerEnabled():
+		return "tls"
+	default:
+		return "insecure"
+	}
+}
+
+// ClientMode descreve o modo de transporte do cliente para os logs
+func (o *Options) ClientMode() string {
+	switch {
+	case o.ClientEnabled() && o.CertFile != "":
+		return "mtls"
+	case o.ClientEnabled():
+		return "tls"
+	default:
+		return "insecure"
+	}
+}
+
+func loadCA(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("nenhum certificado válido em %s", path)
+	}
+	return pool, nil
+}
+
+func splitNames(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// allowNames restringe os certificados de cliente (já verificados pela CA) aos nomes informados
+func allowNames(names []string) func([][]byte, [][]*x509.Certificate) error {
+	return func(_ [][]byte, chains [][]*x509.Certificate) error {
+		for _, chain := range chains {
+			if len(chain) == 0 {
+				continue
+			}
+			leaf := chain[0]
+			if slices.Contains(names, leaf.Subject.CommonName) {
+				return nil
+			}
+			for _, dns := range leaf.DNSNames {
+				if slices.Contains(names, dns) {
+					return nil
+				}
+			}
+		}
+		return errors.New("certificado de cliente não autorizado")
+	}
+}
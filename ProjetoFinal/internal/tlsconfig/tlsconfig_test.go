@@ -0,0 +1,25 @@
+package tlsconfig
+
+import "testing"
+
+func TestServerCredentialsFlagCombinations(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"inseguro", Options{}, false},
+		{"allow sem client-auth", Options{AllowNames: "dispatcher"}, true},
+		{"ca sem client-auth", Options{CAFile: "ca.pem"}, true},
+		{"allow sem client-auth com certificado", Options{CertFile: "srv.pem", KeyFile: "srv-key.pem", AllowNames: "dispatcher"}, true},
+		{"client-auth sem certificado", Options{ClientAuth: true, CAFile: "ca.pem"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.opts.ServerCredentials()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("erro = %v, esperado erro: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package calcclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/rabbitmq"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/telemetry"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// amqpTransport publica as expressões em calculator.requests e recebe as respostas
+// em uma fila exclusiva deste cliente, informada em reply_to e correlacionada pelo
+// ExpressionID. As consultas pelo ticket usam outra fila exclusiva. A primeira
+// conexão do pool também consome as respostas.
+type amqpTransport struct {
+	conns         []*rabbitmq.Connection
+	next          atomic.Uint64
+	creds         auth.Credentials
+	contentType   string
+	clientID      string
+	responseQueue string // respostas das expressões (calculator.responses.<client_id>)
+	replyQueue    string // respostas das consultas (calculator.status.replies.<client_id>)
+
+	mu       sync.Mutex
+	pending  map[string]chan rabbitmq.ExpressionResponse // ExpressionID -> resposta aguardada
+	statuses map[string]chan rabbitmq.ExpressionStatus   // ticket -> consulta aguardada
+}
+
+// DialAMQP conecta ao RabbitMQ em url e inicia os consumidores de respostas
+func DialAMQP(url string, cfg Config) (Client, error) {
+	cfg = cfg.withDefaults()
+	contentType := rabbitmq.ContentTypeJSON
+	switch strings.ToLower(cfg.Codec) {
+	case "", "json":
+	case "protobuf", "proto":
+		contentType = rabbitmq.ContentTypeProtobuf
+	default:
+		return nil, fmt.Errorf("codificação desconhecida: %s (use json ou protobuf)", cfg.Codec)
+	}
+
+	t := &amqpTransport{
+		creds:         cfg.Credentials.internal(),
+		contentType:   contentType,
+		clientID:      cfg.ClientID,
+		responseQueue: rabbitmq.ResponseReplyQueue(cfg.ClientID),
+		replyQueue:    rabbitmq.StatusReplyQueue(cfg.ClientID),
+		pending:       make(map[string]chan rabbitmq.ExpressionResponse),
+		statuses:      make(map[string]chan rabbitmq.ExpressionStatus),
+	}
+	for range cfg.PoolSize {
+		conn, err := rabbitmq.NewConnection(url)
+		if err != nil {
+			t.close()
+			return nil, err
+		}
+		t.conns = append(t.conns, conn)
+	}
+	if err := t.setup(); err != nil {
+		t.close()
+		return nil, err
+	}
+	return newClient(t, cfg), nil
+}
+
+// setup declara as filas e exchanges usadas pelo cliente e inicia os consumidores
+func (t *amqpTransport) setup() error {
+	conn := t.conns[0]
+	if err := conn.DeclarePriorityQueue(rabbitmq.RequestQueue); err != nil {
+		return fmt.Errorf("erro ao declarar fila de requests: %w", err)
+	}
+	for _, exchange := range []string{rabbitmq.StatusExchange, rabbitmq.CancelExchange} {
+		if err := conn.DeclareExchange(exchange, "fanout"); err != nil {
+			return err
+		}
+	}
+	for _, queue := range []string{t.responseQueue, t.replyQueue} {
+		if err := conn.DeclareReplyQueue(queue); err != nil {
+			return fmt.Errorf("erro ao declarar fila %s: %w", queue, err)
+		}
+	}
+
+	responses, err := conn.Consume(t.responseQueue)
+	if err != nil {
+		return fmt.Errorf("erro ao consumir fila de responses: %w", err)
+	}
+	replies, err := conn.Consume(t.replyQueue)
+	if err != nil {
+		return err
+	}
+	go t.consumeResponses(responses)
+	go t.consumeReplies(replies)
+	return nil
+}
+
+func (t *amqpTransport) consumeResponses(msgs <-chan amqp.Delivery) {
+	for msg := range msgs {
+		var resp rabbitmq.ExpressionResponse
+		if err := rabbitmq.Decode(msg.ContentType, msg.Body, &resp); err != nil {
+			msg.Nack(false, false)
+			continue
+		}
+
+		// Respostas que chegam depois do prazo são descartadas
+		t.mu.Lock()
+		if ch, ok := t.pending[resp.ExpressionID]; ok {
+			ch <- resp
+			delete(t.pending, resp.ExpressionID)
+		}
+		t.mu.Unlock()
+		msg.Ack(false)
+	}
+}
+
+func (t *amqpTransport) consumeReplies(msgs <-chan amqp.Delivery) {
+	for msg := range msgs {
+		var st rabbitmq.ExpressionStatus
+		if err := rabbitmq.Decode(msg.ContentType, msg.Body, &st); err == nil {
+			t.mu.Lock()
+			if ch, ok := t.statuses[st.Ticket]; ok {
+				ch <- st
+				delete(t.statuses, st.Ticket)
+			}
+			t.mu.Unlock()
+		}
+		msg.Ack(false)
+	}
+}
+
+// send assina e publica a mensagem pela próxima conexão do pool
+func (t *amqpTransport) send(ctx context.Context, msg rabbitmq.Message, v any) error {
+	body, err := rabbitmq.Encode(t.contentType, v)
+	if err != nil {
+		return err
+	}
+	msg.Body = body
+	msg.ContentType = t.contentType
+	msg.Headers = amqp.Table{}
+	rabbitmq.SignHeaders(t.creds, msg.Headers, body)
+
+	conn := t.conns[t.next.Add(1)%uint64(len(t.conns))]
+	if err := conn.Send(ctx, msg); err != nil {
+		return &transportError{err: fmt.Errorf("erro ao publicar: %w", err), retryable: true}
+	}
+	return nil
+}
+
+func (t *amqpTransport) calculate(ctx context.Context, req request) (core.ExpressionResponse, error) {
+	// Span raiz do trace desta expressão (propagado nos headers AMQP)
+	ctx, span := telemetry.Tracer().Start(ctx, "calculate", trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			telemetry.AttrExpressionID.String(req.expressionID),
+			telemetry.AttrClientID.String(t.clientID),
+		))
+	defer span.End()
+
+	ch := make(chan rabbitmq.ExpressionResponse, 1)
+	t.mu.Lock()
+	t.pending[req.expressionID] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, req.expressionID)
+		t.mu.Unlock()
+	}()
+
+	err := t.send(ctx, rabbitmq.Message{RoutingKey: rabbitmq.RequestQueue, Priority: req.priority}, &rabbitmq.ExpressionRequest{
+		ExpressionID: req.expressionID,
+		Expression:   req.expression,
+		DeadlineMs:   req.deadline.Milliseconds(),
+		IncludeTrace: req.trace,
+		Priority:     req.priority,
+		ClientID:     t.clientID,
+		ReplyTo:      t.responseQueue,
+	})
+	if err != nil {
+		return core.ExpressionResponse{}, err
+	}
+
+	select {
+	case resp := <-ch:
+		return responseFromMessage(resp), nil
+	case <-ctx.Done():
+		return core.ExpressionResponse{}, ctx.Err()
+	}
+}
+
+// result consulta o ticket em todas as instâncias do dispatcher; sem resposta de
+// nenhuma até ctx expirar (ex: nenhuma tem o ticket), retorna erro
+func (t *amqpTransport) result(ctx context.Context, ticket string, wait time.Duration) (*Status, error) {
+	ch := make(chan rabbitmq.ExpressionStatus, 1)
+	t.mu.Lock()
+	t.statuses[ticket] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.statuses, ticket)
+		t.mu.Unlock()
+	}()
+
+	err := t.send(ctx, rabbitmq.Message{Exchange: rabbitmq.StatusExchange}, &rabbitmq.ResultRequest{
+		Ticket:    ticket,
+		TimeoutMs: wait.Milliseconds(),
+		ReplyTo:   t.replyQueue,
+		ClientID:  t.clientID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case st := <-ch:
+		if st.Error != nil {
+			return nil, &Error{Code: st.Error.Code, Message: st.Error.Message}
+		}
+		var resp *core.ExpressionResponse
+		if st.Response != nil {
+			r := responseFromMessage(*st.Response)
+			resp = &r
+		}
+		return statusFromCore(st.Ticket, st.State, resp), nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("nenhum dispatcher conhece o ticket %s", ticket)
+	}
+}
+
+// cancel pede o cancelamento a todas as instâncias do dispatcher; a resposta
+// CANCELLED chega a quem enviou a expressão
+func (t *amqpTransport) cancel(ctx context.Context, ticket string) (*CancelStatus, error) {
+	err := t.send(ctx, rabbitmq.Message{Exchange: rabbitmq.CancelExchange}, &rabbitmq.CancelRequest{ExpressionID: ticket, ClientID: t.clientID})
+	if err != nil {
+		return nil, err
+	}
+	return &CancelStatus{}, nil
+}
+
+func (t *amqpTransport) close() error {
+	for _, conn := range t.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+func responseFromMessage(r rabbitmq.ExpressionResponse) core.ExpressionResponse {
+	return core.ExpressionResponse{
+		ExpressionID: r.ExpressionID,
+		Result:       r.Result,
+		Error:        rabbitmq.ErrorToCore(r.Error),
+		Trace:        rabbitmq.TraceToCore(r.Trace),
+	}
+}
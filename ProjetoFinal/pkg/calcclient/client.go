@@ -0,0 +1,422 @@
+// Package calcclient é o SDK Go da calculadora distribuída. Um Client envia
+// expressões a um dispatcher por gRPC (DialGRPC) ou RabbitMQ (DialAMQP), gera
+// ExpressionIDs únicos, aplica o prazo de cada expressão, repete as tentativas que
+// falharam por motivos transitórios e correlaciona as respostas.
+//
+//	client, err := calcclient.DialGRPC(ctx, "localhost:50051", calcclient.Config{})
+//	if err != nil { ... }
+//	defer client.Close()
+//
+//	res, err := client.Calculate(ctx, "((4+3)*2)/5", calcclient.WithTimeout(5*time.Second))
+//	var calcErr *calcclient.Error
+//	if errors.As(err, &calcErr) { ... } // erro da expressão (ex: DIV_BY_ZERO)
+//
+// As tentativas de uma expressão reutilizam o mesmo ExpressionID: o dispatcher
+// deduplica o pedido, então uma tentativa repetida nunca executa a expressão duas vezes.
+package calcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/auth"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	"google.golang.org/grpc/credentials"
+)
+
+// Prioridades usuais (0 a MaxPriority; maior é atendida primeiro)
+const (
+	MaxPriority         = core.MaxPriority
+	PriorityBatch       = core.PriorityBatch
+	PriorityInteractive = core.PriorityInteractive
+)
+
+// Códigos de erro retornados pelos dispatchers que o SDK trata de forma especial
+const (
+	CodeCancelled         = core.CodeCancelled
+	CodeExecutionError    = core.CodeExecutionError
+	CodeOverloaded        = core.CodeOverloaded
+	CodeRateLimited       = "RATE_LIMITED"
+	CodeResourceExhausted = "RESOURCE_EXHAUSTED"
+	CodeInterrupted       = "INTERRUPTED"
+	CodeInternalError     = "INTERNAL_ERROR"
+)
+
+// ErrTimeout indica que a resposta não chegou dentro do prazo da expressão
+var ErrTimeout = errors.New("calcclient: timeout ao aguardar a resposta")
+
+// ErrCredentialsRequireTLS indica credenciais em uma conexão gRPC sem Config.TLS
+var ErrCredentialsRequireTLS = errors.New("calcclient: as credenciais exigem TLS na conexão gRPC")
+
+// Client envia expressões a um dispatcher. É seguro para uso concorrente.
+type Client interface {
+	// Calculate envia a expressão e aguarda o resultado. Um erro da expressão é
+	// retornado como *Error junto com o Result (ExpressionID e trace); em falhas do
+	// transporte ou ErrTimeout, o Result traz o ExpressionID, que é o ticket para
+	// consultar depois com Result.
+	Calculate(ctx context.Context, expr string, opts ...Option) (*Result, error)
+	// Result aguarda até wait o resultado de uma expressão enviada antes (inclusive
+	// por outro cliente do mesmo principal)
+	Result(ctx context.Context, ticket string, wait time.Duration) (*Status, error)
+	// Cancel pede o cancelamento de uma expressão em andamento
+	Cancel(ctx context.Context, ticket string) (*CancelStatus, error)
+	Close() error
+}
+
+// Config configura um Client; os valores zero usam os padrões indicados
+type Config struct {
+	ClientID    string        // identifica este cliente nas mensagens (padrão: um UUIDv7)
+	Credentials Credentials   // vazio: sem autenticação
+	Timeout     time.Duration // prazo padrão de cada expressão (padrão: 30s)
+	Priority    int           // prioridade padrão (zero usa PriorityInteractive; WithPriority(0) envia 0)
+	Retry       RetryPolicy
+	PoolSize    int // conexões abertas com o dispatcher ou o broker (padrão: 1)
+
+	TLS   credentials.TransportCredentials // gRPC: TLS/mTLS (nil conecta sem TLS)
+	Codec string                           // RabbitMQ: "json" (padrão) ou "protobuf"
+}
+
+// RetryPolicy define as novas tentativas de uma expressão que falhou por um motivo
+// transitório (dispatcher sobrecarregado, cota, reinício ou falha do transporte),
+// sempre dentro do prazo da expressão
+type RetryPolicy struct {
+	MaxAttempts    int           // tentativas, incluindo a primeira (padrão: 3; 1 desativa)
+	InitialBackoff time.Duration // espera antes da segunda tentativa (padrão: 100ms), dobrando a cada uma
+	MaxBackoff     time.Duration // limite da espera (padrão: 2s); RetryAfter do dispatcher tem precedência
+}
+
+// Credentials identifica o cliente: Token (JWT) ou API key (Principal e Secret)
+type Credentials struct {
+	Principal string
+	Secret    string
+	Token     string
+}
+
+// CredentialsFromEnv lê as credenciais de CALC_TOKEN ou CALC_API_KEY (principal:segredo)
+func CredentialsFromEnv() (Credentials, error) {
+	c, err := auth.CredentialsFromEnv()
+	return Credentials{Principal: c.Principal, Secret: c.Secret, Token: c.Token}, err
+}
+
+func (c Credentials) internal() auth.Credentials {
+	return auth.Credentials{Principal: c.Principal, Secret: c.Secret, Token: c.Token}
+}
+
+// Result é o resultado de uma expressão
+type Result struct {
+	ExpressionID string
+	Value        float64
+	Trace        []StepTrace // preenchido com WithTrace
+	Attempts     int
+	Duration     time.Duration // desde o primeiro envio, incluindo as novas tentativas
+}
+
+// StepTrace descreve a execução de um step
+type StepTrace struct {
+	StepID    string
+	Operation string
+	Operands  []float64
+	Result    float64
+	Server    string
+	Latency   time.Duration
+	Err       *Error
+}
+
+// StepState é a fase de um step informada pelo progresso
+type StepState int
+
+const (
+	StepDispatched StepState = iota + 1 // enviado ao servidor de operação
+	StepCompleted                       // concluído com sucesso
+	StepFailed                          // concluído com erro
+)
+
+// Step é um evento de progresso recebido com WithProgress
+type Step struct {
+	Index     int // 0 a Total-1
+	Total     int
+	StepID    string
+	Operation string
+	Operands  []float64
+	State     StepState
+	Result    float64 // resultado parcial (StepCompleted)
+	Err       *Error
+}
+
+// Status é o estado de uma expressão consultada pelo ticket
+type Status struct {
+	Ticket string
+	State  string  // QUEUED, RUNNING, DONE ou FAILED
+	Result *Result // preenchido em DONE e FAILED (com o trace)
+	Err    *Error  // erro da expressão em FAILED
+}
+
+// CancelStatus é a resposta a um pedido de cancelamento
+type CancelStatus struct {
+	Cancelled bool
+	State     string // estado final; vazio no RabbitMQ, que apenas encaminha o pedido
+}
+
+// Error é um erro retornado pelo dispatcher para a expressão
+type Error struct {
+	Code       string
+	Message    string
+	RetryAfter time.Duration // sugestão de espera do dispatcher (sobrecarga e cotas)
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Code, e.Message)
+}
+
+// Retryable indica que uma nova tentativa da expressão pode ter sucesso
+func (e *Error) Retryable() bool {
+	switch e.Code {
+	case CodeOverloaded, CodeRateLimited, CodeResourceExhausted, CodeInterrupted, CodeExecutionError, CodeInternalError:
+		return true
+	}
+	return e.RetryAfter > 0
+}
+
+// Option ajusta uma chamada de Calculate
+type Option func(*callOptions)
+
+type callOptions struct {
+	expressionID string
+	timeout      time.Duration
+	priority     int
+	trace        bool
+	maxAttempts  int
+	progress     func(Step)
+	submitted    func(ticket, state string)
+}
+
+// WithExpressionID usa o ExpressionID informado em vez de gerar um; reenviar o mesmo
+// ID retorna o resultado da execução anterior
+func WithExpressionID(id string) Option {
+	return func(o *callOptions) { o.expressionID = id }
+}
+
+// WithTimeout define o prazo da expressão, incluindo as novas tentativas
+func WithTimeout(d time.Duration) Option {
+	return func(o *callOptions) { o.timeout = d }
+}
+
+// WithPriority define a prioridade da expressão (0 a MaxPriority)
+func WithPriority(p int) Option {
+	return func(o *callOptions) { o.priority = p }
+}
+
+// WithTrace pede o trace de execução de cada step
+func WithTrace() Option {
+	return func(o *callOptions) { o.trace = true }
+}
+
+// WithMaxAttempts define o número de tentativas desta expressão
+func WithMaxAttempts(n int) Option {
+	return func(o *callOptions) { o.maxAttempts = n }
+}
+
+// WithProgress recebe cada step despachado e concluído (gRPC; ignorado no RabbitMQ)
+func WithProgress(fn func(Step)) Option {
+	return func(o *callOptions) { o.progress = fn }
+}
+
+// WithSubmit envia a expressão de forma assíncrona e aguarda o resultado pelo ticket,
+// informado a fn assim que registrado (gRPC Submit/WaitResult; ignorado no RabbitMQ)
+func WithSubmit(fn func(ticket, state string)) Option {
+	return func(o *callOptions) { o.submitted = fn }
+}
+
+// request é uma tentativa de uma expressão entregue ao transporte
+type request struct {
+	expressionID string
+	expression   string
+	deadline     time.Duration
+	trace        bool
+	priority     int
+	progress     func(Step)
+	submitted    func(ticket, state string)
+}
+
+// transport é a implementação de um protocolo (gRPC ou RabbitMQ)
+type transport interface {
+	calculate(ctx context.Context, req request) (core.ExpressionResponse, error)
+	result(ctx context.Context, ticket string, wait time.Duration) (*Status, error)
+	cancel(ctx context.Context, ticket string) (*CancelStatus, error)
+	close() error
+}
+
+// client aplica IDs, prazos e novas tentativas sobre um transporte
+type client struct {
+	transport transport
+	cfg       Config
+}
+
+func newClient(t transport, cfg Config) *client {
+	return &client{transport: t, cfg: cfg}
+}
+
+// withDefaults preenche os valores zero da configuração
+func (cfg Config) withDefaults() Config {
+	if cfg.ClientID == "" {
+		cfg.ClientID = core.NewID()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.Priority == 0 {
+		cfg.Priority = PriorityInteractive
+	}
+	if cfg.Retry.MaxAttempts <= 0 {
+		cfg.Retry.MaxAttempts = 3
+	}
+	if cfg.Retry.InitialBackoff <= 0 {
+		cfg.Retry.InitialBackoff = 100 * time.Millisecond
+	}
+	if cfg.Retry.MaxBackoff <= 0 {
+		cfg.Retry.MaxBackoff = 2 * time.Second
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 1
+	}
+	return cfg
+}
+
+func (c *client) Calculate(ctx context.Context, expr string, opts ...Option) (*Result, error) {
+	o := callOptions{timeout: c.cfg.Timeout, priority: c.cfg.Priority, maxAttempts: c.cfg.Retry.MaxAttempts}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.expressionID == "" {
+		o.expressionID = core.NewID()
+	}
+	res := &Result{ExpressionID: o.expressionID}
+
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	start := time.Now()
+	backoff := c.cfg.Retry.InitialBackoff
+	for {
+		res.Attempts++
+		req := request{
+			expressionID: o.expressionID,
+			expression:   expr,
+			trace:        o.trace,
+			priority:     o.priority,
+			progress:     o.progress,
+			submitted:    o.submitted,
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			req.deadline = time.Until(deadline)
+		}
+
+		resp, err := c.transport.calculate(ctx, req)
+		res.Duration = time.Since(start)
+
+		var wait time.Duration
+		switch {
+		case err != nil && ctx.Err() != nil:
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return res, ErrTimeout
+			}
+			return res, ctx.Err()
+		case err != nil:
+			var te *transportError
+			if !errors.As(err, &te) || !te.retryable {
+				return res, err
+			}
+			if res.Attempts >= o.maxAttempts {
+				return res, err
+			}
+		case resp.Error != nil:
+			res.Trace = traceFromCore(resp.Trace)
+			calcErr := errorFromCore(resp.Error)
+			if !calcErr.Retryable() || res.Attempts >= o.maxAttempts {
+				return res, calcErr
+			}
+			wait = calcErr.RetryAfter
+		default:
+			res.Value = resp.Result
+			res.Trace = traceFromCore(resp.Trace)
+			return res, nil
+		}
+
+		// Nova tentativa com o mesmo ExpressionID, após o backoff (ou o RetryAfter)
+		wait = max(wait, backoff)
+		backoff = min(backoff*2, c.cfg.Retry.MaxBackoff)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return res, ErrTimeout
+			}
+			return res, ctx.Err()
+		}
+	}
+}
+
+func (c *client) Result(ctx context.Context, ticket string, wait time.Duration) (*Status, error) {
+	return c.transport.result(ctx, ticket, wait)
+}
+
+func (c *client) Cancel(ctx context.Context, ticket string) (*CancelStatus, error) {
+	return c.transport.cancel(ctx, ticket)
+}
+
+func (c *client) Close() error {
+	return c.transport.close()
+}
+
+// transportError é uma falha do transporte; retryable indica que o pedido pode não
+// ter chegado ao dispatcher (ex: conexão indisponível)
+type transportError struct {
+	err       error
+	retryable bool
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+func errorFromCore(e *core.ErrorInfo) *Error {
+	if e == nil {
+		return nil
+	}
+	return &Error{Code: e.Code, Message: e.Message, RetryAfter: time.Duration(e.RetryAfterMs) * time.Millisecond}
+}
+
+func traceFromCore(trace []core.StepTrace) []StepTrace {
+	if len(trace) == 0 {
+		return nil
+	}
+	out := make([]StepTrace, len(trace))
+	for i, t := range trace {
+		out[i] = StepTrace{
+			StepID:    t.StepID,
+			Operation: t.Operation,
+			Operands:  t.Operands,
+			Result:    t.Result,
+			Server:    t.Server,
+			Latency:   time.Duration(t.LatencyUs) * time.Microsecond,
+			Err:       errorFromCore(t.Error),
+		}
+	}
+	return out
+}
+
+// statusFromCore monta o Status de uma expressão terminada (resp não nulo) ou em andamento
+func statusFromCore(ticket, state string, resp *core.ExpressionResponse) *Status {
+	st := &Status{Ticket: ticket, State: state}
+	if resp == nil {
+		return st
+	}
+	if resp.Error != nil {
+		st.Err = errorFromCore(resp.Error)
+		st.Result = &Result{ExpressionID: resp.ExpressionID, Trace: traceFromCore(resp.Trace)}
+		return st
+	}
+	st.Result = &Result{ExpressionID: resp.ExpressionID, Value: resp.Result, Trace: traceFromCore(resp.Trace)}
+	return st
+}
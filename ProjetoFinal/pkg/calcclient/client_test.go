@@ -0,0 +1,186 @@
+package calcclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+)
+
+// attempt é a resposta do fakeTransport a uma tentativa
+type attempt struct {
+	resp core.ExpressionResponse
+	err  error
+}
+
+// fakeTransport responde cada tentativa com o próximo item de attempts (o último se repete)
+type fakeTransport struct {
+	attempts []attempt
+	requests []request
+	at       []time.Time
+}
+
+func (f *fakeTransport) calculate(ctx context.Context, req request) (core.ExpressionResponse, error) {
+	f.requests = append(f.requests, req)
+	f.at = append(f.at, time.Now())
+	a := f.attempts[min(len(f.requests), len(f.attempts))-1]
+	a.resp.ExpressionID = req.expressionID
+	return a.resp, a.err
+}
+
+func (f *fakeTransport) result(context.Context, string, time.Duration) (*Status, error) {
+	return nil, nil
+}
+
+func (f *fakeTransport) cancel(context.Context, string) (*CancelStatus, error) {
+	return nil, nil
+}
+
+func (f *fakeTransport) close() error { return nil }
+
+func newTestClient(f *fakeTransport) *client {
+	return newClient(f, Config{Retry: RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}}.withDefaults())
+}
+
+func failed(code string, retryAfterMs int64) attempt {
+	return attempt{resp: core.ExpressionResponse{Error: &core.ErrorInfo{Code: code, Message: code, RetryAfterMs: retryAfterMs}}}
+}
+
+func TestErrorRetryable(t *testing.T) {
+	tests := []struct {
+		err  Error
+		want bool
+	}{
+		{Error{Code: CodeOverloaded}, true},
+		{Error{Code: CodeRateLimited}, true},
+		{Error{Code: CodeResourceExhausted}, true},
+		{Error{Code: CodeInterrupted}, true},
+		{Error{Code: CodeExecutionError}, true},
+		{Error{Code: CodeInternalError}, true},
+		{Error{Code: "DIV_BY_ZERO"}, false},
+		{Error{Code: "PARSE_ERROR"}, false},
+		{Error{Code: CodeCancelled}, false},
+		{Error{Code: "QUOTA", RetryAfter: time.Second}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Code, func(t *testing.T) {
+			if got := tt.err.Retryable(); got != tt.want {
+				t.Errorf("Retryable() = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateRetries(t *testing.T) {
+	ok := attempt{resp: core.ExpressionResponse{Result: 14}}
+	unavailable := attempt{err: &transportError{err: errors.New("conexão indisponível"), retryable: true}}
+	tests := []struct {
+		name         string
+		attempts     []attempt
+		wantAttempts int
+		wantCode     string // vazio: sucesso, ou erro sem *Error
+		wantErr      bool
+	}{
+		{name: "sucesso", attempts: []attempt{ok}, wantAttempts: 1},
+		{name: "sobrecarga e sucesso", attempts: []attempt{failed(CodeOverloaded, 0), ok}, wantAttempts: 2},
+		{name: "transporte indisponível e sucesso", attempts: []attempt{unavailable, ok}, wantAttempts: 2},
+		{name: "erro definitivo", attempts: []attempt{failed("DIV_BY_ZERO", 0)}, wantAttempts: 1, wantCode: "DIV_BY_ZERO"},
+		{name: "esgota as tentativas", attempts: []attempt{failed(CodeInterrupted, 0)}, wantAttempts: 3, wantCode: CodeInterrupted},
+		{name: "transporte esgota as tentativas", attempts: []attempt{unavailable}, wantAttempts: 3, wantErr: true},
+		{name: "falha de transporte definitiva", attempts: []attempt{{err: &transportError{err: errors.New("corpo inválido")}}}, wantAttempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeTransport{attempts: tt.attempts}
+			res, err := newTestClient(f).Calculate(context.Background(), "(4+3)*2")
+
+			var calcErr *Error
+			switch {
+			case tt.wantCode != "":
+				if !errors.As(err, &calcErr) || calcErr.Code != tt.wantCode {
+					t.Fatalf("erro = %v, esperado %s", err, tt.wantCode)
+				}
+			case tt.wantErr:
+				if err == nil || errors.As(err, &calcErr) {
+					t.Fatalf("erro = %v, esperado falha do transporte", err)
+				}
+			case err != nil:
+				t.Fatalf("erro = %v", err)
+			case res.Value != 14:
+				t.Fatalf("Value = %v, esperado 14", res.Value)
+			}
+			if res.Attempts != tt.wantAttempts || len(f.requests) != tt.wantAttempts {
+				t.Errorf("tentativas = %d (transporte: %d), esperado %d", res.Attempts, len(f.requests), tt.wantAttempts)
+			}
+			// Todas as tentativas reutilizam o ExpressionID
+			for _, req := range f.requests {
+				if req.expressionID != res.ExpressionID {
+					t.Errorf("tentativa com ExpressionID %q, esperado %q", req.expressionID, res.ExpressionID)
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateOptions(t *testing.T) {
+	f := &fakeTransport{attempts: []attempt{failed(CodeOverloaded, 0)}}
+	res, _ := newTestClient(f).Calculate(context.Background(), "1+1",
+		WithExpressionID("expr-1"), WithMaxAttempts(1), WithPriority(0), WithTrace())
+	if res.ExpressionID != "expr-1" || res.Attempts != 1 {
+		t.Fatalf("resultado = %+v, esperado expr-1 com 1 tentativa", res)
+	}
+	req := f.requests[0]
+	if req.expressionID != "expr-1" || req.priority != 0 || !req.trace || req.deadline <= 0 {
+		t.Errorf("requisição = %+v", req)
+	}
+}
+
+func TestGeneratedIDs(t *testing.T) {
+	// Clientes iniciados juntos não compartilham o clientID
+	a, b := Config{}.withDefaults(), Config{}.withDefaults()
+	if a.ClientID == "" || a.ClientID == b.ClientID {
+		t.Fatalf("clientIDs = %q, %q", a.ClientID, b.ClientID)
+	}
+
+	f := &fakeTransport{attempts: []attempt{{resp: core.ExpressionResponse{Result: 2}}}}
+	c := newTestClient(f)
+	first, _ := c.Calculate(context.Background(), "1+1")
+	second, _ := c.Calculate(context.Background(), "1+1")
+	if first.ExpressionID == "" || first.ExpressionID == second.ExpressionID {
+		t.Errorf("ExpressionIDs = %q, %q, esperado IDs distintos", first.ExpressionID, second.ExpressionID)
+	}
+}
+
+func TestCalculateBackoff(t *testing.T) {
+	// RetryAfter do dispatcher tem precedência sobre o backoff
+	f := &fakeTransport{attempts: []attempt{failed(CodeRateLimited, 30), {resp: core.ExpressionResponse{Result: 1}}}}
+	if _, err := newTestClient(f).Calculate(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	if wait := f.at[1].Sub(f.at[0]); wait < 30*time.Millisecond {
+		t.Errorf("espera = %v, esperado ao menos o RetryAfter de 30ms", wait)
+	}
+}
+
+func TestCalculateTimeout(t *testing.T) {
+	// O backoff não ultrapassa o prazo da expressão
+	f := &fakeTransport{attempts: []attempt{failed(CodeRateLimited, 10_000)}}
+	start := time.Now()
+	res, err := newTestClient(f).Calculate(context.Background(), "1", WithTimeout(20*time.Millisecond))
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("erro = %v, esperado %v", err, ErrTimeout)
+	}
+	if res.Attempts != 1 || time.Since(start) > time.Second {
+		t.Errorf("tentativas = %d em %v", res.Attempts, time.Since(start))
+	}
+
+	// Contexto cancelado pelo chamador não vira ErrTimeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f = &fakeTransport{attempts: []attempt{{err: context.Canceled}}}
+	if _, err := newTestClient(f).Calculate(ctx, "1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("erro = %v, esperado %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,196 @@
+package calcclient
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/core"
+	grpcOps "github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/grpc"
+	"github.com/Monterazo/Atividades-IF711/ProjetoFinal/internal/telemetry"
+	pb "github.com/Monterazo/Atividades-IF711/ProjetoFinal/proto"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpcCodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// grpcTransport distribui as chamadas entre PoolSize conexões em round-robin
+type grpcTransport struct {
+	conns    []*grpc.ClientConn
+	clients  []pb.CalculatorServiceClient
+	next     atomic.Uint64
+	clientID string
+}
+
+// DialGRPC conecta ao dispatcher gRPC em addr, aguardando até ctx expirar.
+// opts são acrescentadas às opções de conexão do SDK.
+func DialGRPC(ctx context.Context, addr string, cfg Config, opts ...grpc.DialOption) (Client, error) {
+	cfg = cfg.withDefaults()
+	transportCreds := cfg.TLS
+	if transportCreds == nil {
+		transportCreds = insecure.NewCredentials()
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCreds),
+		grpc.WithBlock(),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
+	if creds := cfg.Credentials.internal(); !creds.Empty() {
+		// O token trafega no header: sem TLS, as credenciais não são enviadas
+		if cfg.TLS == nil {
+			return nil, ErrCredentialsRequireTLS
+		}
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(grpcOps.NewTokenCredentials(creds)))
+	}
+	dialOpts = append(dialOpts, opts...)
+
+	t := &grpcTransport{clientID: cfg.ClientID}
+	for range cfg.PoolSize {
+		conn, err := grpc.DialContext(ctx, addr, dialOpts...)
+		if err != nil {
+			t.close()
+			return nil, fmt.Errorf("falha ao conectar ao dispatcher %s: %w", addr, err)
+		}
+		t.conns = append(t.conns, conn)
+		t.clients = append(t.clients, pb.NewCalculatorServiceClient(conn))
+	}
+	return newClient(t, cfg), nil
+}
+
+// client escolhe a próxima conexão do pool
+func (t *grpcTransport) client() pb.CalculatorServiceClient {
+	return t.clients[t.next.Add(1)%uint64(len(t.clients))]
+}
+
+func (t *grpcTransport) calculate(ctx context.Context, req request) (core.ExpressionResponse, error) {
+	// Raiz do trace desta expressão
+	ctx, span := telemetry.Tracer().Start(ctx, "calculate", trace.WithAttributes(
+		telemetry.AttrExpressionID.String(req.expressionID),
+		telemetry.AttrClientID.String(t.clientID),
+	))
+	defer span.End()
+
+	pbReq := &pb.ExpressionRequest{
+		ExpressionId: req.expressionID,
+		Expression:   req.expression,
+		DeadlineMs:   req.deadline.Milliseconds(),
+		IncludeTrace: req.trace,
+		Priority:     int32(req.priority),
+		ClientId:     t.clientID,
+	}
+
+	var resp *pb.ExpressionResponse
+	var err error
+	switch {
+	case req.progress != nil:
+		resp, err = t.calculateWithProgress(ctx, pbReq, req.progress)
+	case req.submitted != nil:
+		resp, err = t.submitAndWait(ctx, pbReq, req.submitted)
+	default:
+		resp, err = t.client().Calculate(ctx, pbReq)
+	}
+	if err != nil {
+		return core.ExpressionResponse{}, grpcError(err)
+	}
+	return grpcOps.ResponseFromProto(resp), nil
+}
+
+// calculateWithProgress repassa cada step despachado e concluído e retorna a resposta final
+func (t *grpcTransport) calculateWithProgress(ctx context.Context, req *pb.ExpressionRequest, progress func(Step)) (*pb.ExpressionResponse, error) {
+	stream, err := t.client().CalculateWithProgress(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		step := event.GetStep()
+		if step == nil {
+			return event.GetResult(), nil
+		}
+		progress(stepFromCore(grpcOps.StepProgressFromProto(step)))
+	}
+}
+
+// submitAndWait envia a expressão com Submit e aguarda o resultado com WaitResult
+func (t *grpcTransport) submitAndWait(ctx context.Context, req *pb.ExpressionRequest, submitted func(ticket, state string)) (*pb.ExpressionResponse, error) {
+	client := t.client()
+	sub, err := client.Submit(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	submitted(sub.Ticket, sub.State.String())
+
+	st, err := client.WaitResult(ctx, &pb.ResultRequest{Ticket: sub.Ticket, TimeoutMs: req.DeadlineMs, ClientId: req.ClientId})
+	if err != nil {
+		return nil, err
+	}
+	if st.Response == nil {
+		return nil, fmt.Errorf("expressão ainda em andamento (%s); consulte com o ticket %s", st.State, st.Ticket)
+	}
+	return st.Response, nil
+}
+
+func (t *grpcTransport) result(ctx context.Context, ticket string, wait time.Duration) (*Status, error) {
+	st, err := t.client().WaitResult(ctx, &pb.ResultRequest{Ticket: ticket, TimeoutMs: wait.Milliseconds(), ClientId: t.clientID})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	var resp *core.ExpressionResponse
+	if st.Response != nil {
+		r := grpcOps.ResponseFromProto(st.Response)
+		resp = &r
+	}
+	return statusFromCore(st.Ticket, st.State.String(), resp), nil
+}
+
+func (t *grpcTransport) cancel(ctx context.Context, ticket string) (*CancelStatus, error) {
+	resp, err := t.client().Cancel(ctx, &pb.CancelRequest{ExpressionId: ticket, ClientId: t.clientID})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &CancelStatus{Cancelled: resp.Cancelled, State: resp.State.String()}, nil
+}
+
+func (t *grpcTransport) close() error {
+	var first error
+	for _, conn := range t.conns {
+		if err := conn.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// grpcError marca como transitória a falha de conexão com o dispatcher
+func grpcError(err error) error {
+	return &transportError{err: err, retryable: status.Code(err) == grpcCodes.Unavailable}
+}
+
+func stepFromCore(e core.StepEvent) Step {
+	var state StepState
+	switch e.State {
+	case core.StepDispatched:
+		state = StepDispatched
+	case core.StepCompleted:
+		state = StepCompleted
+	case core.StepFailed:
+		state = StepFailed
+	}
+	return Step{
+		Index:     e.Index,
+		Total:     e.Total,
+		StepID:    e.StepID,
+		Operation: e.Operation,
+		Operands:  e.Operands,
+		State:     state,
+		Result:    e.Result,
+		Err:       errorFromCore(e.Error),
+	}
+}